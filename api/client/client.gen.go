@@ -0,0 +1,1208 @@
+// Code generated by clientgen from go-api v1. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type AdminAuditListResponse struct {
+	Items      []AdminAuditLogResponse `json:"items,omitempty"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
+
+type AdminAuditLogResponse struct {
+	Action     string         `json:"action,omitempty"`
+	ActorID    int            `json:"actorId,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	ID         int            `json:"id,omitempty"`
+	IP         string         `json:"ip,omitempty"`
+	Reason     string         `json:"reason,omitempty"`
+	TargetID   int            `json:"targetId,omitempty"`
+	TargetType string         `json:"targetType,omitempty"`
+}
+
+type AdminReasonBody struct {
+	Reason string `json:"reason"`
+}
+
+type AdminRoleBody struct {
+	Reason string `json:"reason,omitempty"`
+	// Role is one of user, admin.
+	Role string `json:"role"`
+}
+
+type AdminUserListResponse struct {
+	Items      []AdminUserResponse `json:"items,omitempty"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+type AdminUserResponse struct {
+	CreatedAt       time.Time  `json:"createdAt,omitempty"`
+	DisabledAt      *time.Time `json:"disabledAt,omitempty"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty"`
+	ID              int        `json:"id,omitempty"`
+	LockedUntil     *time.Time `json:"lockedUntil,omitempty"`
+	Name            string     `json:"name,omitempty"`
+	Role            string     `json:"role,omitempty"`
+}
+
+type AuthAPIKeyCreateRequestBody struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type AuthAPIKeyListResponse struct {
+	Keys   []AuthAPIKeyResponse `json:"keys,omitempty"`
+	Scopes map[string]string    `json:"scopes,omitempty"`
+}
+
+type AuthAPIKeyResponse struct {
+	CreatedAt  time.Time  `json:"createdAt,omitempty"`
+	ID         int        `json:"id,omitempty"`
+	Key        string     `json:"key,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Prefix     string     `json:"prefix,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+}
+
+type AuthEmailRequestBody struct {
+	Email string `json:"email"`
+}
+
+type AuthLoginRequestBody struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type AuthLogoutAllResponse struct {
+	RevokedSessions int64 `json:"revokedSessions,omitempty"`
+}
+
+type AuthOIDCProvidersResponse struct {
+	Providers []string `json:"providers,omitempty"`
+}
+
+type AuthPasswordResetRequestBody struct {
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+type AuthRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type AuthRefreshRequestBody struct {
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+type AuthRegisterRequestBody struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type AuthRegisterResponse struct {
+	UserID int `json:"userId,omitempty"`
+}
+
+type AuthTokenResponse struct {
+	AccessToken  string `json:"accessToken,omitempty"`
+	ExpiresIn    int    `json:"expiresIn,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	UserID       int    `json:"userId,omitempty"`
+}
+
+type AuthTwoFactorChallengeResponse struct {
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	ExpiresIn         int    `json:"expiresIn,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	UserID            int    `json:"userId,omitempty"`
+}
+
+type AuthTwoFactorCodeBody struct {
+	Code string `json:"code"`
+}
+
+type AuthTwoFactorSetupResponse struct {
+	ProvisioningURI string `json:"provisioningUri,omitempty"`
+	QRPayload       string `json:"qrPayload,omitempty"`
+	Secret          string `json:"secret,omitempty"`
+}
+
+type AuthTwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled,omitempty"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining,omitempty"`
+}
+
+type AuthTwoFactorVerifyBody struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+}
+
+type AuthVerifyEmailRequestBody struct {
+	Token string `json:"token"`
+}
+
+type DomainBody struct {
+	Hostname string `json:"hostname"`
+}
+
+type DomainResponse struct {
+	CreatedAt     time.Time                `json:"createdAt,omitempty"`
+	Hostname      string                   `json:"hostname,omitempty"`
+	ID            int                      `json:"id,omitempty"`
+	LastCheckedAt *time.Time               `json:"lastCheckedAt,omitempty"`
+	Verification  DomainVerificationRecord `json:"verification,omitempty"`
+	Verified      bool                     `json:"verified,omitempty"`
+	VerifiedAt    *time.Time               `json:"verifiedAt,omitempty"`
+	WorkspaceID   *int                     `json:"workspaceId,omitempty"`
+}
+
+type DomainVerificationRecord struct {
+	Name  string `json:"name,omitempty"`
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type FieldError struct {
+	Code    string `json:"code,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type HealthComponent struct {
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+type HealthMessageResponse struct {
+	Message string `json:"message,omitempty"`
+}
+
+type HealthPost struct {
+	Message string `json:"message"`
+}
+
+type HealthQuantityResponse struct {
+	Message int `json:"message,omitempty"`
+}
+
+type HealthReport struct {
+	Components map[string]HealthComponent `json:"components,omitempty"`
+	Status     string                     `json:"status,omitempty"`
+}
+
+type HealthStatusResponse struct {
+	Status string `json:"status,omitempty"`
+}
+
+type Problem struct {
+	Code      string       `json:"code,omitempty"`
+	Detail    string       `json:"detail,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+	Status    int          `json:"status,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	Type      string       `json:"type,omitempty"`
+}
+
+type ShortLinkCacheStats struct {
+	Errors       int64   `json:"errors,omitempty"`
+	HitRatio     float64 `json:"hitRatio,omitempty"`
+	Hits         int64   `json:"hits,omitempty"`
+	Loads        int64   `json:"loads,omitempty"`
+	Misses       int64   `json:"misses,omitempty"`
+	NegativeHits int64   `json:"negativeHits,omitempty"`
+}
+
+type ShortLinkListResponse struct {
+	Items      []ShortLinkResponse `json:"items,omitempty"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+type ShortLinkResponse struct {
+	ActivatesAt    *time.Time `json:"activatesAt,omitempty"`
+	ClickCount     int        `json:"clickCount,omitempty"`
+	Code           string     `json:"code,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt,omitempty"`
+	Disabled       bool       `json:"disabled,omitempty"`
+	DomainID       int        `json:"domainId,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	ID             int        `json:"id,omitempty"`
+	MaxClicks      int        `json:"maxClicks,omitempty"`
+	ShortURL       string     `json:"shortUrl,omitempty"`
+	TakedownReason string     `json:"takedownReason,omitempty"`
+	TakenDownAt    *time.Time `json:"takenDownAt,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt,omitempty"`
+	URL            string     `json:"url,omitempty"`
+	WorkspaceID    *int       `json:"workspaceId,omitempty"`
+}
+
+type ShortenerBulkCreated struct {
+	Code     string `json:"code,omitempty"`
+	ID       int    `json:"id,omitempty"`
+	Row      int    `json:"row,omitempty"`
+	ShortURL string `json:"shortUrl,omitempty"`
+}
+
+type ShortenerBulkResponse struct {
+	Created []ShortenerBulkCreated  `json:"created,omitempty"`
+	Errors  []ShortenerBulkRowError `json:"errors,omitempty"`
+	Mode    string                  `json:"mode,omitempty"`
+}
+
+type ShortenerBulkRowError struct {
+	Code    string `json:"code,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message,omitempty"`
+	Row     int    `json:"row,omitempty"`
+}
+
+type ShortenerCreateResponse struct {
+	ActivatesAt *time.Time `json:"activatesAt,omitempty"`
+	Code        string     `json:"code,omitempty"`
+	DomainID    int        `json:"domainId,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LongURL     string     `json:"longUrl,omitempty"`
+	MaxClicks   int        `json:"maxClicks,omitempty"`
+	ShortURL    string     `json:"shortUrl,omitempty"`
+	WorkspaceID *int       `json:"workspaceId,omitempty"`
+}
+
+type ShortenerExportRow struct {
+	ActivatesAt *time.Time `json:"activatesAt,omitempty"`
+	Code        string     `json:"code,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt,omitempty"`
+	Disabled    bool       `json:"disabled,omitempty"`
+	DomainID    int        `json:"domainId,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	ID          int        `json:"id,omitempty"`
+	MaxClicks   int        `json:"maxClicks,omitempty"`
+	ShortURL    string     `json:"shortUrl,omitempty"`
+	TotalClicks int64      `json:"totalClicks,omitempty"`
+	URL         string     `json:"url,omitempty"`
+}
+
+type ShortenerPatch struct {
+	ActivatesAt *time.Time `json:"activatesAt,omitempty"`
+	Disabled    *bool      `json:"disabled,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks   *int       `json:"maxClicks,omitempty"`
+	Slug        *string    `json:"slug,omitempty"`
+	URL         *string    `json:"url,omitempty"`
+}
+
+type ShortenerPost struct {
+	ActivatesAt *time.Time `json:"activatesAt,omitempty"`
+	DomainID    int        `json:"domainId,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	MaxClicks   int        `json:"maxClicks,omitempty"`
+	Slug        string     `json:"slug,omitempty"`
+	URL         string     `json:"url"`
+}
+
+type ShortenerReferrerCount struct {
+	Count    int64  `json:"count,omitempty"`
+	Referrer string `json:"referrer,omitempty"`
+}
+
+type ShortenerStatsBucket struct {
+	Count int64     `json:"count,omitempty"`
+	Start time.Time `json:"start,omitempty"`
+}
+
+type ShortenerStatsResponse struct {
+	Buckets        []ShortenerStatsBucket   `json:"buckets,omitempty"`
+	Code           string                   `json:"code,omitempty"`
+	From           time.Time                `json:"from,omitempty"`
+	ID             int                      `json:"id,omitempty"`
+	Interval       string                   `json:"interval,omitempty"`
+	To             time.Time                `json:"to,omitempty"`
+	TopReferrers   []ShortenerReferrerCount `json:"topReferrers,omitempty"`
+	TotalClicks    int                      `json:"totalClicks,omitempty"`
+	UniqueVisitors int64                    `json:"uniqueVisitors,omitempty"`
+}
+
+type SystemStats struct {
+	Admins         int64 `json:"admins,omitempty"`
+	Clicks         int64 `json:"clicks,omitempty"`
+	ClicksLastDay  int64 `json:"clicksLastDay,omitempty"`
+	DisabledLinks  int64 `json:"disabledLinks,omitempty"`
+	DisabledUsers  int64 `json:"disabledUsers,omitempty"`
+	Links          int64 `json:"links,omitempty"`
+	LinksLastDay   int64 `json:"linksLastDay,omitempty"`
+	TakenDownLinks int64 `json:"takenDownLinks,omitempty"`
+	Users          int64 `json:"users,omitempty"`
+}
+
+type WorkspaceAcceptBody struct {
+	Token string `json:"token"`
+}
+
+type WorkspaceBody struct {
+	Name string `json:"name"`
+}
+
+type WorkspaceInvitationResponse struct {
+	CreatedAt   time.Time `json:"createdAt,omitempty"`
+	Email       string    `json:"email,omitempty"`
+	ExpiresAt   time.Time `json:"expiresAt,omitempty"`
+	ID          int       `json:"id,omitempty"`
+	InvitedByID int       `json:"invitedById,omitempty"`
+	Role        string    `json:"role,omitempty"`
+}
+
+type WorkspaceInviteBody struct {
+	Email string `json:"email"`
+	// Role is one of owner, editor, viewer.
+	Role string `json:"role"`
+}
+
+type WorkspaceMemberResponse struct {
+	Email    string    `json:"email,omitempty"`
+	JoinedAt time.Time `json:"joinedAt,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Role     string    `json:"role,omitempty"`
+	UserID   int       `json:"userId,omitempty"`
+}
+
+type WorkspaceMemberRoleBody struct {
+	// Role is one of owner, editor, viewer.
+	Role string `json:"role"`
+}
+
+type WorkspaceResponse struct {
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+	ID        int       `json:"id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Role      string    `json:"role,omitempty"`
+}
+
+// ListAuditLogsParams holds the query parameters of ListAuditLogs.
+type ListAuditLogsParams struct {
+	Action   string `json:"action,omitempty"`
+	ActorID  int    `json:"actorId,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+	TargetID int    `json:"targetId,omitempty"`
+	// TargetType is one of user, link.
+	TargetType string `json:"targetType,omitempty"`
+}
+
+// ListAuditLogs is GET /api/v1/admin/audit.
+//
+// List the audit log, newest first.
+func (c *Client) ListAuditLogs(ctx context.Context, params *ListAuditLogsParams) (*AdminAuditListResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "cursor", params.Cursor)
+		setQuery(query, "limit", params.Limit)
+		setQuery(query, "actorId", params.ActorID)
+		setQuery(query, "action", params.Action)
+		setQuery(query, "targetType", params.TargetType)
+		setQuery(query, "targetId", params.TargetID)
+	}
+	var out AdminAuditListResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/audit", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCacheStats is GET /api/v1/admin/cache/stats.
+//
+// Report the hit ratio of the link cache.
+func (c *Client) GetCacheStats(ctx context.Context) (*ShortLinkCacheStats, error) {
+	var out ShortLinkCacheStats
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/cache/stats", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreLink is POST /api/v1/admin/links/{id}/restore.
+//
+// Undo the takedown of a link.
+func (c *Client) RestoreLink(ctx context.Context, id int, body AdminReasonBody) (*ShortLinkResponse, error) {
+	var out ShortLinkResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/links/"+pathParam(id)+"/restore", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TakeDownLink is POST /api/v1/admin/links/{id}/takedown.
+//
+// Stop a link from redirecting.
+func (c *Client) TakeDownLink(ctx context.Context, id int, body AdminReasonBody) (*ShortLinkResponse, error) {
+	var out ShortLinkResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/links/"+pathParam(id)+"/takedown", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSystemStats is GET /api/v1/admin/stats.
+//
+// Count users, links and clicks.
+func (c *Client) GetSystemStats(ctx context.Context) (*SystemStats, error) {
+	var out SystemStats
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/stats", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListUsersParams holds the query parameters of ListUsers.
+type ListUsersParams struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Q      string `json:"q,omitempty"`
+	// Role is one of user, admin.
+	Role string `json:"role,omitempty"`
+}
+
+// ListUsers is GET /api/v1/admin/users.
+//
+// List and search users.
+func (c *Client) ListUsers(ctx context.Context, params *ListUsersParams) (*AdminUserListResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "cursor", params.Cursor)
+		setQuery(query, "limit", params.Limit)
+		setQuery(query, "q", params.Q)
+		setQuery(query, "role", params.Role)
+	}
+	var out AdminUserListResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/users", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUser is GET /api/v1/admin/users/{id}.
+func (c *Client) GetUser(ctx context.Context, id int) (*AdminUserResponse, error) {
+	var out AdminUserResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/users/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DisableUser is POST /api/v1/admin/users/{id}/disable.
+//
+// Block an account and end its sessions.
+//
+// API keys of the account stop working while it is disabled.
+func (c *Client) DisableUser(ctx context.Context, id int, body AdminReasonBody) (*AdminUserResponse, error) {
+	var out AdminUserResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/users/"+pathParam(id)+"/disable", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EnableUser is POST /api/v1/admin/users/{id}/enable.
+//
+// Unblock a disabled account.
+func (c *Client) EnableUser(ctx context.Context, id int, body AdminReasonBody) (*AdminUserResponse, error) {
+	var out AdminUserResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/users/"+pathParam(id)+"/enable", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetUserRole is PUT /api/v1/admin/users/{id}/role.
+func (c *Client) SetUserRole(ctx context.Context, id int, body AdminRoleBody) (*AdminUserResponse, error) {
+	var out AdminUserResponse
+	if err := c.do(ctx, http.MethodPut, "/api/v1/admin/users/"+pathParam(id)+"/role", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTwoFactorStatus is GET /api/v1/auth/2fa.
+func (c *Client) GetTwoFactorStatus(ctx context.Context) (*AuthTwoFactorStatusResponse, error) {
+	var out AuthTwoFactorStatusResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/2fa", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DisableTwoFactor is POST /api/v1/auth/2fa/disable.
+func (c *Client) DisableTwoFactor(ctx context.Context, body AuthTwoFactorCodeBody) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/auth/2fa/disable", nil, body, nil)
+}
+
+// EnableTwoFactor is POST /api/v1/auth/2fa/enable.
+func (c *Client) EnableTwoFactor(ctx context.Context, body AuthTwoFactorCodeBody) (*AuthRecoveryCodesResponse, error) {
+	var out AuthRecoveryCodesResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/2fa/enable", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RegenerateRecoveryCodes is POST /api/v1/auth/2fa/recovery-codes.
+//
+// Replace the recovery codes.
+func (c *Client) RegenerateRecoveryCodes(ctx context.Context, body AuthTwoFactorCodeBody) (*AuthRecoveryCodesResponse, error) {
+	var out AuthRecoveryCodesResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/2fa/recovery-codes", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetupTwoFactor is POST /api/v1/auth/2fa/setup.
+//
+// Start setting up two-factor login.
+//
+// Returns a new secret, which is only used once /auth/2fa/enable confirms a code from it.
+func (c *Client) SetupTwoFactor(ctx context.Context) (*AuthTwoFactorSetupResponse, error) {
+	var out AuthTwoFactorSetupResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/2fa/setup", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// VerifyTwoFactor is POST /api/v1/auth/2fa/verify.
+//
+// Complete a login with a second factor.
+func (c *Client) VerifyTwoFactor(ctx context.Context, body AuthTwoFactorVerifyBody) (*AuthTokenResponse, error) {
+	var out AuthTokenResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/2fa/verify", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAPIKeys is GET /api/v1/auth/keys.
+func (c *Client) ListAPIKeys(ctx context.Context) (*AuthAPIKeyListResponse, error) {
+	var out AuthAPIKeyListResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/keys", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAPIKey is POST /api/v1/auth/keys.
+//
+// Create a personal API key.
+//
+// The key itself is only returned in this response.
+func (c *Client) CreateAPIKey(ctx context.Context, body AuthAPIKeyCreateRequestBody) (*AuthAPIKeyResponse, error) {
+	var out AuthAPIKeyResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/keys", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeAPIKey is DELETE /api/v1/auth/keys/{id}.
+func (c *Client) RevokeAPIKey(ctx context.Context, id int) (*AuthAPIKeyResponse, error) {
+	var out AuthAPIKeyResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/auth/keys/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// LoginResponse is one of AuthTokenResponse, AuthTwoFactorChallengeResponse. Only the fields of the one returned are set.
+type LoginResponse struct {
+	AccessToken       string `json:"accessToken,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	ExpiresIn         int    `json:"expiresIn,omitempty"`
+	RefreshToken      string `json:"refreshToken,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	UserID            int    `json:"userId,omitempty"`
+}
+
+// Login is POST /api/v1/auth/login.
+//
+// Log in with email and password.
+//
+// Accounts with two-factor login get a challenge to complete at /auth/2fa/verify instead of tokens.
+func (c *Client) Login(ctx context.Context, body AuthLoginRequestBody) (*LoginResponse, error) {
+	var out LoginResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/login", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Logout is POST /api/v1/auth/logout.
+//
+// End the current session.
+func (c *Client) Logout(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/auth/logout", nil, nil, nil)
+}
+
+// LogoutAll is POST /api/v1/auth/logout-all.
+//
+// End every session of the user.
+func (c *Client) LogoutAll(ctx context.Context) (*AuthLogoutAllResponse, error) {
+	var out AuthLogoutAllResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/logout-all", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListOIDCProviders is GET /api/v1/auth/oidc.
+//
+// Name the identity providers users can log in with.
+func (c *Client) ListOIDCProviders(ctx context.Context) (*AuthOIDCProvidersResponse, error) {
+	var out AuthOIDCProvidersResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/oidc", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CompleteOIDCLoginParams holds the query parameters of CompleteOIDCLogin.
+type CompleteOIDCLoginParams struct {
+	Code             string `json:"code,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	State            string `json:"state"`
+}
+
+// CompleteOIDCLoginResponse is one of AuthTokenResponse, AuthTwoFactorChallengeResponse. Only the fields of the one returned are set.
+type CompleteOIDCLoginResponse struct {
+	AccessToken       string `json:"accessToken,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	ExpiresIn         int    `json:"expiresIn,omitempty"`
+	RefreshToken      string `json:"refreshToken,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	UserID            int    `json:"userId,omitempty"`
+}
+
+// CompleteOIDCLogin is GET /api/v1/auth/oidc/{provider}/callback.
+//
+// Complete a login at an identity provider.
+func (c *Client) CompleteOIDCLogin(ctx context.Context, provider string, params *CompleteOIDCLoginParams) (*CompleteOIDCLoginResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "code", params.Code)
+		setQuery(query, "state", params.State)
+		setQuery(query, "error", params.Error)
+		setQuery(query, "error_description", params.ErrorDescription)
+	}
+	var out CompleteOIDCLoginResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/auth/oidc/"+pathParam(provider)+"/callback", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartOIDCLogin is GET /api/v1/auth/oidc/{provider}/login.
+//
+// Redirect to the identity provider.
+//
+// The caller must close the body of the response.
+func (c *Client) StartOIDCLogin(ctx context.Context, provider string) (*http.Response, error) {
+	return c.send(ctx, http.MethodGet, "/api/v1/auth/oidc/"+pathParam(provider)+"/login", nil, nil)
+}
+
+// ForgotPassword is POST /api/v1/auth/password/forgot.
+//
+// Send a password reset email.
+func (c *Client) ForgotPassword(ctx context.Context, body AuthEmailRequestBody) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodPost, "/api/v1/auth/password/forgot", nil, body, &out)
+	return out, err
+}
+
+// ResetPassword is POST /api/v1/auth/password/reset.
+func (c *Client) ResetPassword(ctx context.Context, body AuthPasswordResetRequestBody) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodPost, "/api/v1/auth/password/reset", nil, body, &out)
+	return out, err
+}
+
+// RefreshSession is POST /api/v1/auth/refresh.
+//
+// Exchange a refresh token for new tokens.
+//
+// The refresh token is read from the body, or the refresh_token cookie.
+func (c *Client) RefreshSession(ctx context.Context, body *AuthRefreshRequestBody) (*AuthTokenResponse, error) {
+	var out AuthTokenResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/refresh", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Register is POST /api/v1/auth/register.
+//
+// Create an account.
+func (c *Client) Register(ctx context.Context, body AuthRegisterRequestBody) (*AuthRegisterResponse, error) {
+	var out AuthRegisterResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/auth/register", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// VerifyEmail is POST /api/v1/auth/verify-email.
+func (c *Client) VerifyEmail(ctx context.Context, body AuthVerifyEmailRequestBody) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodPost, "/api/v1/auth/verify-email", nil, body, &out)
+	return out, err
+}
+
+// RequestEmailVerification is POST /api/v1/auth/verify-email/request.
+//
+// Send a new verification email.
+func (c *Client) RequestEmailVerification(ctx context.Context) (string, error) {
+	var out string
+	err := c.do(ctx, http.MethodPost, "/api/v1/auth/verify-email/request", nil, nil, &out)
+	return out, err
+}
+
+// ListDomainsParams holds the query parameters of ListDomains.
+type ListDomainsParams struct {
+	Workspace int `json:"workspace,omitempty"`
+}
+
+// ListDomains is GET /api/v1/domains.
+//
+// List custom domains, personal or of a workspace.
+func (c *Client) ListDomains(ctx context.Context, params *ListDomainsParams) ([]DomainResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "workspace", params.Workspace)
+	}
+	var out []DomainResponse
+	err := c.do(ctx, http.MethodGet, "/api/v1/domains", query, nil, &out)
+	return out, err
+}
+
+// CreateDomainParams holds the query parameters of CreateDomain.
+type CreateDomainParams struct {
+	Workspace int `json:"workspace,omitempty"`
+}
+
+// CreateDomain is POST /api/v1/domains.
+//
+// Claim a custom domain.
+//
+// The domain serves links once the TXT record in the response is published and verified.
+func (c *Client) CreateDomain(ctx context.Context, params *CreateDomainParams, body DomainBody) (*DomainResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "workspace", params.Workspace)
+	}
+	var out DomainResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/domains", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDomain is GET /api/v1/domains/{id}.
+func (c *Client) GetDomain(ctx context.Context, id int) (*DomainResponse, error) {
+	var out DomainResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/domains/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteDomain is DELETE /api/v1/domains/{id}.
+//
+// Remove a domain and every link served on it.
+func (c *Client) DeleteDomain(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/domains/"+pathParam(id), nil, nil, nil)
+}
+
+// VerifyDomain is POST /api/v1/domains/{id}/verify.
+//
+// Look up the verification record of a domain.
+func (c *Client) VerifyDomain(ctx context.Context, id int) (*DomainResponse, error) {
+	var out DomainResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/domains/"+pathParam(id)+"/verify", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPI is GET /api/v1/openapi.json.
+//
+// Describe this version of the API.
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	err := c.do(ctx, http.MethodGet, "/api/v1/openapi.json", nil, nil, &out)
+	return out, err
+}
+
+// Ping is GET /api/v1/ping.
+//
+// Check that the API answers.
+func (c *Client) Ping(ctx context.Context) (*HealthMessageResponse, error) {
+	var out HealthMessageResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/ping", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Echo is POST /api/v1/ping.
+//
+// Echo a message.
+func (c *Client) Echo(ctx context.Context, body HealthPost) (*HealthMessageResponse, error) {
+	var out HealthMessageResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/ping", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PingQuantity is GET /api/v1/ping/{quantity}.
+//
+// Echo a number from the path.
+func (c *Client) PingQuantity(ctx context.Context, quantity int) (*HealthQuantityResponse, error) {
+	var out HealthQuantityResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/ping/"+pathParam(quantity), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListShortLinksParams holds the query parameters of ListShortLinks.
+type ListShortLinksParams struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	Q      string `json:"q,omitempty"`
+	// Sort is one of createdAt, -createdAt, code, -code, url, -url.
+	Sort      string `json:"sort,omitempty"`
+	Workspace int    `json:"workspace,omitempty"`
+}
+
+// ListShortLinks is GET /api/v1/short.
+//
+// List short links, personal or of a workspace.
+func (c *Client) ListShortLinks(ctx context.Context, params *ListShortLinksParams) (*ShortLinkListResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "workspace", params.Workspace)
+		setQuery(query, "cursor", params.Cursor)
+		setQuery(query, "limit", params.Limit)
+		setQuery(query, "q", params.Q)
+		setQuery(query, "sort", params.Sort)
+	}
+	var out ShortLinkListResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/short", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateShortLinkParams holds the query parameters of CreateShortLink.
+type CreateShortLinkParams struct {
+	Workspace int `json:"workspace,omitempty"`
+}
+
+// CreateShortLink is POST /api/v1/short.
+//
+// Shorten a URL.
+func (c *Client) CreateShortLink(ctx context.Context, params *CreateShortLinkParams, body ShortenerPost) (*ShortenerCreateResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "workspace", params.Workspace)
+	}
+	var out ShortenerCreateResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/short", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateShortLinksParams holds the query parameters of CreateShortLinks.
+type CreateShortLinksParams struct {
+	// Mode is one of atomic, partial.
+	Mode      string `json:"mode,omitempty"`
+	Workspace int    `json:"workspace,omitempty"`
+}
+
+// CreateShortLinks is POST /api/v1/short/bulk.
+//
+// Shorten many URLs at once.
+//
+// Takes a JSON array, a CSV file with a url header, or either uploaded as the file field of a form.
+func (c *Client) CreateShortLinks(ctx context.Context, params *CreateShortLinksParams, body []ShortenerPost) (*ShortenerBulkResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "workspace", params.Workspace)
+		setQuery(query, "mode", params.Mode)
+	}
+	var out ShortenerBulkResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/short/bulk", query, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportShortLinksParams holds the query parameters of ExportShortLinks.
+type ExportShortLinksParams struct {
+	// Format is one of csv, ndjson.
+	Format    string `json:"format,omitempty"`
+	Workspace int    `json:"workspace,omitempty"`
+}
+
+// ExportShortLinks is GET /api/v1/short/export.
+//
+// Download every link as CSV or newline delimited JSON.
+//
+// The caller must close the body of the response.
+func (c *Client) ExportShortLinks(ctx context.Context, params *ExportShortLinksParams) (*http.Response, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "workspace", params.Workspace)
+		setQuery(query, "format", params.Format)
+	}
+	return c.send(ctx, http.MethodGet, "/api/v1/short/export", query, nil)
+}
+
+// GetShortLink is GET /api/v1/short/{id}.
+func (c *Client) GetShortLink(ctx context.Context, id int) (*ShortLinkResponse, error) {
+	var out ShortLinkResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/short/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateShortLink is PATCH /api/v1/short/{id}.
+//
+// Change a short link.
+//
+// Only the fields present are changed. The schedule fields take null to remove the limit.
+func (c *Client) UpdateShortLink(ctx context.Context, id int, body ShortenerPatch) (*ShortLinkResponse, error) {
+	var out ShortLinkResponse
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/short/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteShortLink is DELETE /api/v1/short/{id}.
+func (c *Client) DeleteShortLink(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/short/"+pathParam(id), nil, nil, nil)
+}
+
+// GetShortLinkStatsParams holds the query parameters of GetShortLinkStats.
+type GetShortLinkStatsParams struct {
+	From time.Time `json:"from,omitempty"`
+	// Interval is one of hour, day, week.
+	Interval  string    `json:"interval,omitempty"`
+	Referrers int       `json:"referrers,omitempty"`
+	To        time.Time `json:"to,omitempty"`
+}
+
+// GetShortLinkStats is GET /api/v1/short/{id}/stats.
+//
+// Count the clicks of a link over time.
+func (c *Client) GetShortLinkStats(ctx context.Context, id int, params *GetShortLinkStatsParams) (*ShortenerStatsResponse, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "interval", params.Interval)
+		setQuery(query, "from", params.From)
+		setQuery(query, "to", params.To)
+		setQuery(query, "referrers", params.Referrers)
+	}
+	var out ShortenerStatsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/short/"+pathParam(id)+"/stats", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListWorkspaces is GET /api/v1/workspaces.
+//
+// List the workspaces the user is a member of.
+func (c *Client) ListWorkspaces(ctx context.Context) ([]WorkspaceResponse, error) {
+	var out []WorkspaceResponse
+	err := c.do(ctx, http.MethodGet, "/api/v1/workspaces", nil, nil, &out)
+	return out, err
+}
+
+// CreateWorkspace is POST /api/v1/workspaces.
+func (c *Client) CreateWorkspace(ctx context.Context, body WorkspaceBody) (*WorkspaceResponse, error) {
+	var out WorkspaceResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/workspaces", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AcceptInvitation is POST /api/v1/workspaces/invitations/accept.
+//
+// Join a workspace with an invitation token.
+func (c *Client) AcceptInvitation(ctx context.Context, body WorkspaceAcceptBody) (*WorkspaceResponse, error) {
+	var out WorkspaceResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/workspaces/invitations/accept", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetWorkspace is GET /api/v1/workspaces/{id}.
+func (c *Client) GetWorkspace(ctx context.Context, id int) (*WorkspaceResponse, error) {
+	var out WorkspaceResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/workspaces/"+pathParam(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RenameWorkspace is PATCH /api/v1/workspaces/{id}.
+func (c *Client) RenameWorkspace(ctx context.Context, id int, body WorkspaceBody) (*WorkspaceResponse, error) {
+	var out WorkspaceResponse
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/workspaces/"+pathParam(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWorkspace is DELETE /api/v1/workspaces/{id}.
+func (c *Client) DeleteWorkspace(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/workspaces/"+pathParam(id), nil, nil, nil)
+}
+
+// ListInvitations is GET /api/v1/workspaces/{id}/invitations.
+//
+// List the pending invitations of a workspace.
+func (c *Client) ListInvitations(ctx context.Context, id int) ([]WorkspaceInvitationResponse, error) {
+	var out []WorkspaceInvitationResponse
+	err := c.do(ctx, http.MethodGet, "/api/v1/workspaces/"+pathParam(id)+"/invitations", nil, nil, &out)
+	return out, err
+}
+
+// CreateInvitation is POST /api/v1/workspaces/{id}/invitations.
+//
+// Mail an invitation to join a workspace.
+func (c *Client) CreateInvitation(ctx context.Context, id int, body WorkspaceInviteBody) (*WorkspaceInvitationResponse, error) {
+	var out WorkspaceInvitationResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/workspaces/"+pathParam(id)+"/invitations", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeInvitation is DELETE /api/v1/workspaces/{id}/invitations/{invitationId}.
+func (c *Client) RevokeInvitation(ctx context.Context, id int, invitationID int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/workspaces/"+pathParam(id)+"/invitations/"+pathParam(invitationID), nil, nil, nil)
+}
+
+// ListMembers is GET /api/v1/workspaces/{id}/members.
+func (c *Client) ListMembers(ctx context.Context, id int) ([]WorkspaceMemberResponse, error) {
+	var out []WorkspaceMemberResponse
+	err := c.do(ctx, http.MethodGet, "/api/v1/workspaces/"+pathParam(id)+"/members", nil, nil, &out)
+	return out, err
+}
+
+// UpdateMember is PATCH /api/v1/workspaces/{id}/members/{userId}.
+//
+// Change the role of a member.
+func (c *Client) UpdateMember(ctx context.Context, id int, userID int, body WorkspaceMemberRoleBody) (*WorkspaceMemberResponse, error) {
+	var out WorkspaceMemberResponse
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/workspaces/"+pathParam(id)+"/members/"+pathParam(userID), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemoveMember is DELETE /api/v1/workspaces/{id}/members/{userId}.
+func (c *Client) RemoveMember(ctx context.Context, id int, userID int) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/workspaces/"+pathParam(id)+"/members/"+pathParam(userID), nil, nil, nil)
+}
+
+// GetLiveness is GET /healthz.
+//
+// Report that the process serves requests.
+func (c *Client) GetLiveness(ctx context.Context) (*HealthStatusResponse, error) {
+	var out HealthStatusResponse
+	if err := c.do(ctx, http.MethodGet, "/healthz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMetrics is GET /metrics.
+//
+// Expose metrics in the Prometheus text format.
+//
+// Requires the metrics token as a bearer token when one is configured.
+//
+// The caller must close the body of the response.
+func (c *Client) GetMetrics(ctx context.Context) (*http.Response, error) {
+	return c.send(ctx, http.MethodGet, "/metrics", nil, nil)
+}
+
+// GetReadiness is GET /readyz.
+//
+// Report whether the instance and its dependencies are ready.
+func (c *Client) GetReadiness(ctx context.Context) (*HealthReport, error) {
+	var out HealthReport
+	if err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FollowShortLink is GET /short/{code}.
+//
+// Redirect to the destination of a short link.
+//
+// Links that expired or reached their click limit answer 410, or redirect to the fallback URL when one is configured.
+//
+// The caller must close the body of the response.
+func (c *Client) FollowShortLink(ctx context.Context, code string) (*http.Response, error) {
+	return c.send(ctx, http.MethodGet, "/short/"+pathParam(code), nil, nil)
+}
+
+// GetShortLinkQRParams holds the query parameters of GetShortLinkQR.
+type GetShortLinkQRParams struct {
+	Bg string `json:"bg,omitempty"`
+	Fg string `json:"fg,omitempty"`
+	// Format is one of png, svg.
+	Format string `json:"format,omitempty"`
+	// Level is one of L, M, Q, H.
+	Level  string `json:"level,omitempty"`
+	Margin *int   `json:"margin,omitempty"`
+	Size   int    `json:"size,omitempty"`
+}
+
+// GetShortLinkQR is GET /short/{code}/qr.
+//
+// Draw a QR code of a short link.
+//
+// Encodes the public short URL of the link, on the host the request was made to. Send the ETag back in If-None-Match to get 304 while the image is unchanged.
+//
+// The caller must close the body of the response.
+func (c *Client) GetShortLinkQR(ctx context.Context, code string, params *GetShortLinkQRParams) (*http.Response, error) {
+	query := url.Values{}
+	if params != nil {
+		setQuery(query, "format", params.Format)
+		setQuery(query, "size", params.Size)
+		setQuery(query, "level", params.Level)
+		setQuery(query, "margin", params.Margin)
+		setQuery(query, "fg", params.Fg)
+		setQuery(query, "bg", params.Bg)
+	}
+	return c.send(ctx, http.MethodGet, "/short/"+pathParam(code)+"/qr", query, nil)
+}
@@ -1,93 +1,352 @@
 package api
 
 import (
-	"fmt"
-	"go-api/internal/env"
+	"context"
+	"go-api/database/model"
+	"go-api/internal/analytics"
+	"go-api/internal/apiversion"
+	"go-api/internal/cache"
+	"go-api/internal/config"
+	"go-api/internal/domains"
+	"go-api/internal/health"
+	initializers "go-api/internal/intializers"
+	"go-api/internal/mailer"
+	"go-api/internal/metrics"
+	"go-api/internal/middleware"
+	"go-api/internal/oidc"
+	"go-api/internal/openapi"
+	"go-api/internal/ratelimit"
+	"go-api/internal/urlcheck"
+	"go-api/internal/utils"
+	"go-api/service/jobs"
 	"go-api/service/routers"
 	"log"
-	"net/http"
-	"strconv"
-	"time"
+	"log/slog"
+	"net"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+const (
+	backgroundWorkers   = 4
+	backgroundQueueSize = 1000
+)
+
 type ApiServer struct {
-	addr string
-	db   *gorm.DB
+	cfg   *config.Config
+	db    *gorm.DB
+	cache cache.Cache
+
+	links   *model.ShortLinkCache
+	clicks  *analytics.Recorder
+	sweeper *jobs.LinkSweeper
+
+	// dnsResolver answers the TXT lookups of domain verification.
+	dnsResolver domains.Resolver
+
+	checker     *health.Checker
+	metrics     *metrics.Registry
+	httpMetrics middleware.HTTPMetrics
+	redirects   *metrics.CounterVec
+
+	// versions are the versions of the API served side by side.
+	versions     *apiversion.Versions
+	versionUsage *metrics.CounterVec
+
+	// openapi describes the routes registered by Init, by version.
+	openapi map[string]*openapi.Document
+
+	hooksMu   sync.Mutex
+	hooks     []shutdownHook
+	closeOnce sync.Once
 }
 
-func NewApiServer(addr string, db *gorm.DB) *ApiServer {
+func NewApiServer(cfg *config.Config, db *gorm.DB, c cache.Cache) *ApiServer {
 	return &ApiServer{
-		addr: addr,
-		db:   db,
+		cfg:   cfg,
+		db:    db,
+		cache: c,
 	}
 }
 
-func (s *ApiServer) Init(version string) *gin.Engine {
-	if !checkVersion(version) {
-		panic("version must be in format v1")
-	}
+// SetDNSResolver replaces the resolver used to verify custom domains, so
+// tests do not depend on real DNS. It must be called before Init.
+func (s *ApiServer) SetDNSResolver(resolver domains.Resolver) {
+	s.dnsResolver = resolver
+}
 
-	gin.SetMode(env.GetString("GIN_MODE", gin.ReleaseMode))
+// versionedRouter registers its routes under every version of the API.
+type versionedRouter interface {
+	RegisterRouter(router *gin.RouterGroup, version apiversion.Version)
+	DescribeRoutes(spec *openapi.Group, version apiversion.Version)
+}
+
+// baseRouter also has routes outside of /api, shared by every version.
+type baseRouter interface {
+	RegisterBaseRoutes(router *gin.Engine)
+	DescribeBaseRoutes(spec *openapi.Group)
+}
 
-	// Middlewares
+// Init registers the routes of every configured API version.
+func (s *ApiServer) Init() *gin.Engine {
+	s.versions = s.newVersions()
 
-	// logger, recover, cors, requestID
-	r := gin.Default()
+	gin.SetMode(s.cfg.Server.GinMode)
+	utils.ConfigureJWT(s.cfg.Auth.JWTSecret, s.cfg.Auth.AccessTokenTTL)
 
-	// groups
-	versionRouter := r.Group(fmt.Sprintf("/api/%s", version))
-	log.Printf("API version: %s", version)
+	s.newMetrics()
+	logger := slog.Default()
+
+	r := gin.New()
+	r.Use(
+		middleware.RequestID(),
+		middleware.AccessLog(logger),
+		middleware.Metrics(s.httpMetrics),
+		middleware.Errors(),
+		middleware.Recover(logger),
+	)
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(middleware.UnsupportedVersion(s.versions), middleware.NotFound())
+	r.NoMethod(middleware.MethodNotAllowed())
+	r.GET("/metrics", s.metricsHandler())
+
+	s.checker = health.NewChecker(s.cfg.Server.ReadinessTimeout)
+	s.registerDependencies()
 
 	// routers
-	routers.NewHealthRouter().RegisterRouter(versionRouter)
+	healthRouter := routers.NewHealthRouter(s.db, s.checker)
+
+	s.links = model.NewShortLinkCache(
+		s.db,
+		s.cache,
+		s.cfg.Cache.TTL,
+		s.cfg.Cache.NegativeTTL,
+	)
+	s.clicks = analytics.NewRecorder(s.db, analytics.Options{
+		IPSalt: s.cfg.Analytics.IPSalt,
+	})
+	s.sweeper = jobs.NewLinkSweeper(s.db, s.links, s.cfg.Shortener.SweepInterval)
+
+	s.metrics.CounterFunc("click_events_dropped_total", "Click events discarded because the buffer was full.", func() float64 {
+		return float64(s.clicks.Dropped())
+	})
+
+	s.OnShutdown("click recorder", func(context.Context) error {
+		s.clicks.Close()
+		return nil
+	})
+	s.OnShutdown("link sweeper", func(context.Context) error {
+		s.sweeper.Stop()
+		return nil
+	})
+
+	// Work requests leave behind, like sending mail, finishes within the
+	// shutdown timeout.
+	background := jobs.NewQueue(backgroundWorkers, backgroundQueueSize)
+	s.metrics.CounterFunc("background_jobs_dropped_total", "Background jobs discarded because the queue was full or closed.", func() float64 {
+		return float64(background.Dropped())
+	})
+	s.OnShutdown("background jobs", background.Close)
+
+	limits := s.newRateLimiter()
+
+	shortenerRouter := routers.NewShortenerRouter(s.db, s.links, s.clicks, s.newURLValidator(), limits, s.redirects, s.cfg)
+
+	mail := s.newMailer()
+
+	authRouter := routers.NewAuthRouter(s.db, mail, background, limits, s.newOIDCProviders(), s.cfg)
+	adminRouter := routers.NewAdminRouter(s.db, s.links)
+	workspaceRouter := routers.NewWorkspaceRouter(s.db, s.links, mail, background, s.cfg)
+	domainRouter := routers.NewDomainRouter(s.db, s.links, s.newDomainVerifier())
+
+	baseRouters := []baseRouter{healthRouter, shortenerRouter}
+	versionedRouters := []versionedRouter{healthRouter, shortenerRouter, authRouter, adminRouter, workspaceRouter, domainRouter}
+
+	for _, router := range baseRouters {
+		router.RegisterBaseRoutes(r)
+	}
+
+	// Every version gets its own description, and every router describes
+	// its routes next to registering them.
+	s.openapi = make(map[string]*openapi.Document)
+	for _, version := range s.versions.All() {
+		versionRouter := r.Group(version.Prefix(), middleware.APIVersion(version, s.versionUsage))
+		log.Printf("API version: %s", version)
 
-	shortenerRouter := routers.NewShortenerRouter(s.db)
-	shortenerRouter.RegisterBaseRoutes(r)
-	shortenerRouter.RegisterRouter(versionRouter)
+		spec := newSpec(version)
+		baseSpec := spec.Group("")
+		versionSpec := spec.Group(versionRouter.BasePath())
+		if version.IsDeprecated() {
+			versionSpec = versionSpec.Deprecated()
+		}
+		describeServerRoutes(baseSpec, versionSpec)
 
-	routers.NewAuthRouter(s.db).RegisterRouter(versionRouter)
+		for _, router := range baseRouters {
+			router.DescribeBaseRoutes(baseSpec)
+		}
+		for _, router := range versionedRouters {
+			router.RegisterRouter(versionRouter, version)
+			router.DescribeRoutes(versionSpec, version)
+		}
+
+		s.openapi[version.Name] = spec.Document()
+		versionRouter.GET("/openapi.json", s.openAPIHandler(version.Name))
+	}
+	log.Printf("API version %s serves requests that do not ask for one", s.versions.Default().Name)
 
 	return r
 }
 
-func (s *ApiServer) Start(r *gin.Engine) error {
-	log.Printf("Starting API server on %s", s.addr)
-	server := &http.Server{
-		Addr:           s.addr,
-		Handler:        r,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   30 * time.Second,
-		IdleTimeout:    time.Minute,
-		MaxHeaderBytes: 1 << 20,
+// newVersions reads the versions to serve from the configuration.
+func (s *ApiServer) newVersions() *apiversion.Versions {
+	deprecations, err := apiversion.ParseDeprecations(s.cfg.API.Deprecations)
+	if err != nil {
+		log.Fatalf("Invalid API_DEPRECATIONS: %v", err)
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		return err
+	versions, err := apiversion.New(s.cfg.API.Versions, s.cfg.API.DefaultVersion, deprecations)
+	if err != nil {
+		log.Fatalf("Invalid API_VERSIONS: %v", err)
 	}
+	return versions
+}
 
-	return nil
+func (s *ApiServer) newURLValidator() *urlcheck.Validator {
+	opts := urlcheck.Options{
+		AllowedSchemes:  s.cfg.URLCheck.AllowedSchemes,
+		SelfHosts:       s.cfg.Shortener.Hosts,
+		Hosts:           s.links,
+		FollowRedirects: s.cfg.URLCheck.CheckRedirects,
+		MaxRedirects:    s.cfg.URLCheck.MaxRedirects,
+	}
+
+	if path := s.cfg.URLCheck.BlocklistFile; path != "" {
+		blocklist, err := urlcheck.LoadBlocklist(path)
+		if err != nil {
+			log.Fatalf("Failed to load URL blocklist %s: %v", path, err)
+		}
+		log.Printf("Loaded %d blocked domains from %s", blocklist.Len(), path)
+		opts.Blocklist = blocklist
+	}
+
+	return urlcheck.New(opts)
 }
 
-func checkVersion(version string) bool {
-	if len(version) < 2 {
-		return false
+// newDomainVerifier checks custom domains against the configured DNS server,
+// or the system resolver when it is not set. The API's own hosts cannot be claimed.
+func (s *ApiServer) newDomainVerifier() *domains.Verifier {
+	resolver := s.dnsResolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+		if server := s.cfg.Domains.DNSServer; server != "" {
+			resolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, network, server)
+				},
+			}
+		}
 	}
 
-	if version[0] != 'v' {
-		return false
+	return domains.NewVerifier(domains.Options{
+		Resolver:      resolver,
+		RecordPrefix:  s.cfg.Domains.VerifyPrefix,
+		ReservedHosts: s.cfg.Shortener.Hosts,
+	})
+}
+
+// registerDependencies adds the readiness checks and shutdown hooks of the
+// database and cache. They are registered first so their hooks run last,
+// after everything that may still write to them.
+func (s *ApiServer) registerDependencies() {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database pool: %v", err)
+	}
+	s.checker.Register("database", sqlDB.PingContext)
+	s.OnShutdown("database", func(context.Context) error {
+		return sqlDB.Close()
+	})
+
+	if pinger, ok := s.cache.(interface{ Ping(context.Context) error }); ok {
+		s.checker.Register("cache", pinger.Ping)
 	}
+	if closer, ok := s.cache.(interface{ Close() error }); ok {
+		s.OnShutdown("cache", func(context.Context) error {
+			return closer.Close()
+		})
+	}
+}
 
-	v, err := strconv.Atoi(version[1:])
+// newRateLimiter keeps the buckets of the configured policies in memory, or
+// in Redis which shares the cache's server when the cache uses Redis.
+func (s *ApiServer) newRateLimiter() *ratelimit.Limiter {
+	policies, err := ratelimit.ParsePolicies(s.cfg.RateLimit.Rules)
 	if err != nil {
-		return false
+		log.Fatalf("Invalid RATE_LIMITS: %v", err)
+	}
+
+	var store ratelimit.Store
+	switch driver := s.cfg.RateLimit.Store; driver {
+	case "memory":
+		store = ratelimit.NewMemory()
+	case "redis":
+		client, ok := s.cache.(*cache.Redis)
+		if !ok {
+			client = cache.NewRedis(initializers.RedisOptions(s.cfg))
+			s.checker.Register("ratelimit", client.Ping)
+			s.OnShutdown("ratelimit", func(context.Context) error {
+				return client.Close()
+			})
+		}
+		store = ratelimit.NewRedis(client)
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_STORE %q", driver)
+	}
+
+	for _, p := range policies {
+		log.Printf("Rate limit %s", p)
 	}
+	return ratelimit.NewLimiter(store, policies)
+}
 
-	if v < 1 {
-		return false
+// newMailer picks the mail transport: smtp, or log which writes messages to
+// a file or the standard logger.
+func (s *ApiServer) newMailer() mailer.Mailer {
+	switch driver := s.cfg.Mail.Driver; driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPOptions{
+			Host:     s.cfg.Mail.SMTPHost,
+			Port:     s.cfg.Mail.SMTPPort,
+			Username: s.cfg.Mail.SMTPUsername,
+			Password: s.cfg.Mail.SMTPPassword,
+			From:     s.cfg.Mail.From,
+		})
+	case "log":
+		return mailer.NewLogMailer(s.cfg.Mail.LogFile)
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q", driver)
+		return nil
 	}
+}
 
-	return true
+// newOIDCProviders sets up the configured identity providers.
+func (s *ApiServer) newOIDCProviders() map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider)
+	for _, name := range s.cfg.OIDC.Providers {
+		p := s.cfg.OIDC.Provider[name]
+		providers[name] = oidc.NewProvider(oidc.Options{
+			Name:         name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+		log.Printf("OIDC login enabled for %s", name)
+	}
+	return providers
 }
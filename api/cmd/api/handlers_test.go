@@ -0,0 +1,405 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// client sends requests to the server built by newTestServer, which runs on
+// an in-memory SQLite database. It keeps the cookies it is sent.
+type client struct {
+	t     *testing.T
+	h     http.Handler
+	token string
+
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	return newClientWith(t, nil)
+}
+
+func newClientWith(t *testing.T, env map[string]string) *client {
+	t.Helper()
+	server, r := newTestServerWith(t, env)
+	return &client{t: t, h: server.Handler(r), cookies: make(map[string]*http.Cookie)}
+}
+
+func (c *client) do(method, path string, body any) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	c.mu.Lock()
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	c.mu.Unlock()
+
+	w := httptest.NewRecorder()
+	c.h.ServeHTTP(w, req)
+
+	c.mu.Lock()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(c.cookies, cookie.Name)
+		} else {
+			c.cookies[cookie.Name] = cookie
+		}
+	}
+	c.mu.Unlock()
+	return w
+}
+
+func (c *client) decode(w *httptest.ResponseRecorder, v any) {
+	c.t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		c.t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
+
+// login registers an account and keeps its access token.
+func (c *client) login(email string) {
+	c.t.Helper()
+
+	account := gin.H{"email": email, "password": "Passw0rd!xyz", "name": "Test"}
+	if w := c.do(http.MethodPost, "/api/v1/auth/register", account); w.Code != http.StatusOK && w.Code != http.StatusCreated {
+		c.t.Fatalf("register: %d %s", w.Code, w.Body)
+	}
+	w := c.do(http.MethodPost, "/api/v1/auth/login", account)
+	if w.Code != http.StatusOK {
+		c.t.Fatalf("login: %d %s", w.Code, w.Body)
+	}
+	var body struct {
+		AccessToken string `json:"accessToken"`
+	}
+	c.decode(w, &body)
+	c.token = body.AccessToken
+}
+
+func TestShortenAndRedirect(t *testing.T) {
+	c := newClient(t)
+	c.login("owner@example.com")
+
+	w := c.do(http.MethodPost, "/api/v1/short", gin.H{"url": "https://example.com/page", "slug": "my-page"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	c.decode(w, &created)
+	if created.Code != "my-page" {
+		t.Errorf("code = %q", created.Code)
+	}
+
+	w = c.do(http.MethodGet, "/short/my-page", nil)
+	if w.Code != http.StatusFound && w.Code != http.StatusMovedPermanently {
+		t.Fatalf("redirect: %d %s", w.Code, w.Body)
+	}
+	if got := w.Header().Get("Location"); got != "https://example.com/page" {
+		t.Errorf("Location = %q", got)
+	}
+
+	if w := c.do(http.MethodGet, "/short/unknown-code", nil); w.Code != http.StatusNotFound {
+		t.Errorf("unknown code: %d %s, want 404", w.Code, w.Body)
+	}
+}
+
+func TestShortenSlugTaken(t *testing.T) {
+	c := newClient(t)
+	c.login("owner@example.com")
+
+	link := gin.H{"url": "https://example.com/", "slug": "taken"}
+	if w := c.do(http.MethodPost, "/api/v1/short", link); w.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	if w := c.do(http.MethodPost, "/api/v1/short", link); w.Code != http.StatusConflict {
+		t.Errorf("second create: %d %s, want 409", w.Code, w.Body)
+	}
+}
+
+func TestAuthRequired(t *testing.T) {
+	c := newClient(t)
+
+	if w := c.do(http.MethodGet, "/api/v1/short", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("list without a token: %d", w.Code)
+	}
+	c.login("user@example.com")
+	if w := c.do(http.MethodGet, "/api/v1/admin/cache/stats", nil); w.Code != http.StatusForbidden {
+		t.Errorf("admin route as a user: %d", w.Code)
+	}
+}
+
+// Concurrent requests take turns on the single connection of the in-memory
+// database.
+func TestConcurrentRequests(t *testing.T) {
+	c := newClient(t)
+	c.login("owner@example.com")
+
+	// Creating stays within the burst of the default shorten limit.
+	const links, redirects = 10, 100
+	status := make([]int, links+redirects)
+
+	var wg sync.WaitGroup
+	for i := range links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			link := gin.H{"url": fmt.Sprintf("https://example.com/%d", i), "slug": fmt.Sprintf("link-%d", i), "maxClicks": redirects}
+			status[i] = c.do(http.MethodPost, "/api/v1/short", link).Code
+		}()
+	}
+	wg.Wait()
+
+	// Links with a click limit count every redirect.
+	for i := range redirects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status[links+i] = c.do(http.MethodGet, fmt.Sprintf("/short/link-%d", i%links), nil).Code
+		}()
+	}
+	wg.Wait()
+
+	for i, code := range status[:links] {
+		if code != http.StatusOK {
+			t.Errorf("create %d: status %d", i, code)
+		}
+	}
+	for i, code := range status[links:] {
+		if code != http.StatusFound {
+			t.Errorf("redirect %d: status %d", i, code)
+		}
+	}
+
+	w := c.do(http.MethodGet, "/api/v1/short", nil)
+	var list struct {
+		Items []struct {
+			ClickCount int `json:"clickCount"`
+		} `json:"items"`
+	}
+	c.decode(w, &list)
+	clicks := 0
+	for _, item := range list.Items {
+		clicks += item.ClickCount
+	}
+	if len(list.Items) != links || clicks != redirects {
+		t.Errorf("listed %d links with %d clicks, want %d with %d", len(list.Items), clicks, links, redirects)
+	}
+}
+
+func TestForgotPassword(t *testing.T) {
+	mail := filepath.Join(t.TempDir(), "mail.log")
+	c := newClientWith(t, map[string]string{"MAIL_LOG_FILE": mail})
+	c.login("owner@example.com")
+	c.token = ""
+
+	// Both answers are the same; only the registered address gets mail.
+	for _, email := range []string{"nobody@example.com", "owner@example.com"} {
+		w := c.do(http.MethodPost, "/api/v1/auth/password/forgot", gin.H{"email": email})
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("forgot %s: %d %s", email, w.Code, w.Body)
+		}
+	}
+
+	// Links point at APP_URL, never at the Host the request named.
+	link := regexp.MustCompile(`https://app\.example\.com/reset-password\?token=(\S+)`)
+	var sent []byte
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		sent, _ = os.ReadFile(mail)
+		if link.Match(sent) || time.Now().After(deadline) {
+			break
+		}
+	}
+	match := link.FindSubmatch(sent)
+	if match == nil {
+		t.Fatalf("no reset email sent:\n%s", sent)
+	}
+	if bytes.Contains(sent, []byte("nobody@example.com")) {
+		t.Errorf("mail sent to an unknown address:\n%s", sent)
+	}
+
+	token, err := url.QueryUnescape(string(match[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reset := gin.H{"token": token, "password": "N3w-passw0rd!"}
+	if w := c.do(http.MethodPost, "/api/v1/auth/password/reset", reset); w.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", w.Code, w.Body)
+	}
+	login := gin.H{"email": "owner@example.com", "password": "N3w-passw0rd!"}
+	if w := c.do(http.MethodPost, "/api/v1/auth/login", login); w.Code != http.StatusOK {
+		t.Errorf("login with the new password: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestBulkPartial(t *testing.T) {
+	c := newClient(t)
+	c.login("owner@example.com")
+
+	if w := c.do(http.MethodPost, "/api/v1/short", gin.H{"url": "https://example.com/", "slug": "taken"}); w.Code != http.StatusOK {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+
+	rows := []gin.H{
+		{"url": "https://example.com/a", "slug": "row-one"},
+		{"url": "https://example.com/b", "slug": "taken"},
+		{"url": "not a url"},
+		{"url": "https://example.com/d"},
+	}
+
+	// Atomic imports create nothing when a row fails.
+	if w := c.do(http.MethodPost, "/api/v1/short/bulk", rows); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("atomic: %d %s", w.Code, w.Body)
+	}
+
+	w := c.do(http.MethodPost, "/api/v1/short/bulk?mode=partial", rows)
+	if w.Code != http.StatusOK {
+		t.Fatalf("partial: %d %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Created []struct {
+			Row int `json:"row"`
+		} `json:"created"`
+		Errors []struct {
+			Row   int    `json:"row"`
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	c.decode(w, &resp)
+
+	// Rows are numbered from 1.
+	if len(resp.Created) != 2 || resp.Created[0].Row != 1 || resp.Created[1].Row != 4 {
+		t.Errorf("created %+v, want rows 1 and 4", resp.Created)
+	}
+	if len(resp.Errors) != 2 || resp.Errors[0].Row != 2 || resp.Errors[0].Field != "slug" || resp.Errors[1].Row != 3 {
+		t.Errorf("errors %+v, want the slug of row 2 and row 3", resp.Errors)
+	}
+}
+
+func TestPatchShortLink(t *testing.T) {
+	c := newClient(t)
+	c.login("owner@example.com")
+
+	create := func(body gin.H) uint {
+		t.Helper()
+		w := c.do(http.MethodPost, "/api/v2/short", body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create: %d %s", w.Code, w.Body)
+		}
+		var link struct {
+			ID uint `json:"id"`
+		}
+		c.decode(w, &link)
+		return link.ID
+	}
+	create(gin.H{"url": "https://example.com/", "slug": "taken"})
+	id := create(gin.H{"url": "https://example.com/", "slug": "mine", "expiresAt": time.Now().Add(200 * time.Millisecond)})
+	path := fmt.Sprintf("/api/v1/short/%d", id)
+
+	if w := c.do(http.MethodPatch, path, gin.H{"slug": "taken"}); w.Code != http.StatusConflict {
+		t.Errorf("taken slug: %d %s", w.Code, w.Body)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	// The expiry has passed; changing something else does not re-check it.
+	hourAgo := time.Now().Add(-time.Hour)
+	if w := c.do(http.MethodPatch, path, gin.H{"activatesAt": hourAgo}); w.Code != http.StatusOK {
+		t.Errorf("activatesAt on an expired link: %d %s", w.Code, w.Body)
+	}
+	if w := c.do(http.MethodPatch, path, gin.H{"maxClicks": 5}); w.Code != http.StatusOK {
+		t.Errorf("maxClicks on an expired link: %d %s", w.Code, w.Body)
+	}
+
+	// A new expiry must lie ahead, and after the activation.
+	if w := c.do(http.MethodPatch, path, gin.H{"expiresAt": hourAgo}); w.Code != http.StatusBadRequest {
+		t.Errorf("past expiresAt: %d %s", w.Code, w.Body)
+	}
+	if w := c.do(http.MethodPatch, path, gin.H{"activatesAt": time.Now().Add(time.Hour)}); w.Code != http.StatusBadRequest {
+		t.Errorf("activatesAt after the expiry: %d %s", w.Code, w.Body)
+	}
+	inTwoHours := time.Now().Add(2 * time.Hour)
+	if w := c.do(http.MethodPatch, path, gin.H{"activatesAt": time.Now().Add(time.Hour), "expiresAt": inTwoHours}); w.Code != http.StatusOK {
+		t.Errorf("new schedule: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	c := newClientWith(t, map[string]string{"API_DEPRECATIONS": "v1 deprecated=2026-06-01 sunset=2027-01-01"})
+
+	for _, tt := range []struct {
+		path        string
+		header      string
+		status      int
+		served      string
+		vary        bool
+		deprecation string
+		sunset      string
+		code        string
+	}{
+		{path: "/api/openapi.json", status: http.StatusOK, served: "v1", vary: true, deprecation: "@1780272000", sunset: "Fri, 01 Jan 2027 00:00:00 GMT"},
+		{path: "/api/openapi.json", header: "2", status: http.StatusOK, served: "v2", vary: true},
+		{path: "/api/v1/openapi.json", header: "v2", status: http.StatusOK, served: "v1", deprecation: "@1780272000", sunset: "Fri, 01 Jan 2027 00:00:00 GMT"},
+		{path: "/api/v2/openapi.json", status: http.StatusOK, served: "v2"},
+		{path: "/api/v9/openapi.json", status: http.StatusNotFound, code: "unsupported_api_version"},
+		{path: "/api/openapi.json", header: "v9", status: http.StatusBadRequest, vary: true, code: "unsupported_api_version"},
+		{path: "/api/v2/nothing", status: http.StatusNotFound, code: "not_found"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Accept-Version", tt.header)
+		}
+		w := httptest.NewRecorder()
+		c.h.ServeHTTP(w, req)
+
+		name := tt.path + " " + tt.header
+		if w.Code != tt.status {
+			t.Errorf("%s: %d %s, want %d", name, w.Code, w.Body, tt.status)
+			continue
+		}
+		if got := w.Header().Get("API-Version"); got != tt.served {
+			t.Errorf("%s: served by %q, want %q", name, got, tt.served)
+		}
+		if vary := w.Header().Get("Vary") == "Accept-Version"; vary != tt.vary {
+			t.Errorf("%s: Vary = %q", name, w.Header().Get("Vary"))
+		}
+		if got := w.Header().Get("Deprecation"); got != tt.deprecation {
+			t.Errorf("%s: Deprecation = %q, want %q", name, got, tt.deprecation)
+		}
+		if got := w.Header().Get("Sunset"); got != tt.sunset {
+			t.Errorf("%s: Sunset = %q, want %q", name, got, tt.sunset)
+		}
+		if tt.code != "" {
+			if got := errorCode(t, w.Body.Bytes()); got != tt.code {
+				t.Errorf("%s: code %q, want %q", name, got, tt.code)
+			}
+		}
+	}
+}
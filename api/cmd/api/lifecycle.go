@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShutdownHook releases a resource once the server stopped taking requests.
+// It should give up when ctx is done.
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name string
+	fn   ShutdownHook
+}
+
+// OnShutdown registers a hook to run on shutdown. Hooks run in reverse
+// order of registration, so a resource registered early, like the database,
+// outlives the ones registered later that still write to it.
+func (s *ApiServer) OnShutdown(name string, fn ShutdownHook) {
+	s.hooksMu.Lock()
+	defer s.hooksMu.Unlock()
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// Start serves r until SIGINT or SIGTERM, then shuts down gracefully. A
+// second signal kills the process right away.
+func (s *ApiServer) Start(r *gin.Engine) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	return s.Serve(ctx, r)
+}
+
+// Serve serves r until ctx is done. In-flight requests then get the shutdown
+// timeout to finish before the shutdown hooks run.
+func (s *ApiServer) Serve(ctx context.Context, r *gin.Engine) error {
+	if s.sweeper != nil {
+		s.sweeper.Start()
+	}
+
+	log.Printf("Starting API server on %s", s.cfg.Server.Port)
+	server := &http.Server{
+		Addr:           s.cfg.Server.Port,
+		Handler:        s.Handler(r),
+		ReadTimeout:    10 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		IdleTimeout:    time.Minute,
+		MaxHeaderBytes: 1 << 20,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		s.Close()
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, draining requests...")
+	if s.checker != nil {
+		s.checker.Drain()
+	}
+
+	// Give load balancers time to see the failing readiness probe before
+	// the listener closes.
+	if delay := s.cfg.Server.ShutdownDelay; delay > 0 {
+		time.Sleep(delay)
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := server.Shutdown(drainCtx); err != nil {
+		errs = append(errs, fmt.Errorf("draining requests: %w", err))
+		server.Close()
+	}
+	if err := s.Close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	log.Printf("Server stopped")
+	return errors.Join(errs...)
+}
+
+// Handler serves r, picking the version of /api paths that name none by
+// the Accept-Version header.
+func (s *ApiServer) Handler(r *gin.Engine) http.Handler {
+	return s.versions.Negotiate(r)
+}
+
+// Close runs the shutdown hooks once. It must be called once the server
+// stopped handling requests; Serve does so itself.
+func (s *ApiServer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.hooksMu.Lock()
+		hooks := s.hooks
+		s.hooksMu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		var errs []error
+		for i := len(hooks) - 1; i >= 0; i-- {
+			if hookErr := runHook(ctx, hooks[i].fn); hookErr != nil {
+				log.Printf("Shutdown hook %s failed: %v", hooks[i].name, hookErr)
+				errs = append(errs, fmt.Errorf("%s: %w", hooks[i].name, hookErr))
+			}
+		}
+		err = errors.Join(errs...)
+	})
+	return err
+}
+
+// runHook stops waiting for a hook once ctx is done, since hooks wrapping
+// blocking calls may not watch it themselves.
+func runHook(ctx context.Context, fn ShutdownHook) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"crypto/subtle"
+	"go-api/internal/apperr"
+	"go-api/internal/metrics"
+	"go-api/internal/middleware"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newMetrics registers the process wide metrics. Routers get the metrics
+// they record passed in.
+func (s *ApiServer) newMetrics() {
+	s.metrics = metrics.NewRegistry()
+
+	s.httpMetrics = middleware.HTTPMetrics{
+		Duration: s.metrics.Histogram("http_request_duration_seconds", "Time spent serving HTTP requests.", metrics.DefBuckets, "method", "route"),
+		Requests: s.metrics.Counter("http_requests_total", "HTTP requests by status code.", "method", "route", "status"),
+	}
+	s.redirects = s.metrics.Counter("shortlink_redirects_total", "Short link lookups by outcome.", "outcome")
+	s.versionUsage = s.metrics.Counter("api_version_requests_total", "API requests by version, and whether the path, the Accept-Version header or the default picked it.", "version", "selected_by")
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		log.Fatalf("Failed to get database pool: %v", err)
+	}
+	s.metrics.GaugeFunc("db_pool_open_connections", "Open database connections, in use or idle.", func() float64 {
+		return float64(sqlDB.Stats().OpenConnections)
+	})
+	s.metrics.GaugeFunc("db_pool_in_use_connections", "Database connections currently in use.", func() float64 {
+		return float64(sqlDB.Stats().InUse)
+	})
+	s.metrics.GaugeFunc("db_pool_idle_connections", "Idle database connections.", func() float64 {
+		return float64(sqlDB.Stats().Idle)
+	})
+	s.metrics.GaugeFunc("db_pool_max_open_connections", "Maximum number of open database connections.", func() float64 {
+		return float64(sqlDB.Stats().MaxOpenConnections)
+	})
+	s.metrics.CounterFunc("db_pool_wait_count_total", "Times a query waited for a free database connection.", func() float64 {
+		return float64(sqlDB.Stats().WaitCount)
+	})
+	s.metrics.CounterFunc("db_pool_wait_duration_seconds_total", "Time spent waiting for free database connections.", func() float64 {
+		return sqlDB.Stats().WaitDuration.Seconds()
+	})
+
+	s.metrics.GaugeFunc("go_goroutines", "Number of goroutines.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// metricsHandler serves the registry. When a metrics token is configured,
+// scrapers must send it as a bearer token.
+func (s *ApiServer) metricsHandler() gin.HandlerFunc {
+	token := s.cfg.Server.MetricsToken
+	handler := s.metrics.Handler()
+
+	return func(c *gin.Context) {
+		if token != "" {
+			given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				apperr.Abort(c, middleware.ErrUnauthorized)
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
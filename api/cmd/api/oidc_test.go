@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"go-api/internal/oidc/oidctest"
+)
+
+func newOIDCClient(t *testing.T) (*client, *oidctest.Server) {
+	t.Helper()
+
+	srv, err := oidctest.NewServer(oidctest.Options{ClientID: "client", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	return newClientWith(t, map[string]string{
+		"OIDC_PROVIDERS":          "test",
+		"OIDC_TEST_ISSUER":        srv.Issuer(),
+		"OIDC_TEST_CLIENT_ID":     "client",
+		"OIDC_TEST_CLIENT_SECRET": "secret",
+	}), srv
+}
+
+// startLogin starts a login and follows it through the provider. It returns
+// the callback the provider sent the browser back to.
+func startLogin(t *testing.T, c *client) *url.URL {
+	t.Helper()
+
+	w := c.do(http.MethodGet, "/api/v1/auth/oidc/test/login", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: %d %s", w.Code, w.Body)
+	}
+
+	browser := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := browser.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if callback.Path != "/api/v1/auth/oidc/test/callback" {
+		t.Fatalf("provider redirected to %s", callback)
+	}
+	return callback
+}
+
+func errorCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var resp struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(body, &resp)
+	return resp.Code
+}
+
+func TestOIDCLogin(t *testing.T) {
+	c, srv := newOIDCClient(t)
+	srv.SetUser(oidctest.User{Subject: "sub-1", Email: "a@example.com", EmailVerified: true, Name: "A"})
+
+	var first struct {
+		UserID      uint   `json:"userId"`
+		AccessToken string `json:"accessToken"`
+	}
+	w := c.do(http.MethodGet, startLogin(t, c).RequestURI(), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("callback: %d %s", w.Code, w.Body)
+	}
+	c.decode(w, &first)
+	if first.UserID == 0 || first.AccessToken == "" {
+		t.Fatalf("callback response %s", w.Body)
+	}
+
+	// The token works, and the next login finds the same account.
+	c.token = first.AccessToken
+	if w := c.do(http.MethodGet, "/api/v1/short", nil); w.Code != http.StatusOK {
+		t.Errorf("authenticated request: %d %s", w.Code, w.Body)
+	}
+	c.token = ""
+
+	var second struct {
+		UserID uint `json:"userId"`
+	}
+	c.decode(c.do(http.MethodGet, startLogin(t, c).RequestURI(), nil), &second)
+	if second.UserID != first.UserID {
+		t.Errorf("second login signed in user %d, want %d", second.UserID, first.UserID)
+	}
+}
+
+func TestOIDCCallbackStateMismatch(t *testing.T) {
+	c, _ := newOIDCClient(t)
+
+	callback := startLogin(t, c)
+	query := callback.Query()
+	query.Set("state", "forged")
+	callback.RawQuery = query.Encode()
+
+	w := c.do(http.MethodGet, callback.RequestURI(), nil)
+	if w.Code != http.StatusBadRequest || errorCode(t, w.Body.Bytes()) != "invalid_login_state" {
+		t.Errorf("forged state: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestOIDCCallbackWithoutStateCookie(t *testing.T) {
+	c, _ := newOIDCClient(t)
+
+	// The callback has to come back to the browser that started the login.
+	callback := startLogin(t, c)
+	other := &client{t: t, h: c.h, cookies: make(map[string]*http.Cookie)}
+
+	w := other.do(http.MethodGet, callback.RequestURI(), nil)
+	if w.Code != http.StatusBadRequest || errorCode(t, w.Body.Bytes()) != "invalid_login_state" {
+		t.Errorf("callback in another browser: %d %s", w.Code, w.Body)
+	}
+
+	// Nor can a state be used twice.
+	if w := c.do(http.MethodGet, callback.RequestURI(), nil); w.Code != http.StatusOK {
+		t.Fatalf("callback: %d %s", w.Code, w.Body)
+	}
+	c.cookies["oidc_state"] = &http.Cookie{Name: "oidc_state", Value: callback.Query().Get("state")}
+	if w := c.do(http.MethodGet, callback.RequestURI(), nil); w.Code != http.StatusBadRequest {
+		t.Errorf("replayed callback: %d %s", w.Code, w.Body)
+	}
+}
+
+// A code is bound to the PKCE challenge of the login it was issued to, so
+// it cannot complete another one.
+func TestOIDCCallbackPKCE(t *testing.T) {
+	c, _ := newOIDCClient(t)
+
+	stolen := startLogin(t, c).Query().Get("code")
+	callback := startLogin(t, c)
+	query := callback.Query()
+	query.Set("code", stolen)
+	callback.RawQuery = query.Encode()
+
+	w := c.do(http.MethodGet, callback.RequestURI(), nil)
+	if w.Code != http.StatusBadGateway || errorCode(t, w.Body.Bytes()) != "identity_provider_failed" {
+		t.Errorf("code of another login: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"go-api/entities"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/health"
+	"go-api/internal/openapi"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newSpec starts the description of the API, teaching it the types that
+// do not describe themselves.
+func newSpec(version apiversion.Version) *openapi.Spec {
+	spec := openapi.New(openapi.Info{
+		Title:       "go-api",
+		Description: "URL shortener with accounts, workspaces, custom domains and click analytics.",
+		Version:     version.Name,
+	})
+
+	spec.Errors("The request failed. The body is an RFC 7807 problem whose code says why.",
+		openapi.Content{apperr.ContentType: apperr.Problem{}})
+	spec.Override(entities.OptionalTime{}, openapi.Schema{Type: "string", Format: "date-time", Nullable: true})
+	spec.Name(health.Report{}, "HealthReport")
+	spec.Name(health.Component{}, "HealthComponent")
+	return spec
+}
+
+// describeServerRoutes adds the routes the server registers itself.
+func describeServerRoutes(base, versioned *openapi.Group) {
+	base.Group("", "meta").GET("/metrics", openapi.Op{
+		ID:          "getMetrics",
+		Summary:     "Expose metrics in the Prometheus text format",
+		Description: "Requires the metrics token as a bearer token when one is configured.",
+		Responses:   openapi.Responses{http.StatusOK: openapi.Content{"text/plain": ""}},
+	})
+	versioned.Group("", "meta").GET("/openapi.json", openapi.Op{
+		ID:        "getOpenAPI",
+		Summary:   "Describe this version of the API",
+		Responses: openapi.Responses{http.StatusOK: map[string]any{}},
+	})
+}
+
+// OpenAPI returns the description of a version of the API registered by
+// Init, or nil when the version is not served.
+func (s *ApiServer) OpenAPI(version string) *openapi.Document {
+	return s.openapi[version]
+}
+
+// openAPIHandler serves the description of version, encoded once.
+func (s *ApiServer) openAPIHandler(version string) gin.HandlerFunc {
+	data, err := json.Marshal(s.openapi[version])
+	if err != nil {
+		log.Fatalf("Failed to encode the OpenAPI description: %v", err)
+	}
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"go-api/database/migrations"
+	"go-api/internal/cache"
+	"go-api/internal/config"
+	"go-api/internal/database"
+	"go-api/internal/migrator"
+	"go-api/internal/openapi"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var update = flag.Bool("update", false, "rewrite the OpenAPI description the client is generated from")
+
+// clientSpec is the description the client package is generated from, of
+// clientVersion.
+const (
+	clientSpec    = "../../client/openapi.json"
+	clientVersion = "v1"
+)
+
+func newTestServer(t *testing.T) (*ApiServer, *gin.Engine) {
+	t.Helper()
+	return newTestServerWith(t, nil)
+}
+
+// newTestServerWith builds a server on a migrated in-memory database, with
+// extra environment on top of the test defaults.
+func newTestServerWith(t *testing.T, extra map[string]string) (*ApiServer, *gin.Engine) {
+	t.Helper()
+
+	env := map[string]string{
+		"JWT_SECRET_KEY":       strings.Repeat("k", config.MinJWTSecretLength),
+		"DB_DRIVER":            database.SQLite,
+		"DB_CONNECTION_STRING": ":memory:",
+		"GIN_MODE":             gin.TestMode,
+		"ANALYTICS_IP_SALT":    "test-salt",
+		"MAIL_LOG_FILE":        os.DevNull,
+		"APP_URL":              "https://app.example.com",
+	}
+	maps.Copy(env, extra)
+	cfg, err := config.Load(config.Options{
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+	})
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+
+	db, err := database.Open(cfg.Database.Driver, cfg.Database.DSN, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrator.New(db, all, migrator.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background(), 0); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	server := NewApiServer(cfg, db, cache.NewLRU(16))
+	r := server.Init()
+	t.Cleanup(func() { server.Close() })
+	return server, r
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	server, r := newTestServer(t)
+
+	for _, version := range server.versions.All() {
+		t.Run(version.Name, func(t *testing.T) {
+			testDescribesEveryRoute(t, server.OpenAPI(version.Name), r.Routes(), version.Prefix())
+		})
+	}
+}
+
+// testDescribesEveryRoute checks doc against the routes outside /api and
+// the ones under prefix.
+func testDescribesEveryRoute(t *testing.T, doc *openapi.Document, routes gin.RoutesInfo, prefix string) {
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, "/api/") && !strings.HasPrefix(route.Path, prefix+"/") {
+			continue
+		}
+		path := openapi.Path(route.Path)
+		registered[route.Method+" "+path] = true
+
+		item := doc.Paths[path]
+		if item == nil || item.Operation(route.Method) == nil {
+			t.Errorf("%s %s is registered but missing from the OpenAPI description", route.Method, route.Path)
+		}
+	}
+
+	ids := make(map[string]string)
+	for path, item := range doc.Paths {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch} {
+			op := item.Operation(method)
+			if op == nil {
+				continue
+			}
+			if !registered[method+" "+path] {
+				t.Errorf("%s %s is described but not registered", method, path)
+			}
+			if op.OperationID == "" {
+				t.Errorf("%s %s has no operation ID", method, path)
+			} else if other, taken := ids[op.OperationID]; taken {
+				t.Errorf("%s %s reuses the operation ID %s of %s", method, path, op.OperationID, other)
+			}
+			ids[op.OperationID] = method + " " + path
+		}
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	server, r := newTestServer(t)
+
+	for _, version := range server.versions.All() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, version.Prefix()+"/openapi.json", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", version.Name, w.Code)
+		}
+
+		want, err := json.Marshal(server.OpenAPI(version.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(w.Body.Bytes(), want) {
+			t.Errorf("%s: served description differs from the one built by Init", version.Name)
+		}
+	}
+}
+
+// TestOpenAPIClientSpec keeps the description the client is generated from
+// current. Run with -update to rewrite it, then go generate ./client.
+func TestOpenAPIClientSpec(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	got, err := json.MarshalIndent(server.OpenAPI(clientVersion), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.WriteFile(clientSpec, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(clientSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is out of date; run go test ./cmd/api -run TestOpenAPIClientSpec -update and go generate ./client", clientSpec)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go-api/internal/openapi"
+	"go/format"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// methods lists the methods of a path item in the order they are written.
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+type generator struct {
+	doc     *openapi.Document
+	body    bytes.Buffer
+	imports map[string]bool
+	// types holds the names of the generated types, to catch collisions.
+	types map[string]bool
+	err   error
+}
+
+// generate writes the source of a client with a struct per component
+// schema and a method per operation.
+func generate(doc *openapi.Document, pkg string) ([]byte, error) {
+	g := &generator{
+		doc:     doc,
+		imports: map[string]bool{"context": true, "net/http": true},
+		types:   make(map[string]bool),
+	}
+
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+		g.types[name] = true
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema := doc.Components.Schemas[name]
+		if schema.Type != "object" || schema.AdditionalProperties != nil {
+			g.fail("component %s is not an object", name)
+			continue
+		}
+		g.structType(name, "", schema.Properties, schema.Required)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		for _, method := range methods {
+			if op := doc.Paths[path].Operation(method); op != nil {
+				g.operation(method, path, op)
+			}
+		}
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by clientgen from %s %s. DO NOT EDIT.\n\n", doc.Info.Title, doc.Info.Version)
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", pkg)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&src, "\t%q\n", path)
+	}
+	src.WriteString(")\n")
+	src.Write(g.body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting: %w", err)
+	}
+	return formatted, nil
+}
+
+func (g *generator) fail(format string, args ...any) {
+	if g.err == nil {
+		g.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+// declare claims a name for a generated type.
+func (g *generator) declare(name string) {
+	if g.types[name] {
+		g.fail("two types named %s", name)
+	}
+	g.types[name] = true
+}
+
+// structType writes a struct with a field per property. Optional
+// properties are left out of requests when zero.
+func (g *generator) structType(name, doc string, props map[string]*openapi.Schema, required []string) {
+	g.printf("\n")
+	if doc != "" {
+		g.printf("// %s\n", doc)
+	}
+	g.printf("type %s struct {\n", name)
+
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		schema := props[key]
+		if len(schema.Enum) > 0 {
+			g.printf("\t// %s is one of %s.\n", goName(key), strings.Join(schema.Enum, ", "))
+		}
+		tag := key
+		if !slices.Contains(required, key) {
+			tag += ",omitempty"
+		}
+		g.printf("\t%s %s `json:%q`\n", goName(key), g.goType(schema), tag)
+	}
+	g.printf("}\n")
+}
+
+func (g *generator) goType(s *openapi.Schema) string {
+	if name := s.RefName(); name != "" {
+		return name
+	}
+	if len(s.AllOf) == 1 {
+		t := g.goType(s.AllOf[0])
+		if s.Nullable {
+			return "*" + t
+		}
+		return t
+	}
+
+	var t string
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			t = "time.Time"
+		case "binary", "byte":
+			return "[]byte"
+		default:
+			t = "string"
+		}
+	case "integer":
+		t = "int"
+		if s.Format == "int64" {
+			t = "int64"
+		}
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		return "[]" + g.goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties)
+		}
+		if len(s.Properties) == 0 {
+			return "map[string]any"
+		}
+		g.fail("inline objects are not supported")
+		return "any"
+	case "":
+		return "any"
+	default:
+		g.fail("unsupported schema type %s", s.Type)
+		return "any"
+	}
+
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// result is what the method of an operation returns besides the error.
+type result struct {
+	// goType is empty for operations without a response body.
+	goType string
+	// raw operations return the *http.Response, for bodies that are not
+	// JSON and for redirects.
+	raw bool
+}
+
+func (g *generator) operation(method, path string, op *openapi.Operation) {
+	name := goName(op.OperationID)
+	if name == "" {
+		g.fail("%s %s has no operation ID", method, path)
+		return
+	}
+
+	var args, pathArgs []string
+	var query []*openapi.Parameter
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			arg := lowerFirst(goName(p.Name))
+			args = append(args, arg+" "+g.goType(p.Schema))
+			pathArgs = append(pathArgs, p.Name, arg)
+		case "query":
+			query = append(query, p)
+		}
+	}
+
+	paramsType := name + "Params"
+	if len(query) > 0 {
+		g.declare(paramsType)
+		props := make(map[string]*openapi.Schema, len(query))
+		var required []string
+		for _, p := range query {
+			props[p.Name] = p.Schema
+			if p.Required {
+				required = append(required, p.Name)
+			}
+		}
+		g.structType(paramsType, fmt.Sprintf("%s holds the query parameters of %s.", paramsType, name), props, required)
+		args = append(args, "params *"+paramsType)
+	}
+
+	hasBody := false
+	if op.RequestBody != nil {
+		if media := op.RequestBody.Content["application/json"]; media != nil {
+			t := g.goType(media.Schema)
+			if !op.RequestBody.Required {
+				t = "*" + t
+			}
+			args = append(args, "body "+t)
+			hasBody = true
+		}
+	}
+
+	res := g.result(name, op)
+
+	g.printf("\n// %s is %s %s.", name, method, path)
+	for _, text := range []string{op.Summary, op.Description} {
+		if text != "" {
+			g.printf("\n//\n// %s", sentence(text))
+		}
+	}
+	if res.raw {
+		g.printf("\n//\n// The caller must close the body of the response.")
+	}
+	g.printf("\n")
+
+	returns := "error"
+	switch {
+	case res.raw:
+		returns = "(*http.Response, error)"
+	case res.goType != "":
+		returns = "(" + res.goType + ", error)"
+	}
+	g.printf("func (c *Client) %s(%s) %s {\n", name, strings.Join(append([]string{"ctx context.Context"}, args...), ", "), returns)
+
+	pathExpr := g.pathExpr(path, pathArgs)
+	queryExpr := "nil"
+	if len(query) > 0 {
+		g.imports["net/url"] = true
+		queryExpr = "query"
+		g.printf("\tquery := url.Values{}\n\tif params != nil {\n")
+		for _, p := range query {
+			g.printf("\t\tsetQuery(query, %q, params.%s)\n", p.Name, goName(p.Name))
+		}
+		g.printf("\t}\n")
+	}
+	bodyExpr := "nil"
+	if hasBody {
+		bodyExpr = "body"
+	}
+	call := fmt.Sprintf("ctx, %s, %s, %s, %s", methodConst(method), pathExpr, queryExpr, bodyExpr)
+
+	switch {
+	case res.raw:
+		g.printf("\treturn c.send(%s)\n", call)
+	case res.goType == "":
+		g.printf("\treturn c.do(%s, nil)\n", call)
+	case strings.HasPrefix(res.goType, "*"):
+		g.printf("\tvar out %s\n", res.goType[1:])
+		g.printf("\tif err := c.do(%s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", call)
+		g.printf("\treturn &out, nil\n")
+	default:
+		g.printf("\tvar out %s\n", res.goType)
+		g.printf("\terr := c.do(%s, &out)\n\treturn out, err\n", call)
+	}
+	g.printf("}\n")
+}
+
+// result picks the successful response of op: the first 2xx one, or a
+// redirect.
+func (g *generator) result(name string, op *openapi.Operation) result {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var response *openapi.Response
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			response = op.Responses[code]
+			break
+		}
+		if strings.HasPrefix(code, "3") {
+			return result{raw: true}
+		}
+	}
+	if response == nil || len(response.Content) == 0 {
+		return result{}
+	}
+
+	media := response.Content["application/json"]
+	if media == nil || len(response.Content) > 1 {
+		return result{raw: true}
+	}
+	if len(media.Schema.OneOf) > 0 {
+		return result{goType: "*" + g.mergedType(name+"Response", media.Schema.OneOf)}
+	}
+
+	t := g.goType(media.Schema)
+	if media.Schema.RefName() != "" {
+		t = "*" + t
+	}
+	return result{goType: t}
+}
+
+// mergedType writes a struct with the properties of all schemas, for
+// responses that are one of several objects.
+func (g *generator) mergedType(name string, schemas []*openapi.Schema) string {
+	g.declare(name)
+
+	props := make(map[string]*openapi.Schema)
+	var alternatives []string
+	for _, ref := range schemas {
+		schema := g.doc.Components.Schemas[ref.RefName()]
+		if schema == nil {
+			g.fail("%s: oneOf may only refer to components", name)
+			return name
+		}
+		alternatives = append(alternatives, ref.RefName())
+
+		for key, prop := range schema.Properties {
+			if other, ok := props[key]; ok && g.goType(other) != g.goType(prop) {
+				g.fail("%s: property %s has different types", name, key)
+			}
+			props[key] = prop
+		}
+	}
+
+	doc := fmt.Sprintf("%s is one of %s. Only the fields of the one returned are set.", name, strings.Join(alternatives, ", "))
+	g.structType(name, doc, props, nil)
+	return name
+}
+
+// pathExpr builds the expression of the request path, with the path
+// parameters given as name and argument pairs.
+func (g *generator) pathExpr(path string, params []string) string {
+	parts := []string{}
+	rest := path
+	for i := 0; i < len(params); i += 2 {
+		before, after, ok := strings.Cut(rest, "{"+params[i]+"}")
+		if !ok {
+			g.fail("%s: parameter %s is not in the path", path, params[i])
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%q", before), fmt.Sprintf("pathParam(%s)", params[i+1]))
+		rest = after
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func methodConst(method string) string {
+	return "http.Method" + method[:1] + strings.ToLower(method[1:])
+}
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{
+	"api": true, "id": true, "ip": true, "json": true, "oidc": true,
+	"qr": true, "uri": true, "url": true,
+}
+
+// goName turns a JSON property, parameter or operation ID like shortUrl,
+// error_description or listOIDCProviders into an exported Go name.
+func goName(s string) string {
+	var b strings.Builder
+	for _, word := range words(s) {
+		if initialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		r := []rune(word)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+// words splits s at separators and where a lower case letter is followed
+// by an upper case one. Runs of capitals stay together.
+func words(s string) []string {
+	var result []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+		}
+		current = append(current, r)
+	}
+	flush()
+	return result
+}
+
+func lowerFirst(s string) string {
+	// A name that is an initialism is lowered whole: ID becomes id, not iD.
+	if initialisms[strings.ToLower(s)] {
+		return strings.ToLower(s)
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// sentence ends text with a period.
+func sentence(text string) string {
+	if strings.HasSuffix(text, ".") {
+		return text
+	}
+	return text + "."
+}
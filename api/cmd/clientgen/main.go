@@ -0,0 +1,39 @@
+// Command clientgen writes a typed Go client for the operations of an
+// OpenAPI description written by the API.
+//
+// Usage: clientgen -spec openapi.json -out client.gen.go -package client
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-api/internal/openapi"
+	"log"
+	"os"
+)
+
+func main() {
+	specPath := flag.String("spec", "openapi.json", "OpenAPI description to read")
+	out := flag.String("out", "client.gen.go", "Go file to write")
+	pkg := flag.String("package", "client", "package of the generated code")
+	flag.Parse()
+
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		log.Fatalf("parsing %s: %v", *specPath, err)
+	}
+
+	src, err := generate(&doc, *pkg)
+	if err != nil {
+		log.Fatalf("generating the client: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %s\n", *out)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"go-api/internal/config"
+	"os"
+)
+
+// configCommand prints the effective configuration and where each setting
+// came from. Problems are listed after it, and make the command fail, so it
+// doubles as a check before deploying.
+func configCommand(args []string) {
+	if len(args) != 1 || args[0] != "print" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(config.EnvOptions())
+	if printErr := config.Print(os.Stdout, cfg); printErr != nil {
+		fmt.Fprintf(os.Stderr, "Printing configuration failed: %v\n", printErr)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nInvalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,23 +1,47 @@
 package main
 
 import (
+	"fmt"
 	"go-api/cmd/api"
-	"go-api/internal/env"
 	initializers "go-api/internal/intializers"
+	"log"
+	"os"
 )
 
-func init() {
-	initializers.EnvironmentVariables()
-	initializers.InitializeDB()
-}
+const usage = `Usage: api [command]
+
+Commands:
+  serve          run the API server (default)
+  config print   show the effective configuration with secrets redacted
+`
 
 func main() {
-	port := env.GetString("PORT", ":8080")
-	db := initializers.GetDB()
-	server := api.NewApiServer(port, db)
-	r := server.Init("v1")
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve()
+	case "config":
+		configCommand(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func serve() {
+	cfg := initializers.LoadConfig()
+	initializers.InitializeLogger(cfg.Log)
+	initializers.InitializeDB(cfg.Database)
+	initializers.InitializeCache(cfg)
+
+	server := api.NewApiServer(cfg, initializers.GetDB(), initializers.GetCache())
+	r := server.Init()
 
 	if err := server.Start(r); err != nil {
-		panic(err)
+		log.Fatalf("Server failed: %v", err)
 	}
 }
@@ -1,32 +1,175 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-api/database/migrations"
 	"go-api/database/model"
 	initializers "go-api/internal/intializers"
+	"go-api/internal/migrator"
 	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-func init() {
-	initializers.EnvironmentVariables()
-	initializers.InitializeDB()
+const usage = `Usage: migrate [-make-admin email] [command] [flags]
+
+Commands:
+  up [-to version]      apply pending migrations (default)
+  down [-steps n]       revert the latest applied migrations
+  status                list migrations and whether they are applied
+  create [-dir d] name  write empty up and down SQL files
+`
+
+func main() {
+	makeAdmin := flag.String("make-admin", "", "grant the admin role to the user with this email after migrating")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command, args := "up", flag.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "create" {
+		create(args)
+		return
+	}
+
+	cfg := initializers.LoadConfig()
+	initializers.InitializeDB(cfg.Database)
+	db := initializers.GetDB()
+	ctx := context.Background()
+
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		log.Fatalf("Loading migrations failed: %v", err)
+	}
+	m, err := migrator.New(db, all, migrator.Options{
+		LockTimeout: cfg.Database.MigrationLockTimeout,
+	})
+	if err != nil {
+		log.Fatalf("Loading migrations failed: %v", err)
+	}
+
+	switch command {
+	case "up":
+		up(ctx, m, args)
+		if *makeAdmin != "" {
+			grantAdmin(db, *makeAdmin)
+		}
+	case "down":
+		down(ctx, m, args)
+	case "status":
+		status(ctx, m)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 }
 
-func registerModels() []interface{} {
-	return []interface{}{
-		&model.User{},
-		&model.ShortLink{},
+func up(ctx context.Context, m *migrator.Migrator, args []string) {
+	flags := flag.NewFlagSet("up", flag.ExitOnError)
+	to := flags.Int64("to", 0, "stop after this version instead of applying everything")
+	flags.Parse(args)
+
+	log.Printf("🕧 Applying migrations...")
+	applied, err := m.Up(ctx, *to)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(applied) == 0 {
+		log.Println("✅ Database is up to date")
+		return
 	}
+	log.Printf("✅ Applied %d migrations", len(applied))
 }
 
-func main() {
-	log.Printf("🕧 Migrating database models...")
-	db := initializers.GetDB()
+func down(ctx context.Context, m *migrator.Migrator, args []string) {
+	flags := flag.NewFlagSet("down", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	flags.Parse(args)
 
-	for _, model := range registerModels() {
-		if err := db.AutoMigrate(model); err != nil {
-			log.Fatalf("Migration failed for %T: %v", model, err)
+	if *steps < 1 {
+		log.Fatalf("-steps must be at least 1")
+	}
+
+	reverted, err := m.Down(ctx, *steps)
+	if err != nil {
+		fatal(err)
+	}
+	log.Printf("✅ Reverted %d migrations", len(reverted))
+}
+
+func status(ctx context.Context, m *migrator.Migrator) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+	for _, s := range statuses {
+		appliedAt, note := "pending", ""
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		switch {
+		case s.Missing:
+			note = "not in this build"
+		case s.Modified:
+			note = "modified after it was applied"
 		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, note)
 	}
+	w.Flush()
+}
 
-	log.Println("✅ All migrations applied successfully!")
+func create(args []string) {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	dir := flags.String("dir", "database/migrations/sql", "directory the SQL files are written to")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		log.Fatalf("create takes exactly one migration name")
+	}
+
+	paths, err := migrator.CreateSQL(*dir, flags.Arg(0), time.Now())
+	if err != nil {
+		log.Fatalf("Creating migration failed: %v", err)
+	}
+	for _, path := range paths {
+		log.Printf("📝 Created %s", path)
+	}
+}
+
+func grantAdmin(db *gorm.DB, email string) {
+	user, err := model.GetUserByEmail(db, email)
+	if err != nil {
+		log.Fatalf("Looking up %s failed: %v", email, err)
+	}
+	if err := model.SetUserRole(db, user.ID, model.RoleAdmin); err != nil {
+		log.Fatalf("Granting admin to %s failed: %v", email, err)
+	}
+	log.Printf("👑 %s is now an admin", email)
+}
+
+func fatal(err error) {
+	switch {
+	case errors.Is(err, migrator.ErrChecksumMismatch):
+		log.Fatalf("Migration failed: %v. Applied SQL files must not be edited; add a new migration instead.", err)
+	case errors.Is(err, migrator.ErrUnknownMigration):
+		log.Fatalf("Migration failed: %v. Run a build that includes them.", err)
+	default:
+		log.Fatalf("Migration failed: %v", err)
+	}
 }
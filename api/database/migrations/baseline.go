@@ -0,0 +1,307 @@
+package migrations
+
+import (
+	"time"
+
+	"go-api/internal/migrator"
+	"go-api/internal/shortcode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// The types below are copies of the models as they were when migrations
+// started to be versioned. The baseline must create that schema forever, so
+// they are never changed along with the models; later changes to the schema
+// are SQL files in sql/.
+
+// uuidColumn stores the UUIDs of BaseModel.
+type uuidColumn string
+
+func (uuidColumn) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "uuid"
+	case "mysql":
+		return "char(36)"
+	default:
+		return "text"
+	}
+}
+
+type baseUser struct {
+	gorm.Model
+	Name             string
+	Email            string `gorm:"unique"`
+	Password         string
+	ShortLinks       []baseShortLink `gorm:"foreignKey:UserID;references:ID"`
+	EmailVerifiedAt  *time.Time
+	FailedLoginCount int `gorm:"not null;default:0"`
+	LockedUntil      *time.Time
+	Role             string `gorm:"size:16;not null;default:user"`
+	DisabledAt       *time.Time
+	TOTPSecret       string `gorm:"size:64"`
+	TOTPEnabledAt    *time.Time
+	TOTPLastStep     int64 `gorm:"not null;default:0"`
+}
+
+func (baseUser) TableName() string { return "users" }
+
+type baseShortLink struct {
+	gorm.Model
+	UserID         int    `gorm:"type:int;index"`
+	Code           string `gorm:"size:64;uniqueIndex:idx_short_links_domain_code"`
+	URL            string
+	DomainID       uint  `gorm:"not null;default:0;uniqueIndex:idx_short_links_domain_code"`
+	WorkspaceID    *uint `gorm:"index"`
+	ActivatesAt    *time.Time
+	ExpiresAt      *time.Time `gorm:"index"`
+	MaxClicks      int        `gorm:"not null;default:0"`
+	ClickCount     int        `gorm:"not null;default:0"`
+	Disabled       bool       `gorm:"not null;default:false"`
+	TakenDownAt    *time.Time
+	TakedownReason string `gorm:"size:500"`
+	Legacy         bool   `gorm:"not null;default:false"`
+}
+
+func (baseShortLink) TableName() string { return "short_links" }
+
+type baseClickEvent struct {
+	ID          uint      `gorm:"primaryKey"`
+	ShortLinkID uint      `gorm:"not null;index:idx_click_events_link_time,priority:1"`
+	OccurredAt  time.Time `gorm:"not null;index:idx_click_events_link_time,priority:2"`
+	Referrer    string    `gorm:"size:2048"`
+	UserAgent   string    `gorm:"size:1024"`
+	IPHash      string    `gorm:"size:64"`
+	Device      string    `gorm:"size:32"`
+	Browser     string    `gorm:"size:32"`
+	OS          string    `gorm:"size:32"`
+}
+
+func (baseClickEvent) TableName() string { return "click_events" }
+
+type baseSession struct {
+	ID         uuidColumn `gorm:"primaryKey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	UserID     uint           `gorm:"not null;index"`
+	ExpiresAt  time.Time      `gorm:"not null"`
+	RevokedAt  *time.Time
+	LastUsedAt time.Time
+	UserAgent  string `gorm:"size:512"`
+	IP         string `gorm:"size:64"`
+}
+
+func (baseSession) TableName() string { return "sessions" }
+
+type baseRefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	SessionID uuidColumn `gorm:"not null;index"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (baseRefreshToken) TableName() string { return "refresh_tokens" }
+
+type baseAPIKey struct {
+	gorm.Model
+	UserID     uint   `gorm:"not null;index"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:32;not null"`
+	KeyHash    string `gorm:"size:64;not null;uniqueIndex"`
+	Scopes     string `gorm:"size:255;not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (baseAPIKey) TableName() string { return "api_keys" }
+
+type baseUserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"size:32;not null"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (baseUserToken) TableName() string { return "user_tokens" }
+
+type baseAuditLog struct {
+	ID         uint   `gorm:"primaryKey"`
+	ActorID    uint   `gorm:"not null;index"`
+	Action     string `gorm:"size:64;not null;index"`
+	TargetType string `gorm:"size:32;not null;index:idx_audit_logs_target"`
+	TargetID   uint   `gorm:"not null;index:idx_audit_logs_target"`
+	Reason     string `gorm:"size:500"`
+	Details    string `gorm:"type:text"`
+	IP         string `gorm:"size:64"`
+	CreatedAt  time.Time
+}
+
+func (baseAuditLog) TableName() string { return "audit_logs" }
+
+type baseWorkspace struct {
+	gorm.Model
+	Name        string `gorm:"size:100;not null"`
+	CreatedByID uint   `gorm:"not null"`
+}
+
+func (baseWorkspace) TableName() string { return "workspaces" }
+
+type baseWorkspaceMember struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user;index"`
+	Role        string `gorm:"size:16;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	User *baseUser `gorm:"foreignKey:UserID"`
+}
+
+func (baseWorkspaceMember) TableName() string { return "workspace_members" }
+
+type baseWorkspaceInvitation struct {
+	ID          uint      `gorm:"primaryKey"`
+	WorkspaceID uint      `gorm:"not null;index"`
+	Email       string    `gorm:"size:255;not null"`
+	Role        string    `gorm:"size:16;not null"`
+	TokenHash   string    `gorm:"size:64;not null;uniqueIndex"`
+	InvitedByID uint      `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	AcceptedAt  *time.Time
+	CreatedAt   time.Time
+}
+
+func (baseWorkspaceInvitation) TableName() string { return "workspace_invitations" }
+
+type baseDomain struct {
+	ID                uint   `gorm:"primaryKey"`
+	Hostname          string `gorm:"size:253;not null;index"`
+	UserID            uint   `gorm:"not null;index"`
+	WorkspaceID       *uint  `gorm:"index"`
+	VerificationToken string `gorm:"size:64;not null"`
+	VerifiedAt        *time.Time
+	LastCheckedAt     *time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (baseDomain) TableName() string { return "domains" }
+
+type baseUserIdentity struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"not null;index"`
+	Provider    string `gorm:"size:64;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string `gorm:"size:255"`
+	LastLoginAt time.Time
+	CreatedAt   time.Time
+}
+
+func (baseUserIdentity) TableName() string { return "user_identities" }
+
+type baseOIDCLogin struct {
+	ID           uint      `gorm:"primaryKey"`
+	Provider     string    `gorm:"size:64;not null"`
+	StateHash    string    `gorm:"size:64;not null;uniqueIndex"`
+	Nonce        string    `gorm:"size:64;not null"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	RedirectURL  string    `gorm:"size:2048;not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+// The name is what gorm derived from OIDCLogin.
+func (baseOIDCLogin) TableName() string { return "o_id_c_logins" }
+
+type baseRecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (baseRecoveryCode) TableName() string { return "recovery_codes" }
+
+// baselineModels are the tables AutoMigrate managed before migrations were
+// versioned, in dependency order.
+func baselineModels() []interface{} {
+	return []interface{}{
+		&baseUser{},
+		&baseShortLink{},
+		&baseClickEvent{},
+		&baseSession{},
+		&baseRefreshToken{},
+		&baseAPIKey{},
+		&baseUserToken{},
+		&baseAuditLog{},
+		&baseWorkspace{},
+		&baseWorkspaceMember{},
+		&baseWorkspaceInvitation{},
+		&baseDomain{},
+		&baseUserIdentity{},
+		&baseOIDCLogin{},
+		&baseRecoveryCode{},
+	}
+}
+
+// baseline creates the schema as AutoMigrate used to. It is safe to run on
+// databases created before this history existed, which pick it up as
+// already in place.
+var baseline = migrator.Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(baselineModels()...); err != nil {
+			return err
+		}
+
+		// Codes used to be unique on their own, now they are unique per domain.
+		if tx.Migrator().HasIndex(&baseShortLink{}, "idx_short_links_code") {
+			if err := tx.Migrator().DropIndex(&baseShortLink{}, "idx_short_links_code"); err != nil {
+				return err
+			}
+		}
+		return backfillShortLinkCodes(tx)
+	},
+	Down: func(tx *gorm.DB) error {
+		models := baselineModels()
+		for i := len(models) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropTable(models[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// backfillShortLinkCodes assigns generated codes to links created before
+// the code column existed and marks them legacy, so they keep resolving by
+// their ID.
+func backfillShortLinkCodes(tx *gorm.DB) error {
+	var ids []uint
+	if err := tx.Model(&baseShortLink{}).Unscoped().Where("code IS NULL OR code = ''").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		code, err := shortcode.Generate(shortcode.DefaultLength)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&baseShortLink{}).Unscoped().Where("id = ?", id).
+			Updates(map[string]any{"code": code, "legacy": true}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
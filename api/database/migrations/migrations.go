@@ -0,0 +1,28 @@
+// Package migrations holds the schema history of the API. The baseline is
+// written in Go on frozen copies of the models; everything after it is a SQL
+// file in sql/, optionally with a per-dialect variant.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+
+	"go-api/internal/migrator"
+)
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// All returns the migrations for the given gorm dialect name.
+func All(dialect string) ([]migrator.Migration, error) {
+	dir, err := fs.Sub(sqlFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := migrator.LoadSQL(dir, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return append(migrations, baseline), nil
+}
@@ -0,0 +1,2 @@
+DROP INDEX idx_domains_verified_hostname ON domains;
+ALTER TABLE domains DROP COLUMN verified_hostname;
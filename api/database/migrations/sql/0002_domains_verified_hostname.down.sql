@@ -0,0 +1 @@
+DROP INDEX idx_domains_verified_hostname;
@@ -0,0 +1,4 @@
+-- MySQL has no partial indexes. A generated column that is only set for
+-- verified domains gets the unique index instead, since NULLs never collide.
+ALTER TABLE domains ADD COLUMN verified_hostname varchar(253) AS (IF(verified_at IS NULL, NULL, hostname)) VIRTUAL;
+CREATE UNIQUE INDEX idx_domains_verified_hostname ON domains (verified_hostname);
@@ -0,0 +1,3 @@
+-- Only one account can hold a verified hostname. The verify endpoint checks
+-- this too, but two checks racing each other could both pass.
+CREATE UNIQUE INDEX idx_domains_verified_hostname ON domains (hostname) WHERE verified_at IS NOT NULL;
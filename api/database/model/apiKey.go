@@ -0,0 +1,68 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a long-lived personal credential. Only a hash of the key is
+// stored; Prefix is kept in clear so users can tell their keys apart.
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:32;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:255;not null" json:"-"` // space separated
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
+
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+func CreateAPIKey(db *gorm.DB, key *APIKey) error {
+	return db.Create(key).Error
+}
+
+func GetAPIKeyByHash(db *gorm.DB, keyHash string) (*APIKey, error) {
+	var key APIKey
+	if err := db.First(&key, "key_hash = ?", keyHash).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func GetUserAPIKey(db *gorm.DB, userID, id uint) (*APIKey, error) {
+	var key APIKey
+	if err := db.First(&key, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func ListUserAPIKeys(db *gorm.DB, userID uint) ([]APIKey, error) {
+	keys := []APIKey{}
+	err := db.Where("user_id = ?", userID).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+func RevokeAPIKey(db *gorm.DB, key *APIKey) error {
+	now := time.Now()
+	if err := db.Model(key).Where("revoked_at IS NULL").Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	key.RevokedAt = &now
+	return nil
+}
+
+// TouchAPIKey records that the key was used. Writes are throttled to one
+// per granularity so busy keys do not turn every request into an UPDATE.
+func TouchAPIKey(db *gorm.DB, id uint, now time.Time, granularity time.Duration) error {
+	return db.Model(&APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-granularity)).
+		UpdateColumn("last_used_at", now).Error
+}
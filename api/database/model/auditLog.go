@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit log actions.
+const (
+	AuditUserDisabled    = "user.disabled"
+	AuditUserEnabled     = "user.enabled"
+	AuditUserRoleChanged = "user.role_changed"
+	AuditLinkTakenDown   = "link.taken_down"
+	AuditLinkRestored    = "link.restored"
+)
+
+// AuditLog records an action an admin took. Entries are never updated or
+// deleted.
+type AuditLog struct {
+	ID         uint   `gorm:"primaryKey" json:"id"`
+	ActorID    uint   `gorm:"not null;index" json:"actorId"`
+	Action     string `gorm:"size:64;not null;index" json:"action"`
+	TargetType string `gorm:"size:32;not null;index:idx_audit_logs_target" json:"targetType"`
+	TargetID   uint   `gorm:"not null;index:idx_audit_logs_target" json:"targetId"`
+	Reason     string `gorm:"size:500" json:"reason"`
+	// Details holds action specific data such as the previous role.
+	Details   string    `gorm:"type:text" json:"details,omitempty"`
+	IP        string    `gorm:"size:64" json:"ip"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func CreateAuditLog(db *gorm.DB, entry *AuditLog) error {
+	return db.Create(entry).Error
+}
+
+type ListAuditLogsQuery struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+	Cursor     *Cursor
+	Limit      int
+}
+
+// ListAuditLogs returns one page of audit log entries, newest first. It
+// returns the cursor for the next page, or nil on the last.
+func ListAuditLogs(db *gorm.DB, query ListAuditLogsQuery) ([]AuditLog, *Cursor, error) {
+	tx := db.Model(&AuditLog{})
+
+	if query.ActorID != 0 {
+		tx = tx.Where("actor_id = ?", query.ActorID)
+	}
+	if query.Action != "" {
+		tx = tx.Where("action = ?", query.Action)
+	}
+	if query.TargetType != "" {
+		tx = tx.Where("target_type = ?", query.TargetType)
+	}
+	if query.TargetID != 0 {
+		tx = tx.Where("target_id = ?", query.TargetID)
+	}
+	if query.Cursor != nil {
+		tx = tx.Where("id < ?", query.Cursor.ID)
+	}
+
+	var entries []AuditLog
+	if err := tx.Order("id DESC").Limit(query.Limit + 1).Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(entries) <= query.Limit {
+		return entries, nil, nil
+	}
+
+	entries = entries[:query.Limit]
+	return entries, &Cursor{ID: entries[len(entries)-1].ID}, nil
+}
@@ -1,16 +1,71 @@
 package model
 
 import (
+	"database/sql/driver"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // Base model with UUID
-type baseModel struct {
-	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+type BaseModel struct {
+	ID        UUID `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
+
+// BeforeCreate assigns the ID in Go rather than with a database default,
+// which not every database has.
+func (m *BaseModel) BeforeCreate(*gorm.DB) error {
+	if m.ID == (UUID{}) {
+		m.ID = NewUUID()
+	}
+	return nil
+}
+
+// UUID is a uuid.UUID stored in the native uuid column on PostgreSQL and as
+// its string form elsewhere.
+type UUID uuid.UUID
+
+func NewUUID() UUID {
+	return UUID(uuid.New())
+}
+
+func ParseUUID(s string) (UUID, error) {
+	id, err := uuid.Parse(s)
+	return UUID(id), err
+}
+
+func (u UUID) String() string {
+	return uuid.UUID(u).String()
+}
+
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+func (u *UUID) Scan(src any) error {
+	return (*uuid.UUID)(u).Scan(src)
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return uuid.UUID(u).MarshalText()
+}
+
+func (u *UUID) UnmarshalText(data []byte) error {
+	return (*uuid.UUID)(u).UnmarshalText(data)
+}
+
+func (UUID) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "uuid"
+	case "mysql":
+		return "char(36)"
+	default:
+		return "text"
+	}
+}
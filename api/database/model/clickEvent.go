@@ -0,0 +1,92 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClickEvent records a single resolution of a short link.
+type ClickEvent struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ShortLinkID uint      `gorm:"not null;index:idx_click_events_link_time,priority:1" json:"shortLinkId"`
+	OccurredAt  time.Time `gorm:"not null;index:idx_click_events_link_time,priority:2" json:"occurredAt"`
+	Referrer    string    `gorm:"size:2048" json:"referrer"`
+	UserAgent   string    `gorm:"size:1024" json:"userAgent"`
+	IPHash      string    `gorm:"size:64" json:"-"`
+	Device      string    `gorm:"size:32" json:"device"`
+	Browser     string    `gorm:"size:32" json:"browser"`
+	OS          string    `gorm:"size:32" json:"os"`
+}
+
+type ReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Count    int64  `json:"count"`
+}
+
+func CreateClickEvents(db *gorm.DB, events []ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(events, 500).Error
+}
+
+func clickEventsInRange(db *gorm.DB, linkID uint, from, to time.Time) *gorm.DB {
+	return db.Model(&ClickEvent{}).
+		Where("short_link_id = ? AND occurred_at >= ? AND occurred_at < ?", linkID, from, to)
+}
+
+// CountClicksByBucket counts the clicks on the link between from
+// (inclusive) and to (exclusive) in buckets of width, the first starting at
+// start. It returns the counts by bucket index, leaving out empty buckets.
+func CountClicksByBucket(db *gorm.DB, linkID uint, from, to, start time.Time, width time.Duration) (map[int]int64, error) {
+	seconds := int64(width / time.Second)
+
+	var bucket clause.Expr
+	switch db.Dialector.Name() {
+	case "postgres":
+		bucket = gorm.Expr("CAST(FLOOR((EXTRACT(EPOCH FROM occurred_at) - ?) / ?) AS BIGINT)", start.Unix(), seconds)
+	case "mysql":
+		bucket = gorm.Expr("FLOOR(TIMESTAMPDIFF(SECOND, ?, occurred_at) / ?)", start.UTC(), seconds)
+	default:
+		bucket = gorm.Expr("(CAST(strftime('%s', occurred_at) AS INTEGER) - ?) / ?", start.Unix(), seconds)
+	}
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	err := clickEventsInRange(db, linkID, from, to).
+		Select("? AS bucket, COUNT(*) AS count", bucket).
+		Group("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+	return counts, nil
+}
+
+func CountUniqueVisitors(db *gorm.DB, linkID uint, from, to time.Time) (int64, error) {
+	var count int64
+	err := clickEventsInRange(db, linkID, from, to).
+		Distinct("ip_hash").
+		Count(&count).Error
+	return count, err
+}
+
+func GetTopReferrers(db *gorm.DB, linkID uint, from, to time.Time, limit int) ([]ReferrerCount, error) {
+	referrers := []ReferrerCount{}
+	err := clickEventsInRange(db, linkID, from, to).
+		Select("referrer, COUNT(*) AS count").
+		Group("referrer").
+		Order("count DESC, referrer").
+		Limit(limit).
+		Scan(&referrers).Error
+	return referrers, err
+}
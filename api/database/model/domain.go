@@ -0,0 +1,150 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrDomainTaken is returned when verifying a hostname that someone else
+// already verified.
+var ErrDomainTaken = errors.New("domain is already verified by another account")
+
+// Domain is a custom hostname short links can be served on. Several accounts
+// may claim the same hostname, but only the first to publish the DNS record
+// gets it verified. Links on the default host have no domain.
+type Domain struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	Hostname          string     `gorm:"size:253;not null;index" json:"hostname"`
+	UserID            uint       `gorm:"not null;index" json:"userId"`
+	WorkspaceID       *uint      `gorm:"index" json:"workspaceId"`
+	VerificationToken string     `gorm:"size:64;not null" json:"-"`
+	VerifiedAt        *time.Time `json:"verifiedAt"`
+	LastCheckedAt     *time.Time `json:"lastCheckedAt"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+func (d *Domain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
+
+// InScope reports whether the domain belongs to the workspace or user the
+// scope selects.
+func (d *Domain) InScope(scope ShortLinkScope) bool {
+	if scope.WorkspaceID != nil {
+		return d.WorkspaceID != nil && *d.WorkspaceID == *scope.WorkspaceID
+	}
+	return d.WorkspaceID == nil && d.UserID == scope.UserID
+}
+
+func CreateDomain(db *gorm.DB, domain *Domain) error {
+	return db.Create(domain).Error
+}
+
+func GetDomainByID(db *gorm.DB, id uint) (*Domain, error) {
+	var domain Domain
+	if err := db.First(&domain, id).Error; err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// GetVerifiedDomainByHostname returns the domain that serves links on
+// hostname.
+func GetVerifiedDomainByHostname(db *gorm.DB, hostname string) (*Domain, error) {
+	var domain Domain
+	if err := db.First(&domain, "hostname = ? AND verified_at IS NOT NULL", hostname).Error; err != nil {
+		return nil, err
+	}
+	return &domain, nil
+}
+
+// ScopeHasDomain reports whether the scope already claimed hostname.
+func ScopeHasDomain(db *gorm.DB, scope ShortLinkScope, hostname string) (bool, error) {
+	var count int64
+	err := scope.apply(db.Model(&Domain{}), "domains").
+		Where("hostname = ?", hostname).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func ListDomains(db *gorm.DB, scope ShortLinkScope) ([]Domain, error) {
+	var domains []Domain
+	err := scope.apply(db.Model(&Domain{}), "domains").
+		Order("hostname, id").
+		Find(&domains).Error
+	return domains, err
+}
+
+// DomainHostnames maps the given domain IDs to their hostnames.
+func DomainHostnames(db *gorm.DB, ids []uint) (map[uint]string, error) {
+	hostnames := make(map[uint]string)
+	if len(ids) == 0 {
+		return hostnames, nil
+	}
+
+	var domains []Domain
+	if err := db.Select("id", "hostname").Where("id IN ?", ids).Find(&domains).Error; err != nil {
+		return nil, err
+	}
+	for _, domain := range domains {
+		hostnames[domain.ID] = domain.Hostname
+	}
+	return hostnames, nil
+}
+
+// MarkDomainChecked records a verification attempt. When verified is true
+// the domain becomes the owner of its hostname and competing claims are
+// dropped; ErrDomainTaken is returned if another claim won first. The count
+// below only answers the common case: two verifications racing each other
+// both pass it, and the unique index on verified hostnames refuses the
+// second.
+func MarkDomainChecked(db *gorm.DB, domain *Domain, verified bool, now time.Time) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]any{"last_checked_at": now}
+
+		if verified && !domain.IsVerified() {
+			var taken int64
+			if err := tx.Model(&Domain{}).
+				Where("hostname = ? AND id <> ? AND verified_at IS NOT NULL", domain.Hostname, domain.ID).
+				Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return ErrDomainTaken
+			}
+
+			updates["verified_at"] = now
+			if err := tx.Where("hostname = ? AND id <> ? AND verified_at IS NULL", domain.Hostname, domain.ID).
+				Delete(&Domain{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(domain).Updates(updates).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrDomainTaken
+	}
+	return err
+}
+
+// DeleteDomain deletes the domain and soft-deletes the links served on it.
+// It returns the deleted links.
+func DeleteDomain(db *gorm.DB, domain *Domain) ([]ShortLink, error) {
+	var links []ShortLink
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "code", "domain_id").Where("domain_id = ?", domain.ID).Find(&links).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("domain_id = ?", domain.ID).Delete(&ShortLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(domain).Error
+	})
+
+	return links, err
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OIDCLogin is a login started at an OpenID provider and not finished yet.
+// It keeps the secrets the callback has to check the provider's answer
+// against. Only the hash of the state is stored since it travels in URLs.
+type OIDCLogin struct {
+	ID           uint      `gorm:"primaryKey"`
+	Provider     string    `gorm:"size:64;not null"`
+	StateHash    string    `gorm:"size:64;not null;uniqueIndex"`
+	Nonce        string    `gorm:"size:64;not null"`
+	CodeVerifier string    `gorm:"size:128;not null"`
+	RedirectURL  string    `gorm:"size:2048;not null"`
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+// CreateOIDCLogin stores a new login and drops expired ones, which belong to
+// users who never came back from the provider.
+func CreateOIDCLogin(db *gorm.DB, login *OIDCLogin) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", time.Now()).Delete(&OIDCLogin{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(login).Error
+	})
+}
+
+// ConsumeOIDCLogin deletes and returns the unexpired login with the given
+// provider and state hash, so every state can be used once. It returns
+// gorm.ErrRecordNotFound otherwise.
+func ConsumeOIDCLogin(db *gorm.DB, provider, stateHash string, now time.Time) (*OIDCLogin, error) {
+	var login OIDCLogin
+	if err := db.First(&login, "state_hash = ? AND provider = ?", stateHash, provider).Error; err != nil {
+		return nil, err
+	}
+
+	result := db.Delete(&OIDCLogin{}, login.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || !now.Before(login.ExpiresAt) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &login, nil
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a login of a user on one device. Access tokens carry the session
+// ID, so revoking the session invalidates them before they expire.
+type Session struct {
+	BaseModel
+	UserID     uint       `gorm:"not null;index" json:"userId"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt"`
+	UserAgent  string     `gorm:"size:512" json:"userAgent"`
+	IP         string     `gorm:"size:64" json:"ip"`
+}
+
+// RefreshToken is one link in a session's chain of refresh tokens. Only the
+// newest token of a session is unused; presenting a used one means the chain
+// leaked.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	SessionID UUID      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// CreateSession stores a new session together with its first refresh token.
+func CreateSession(db *gorm.DB, session *Session, tokenHash string) error {
+	if session.ID == (UUID{}) {
+		session.ID = NewUUID()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&RefreshToken{
+			SessionID: session.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: session.ExpiresAt,
+		}).Error
+	})
+}
+
+func GetSessionByID(db *gorm.DB, id UUID) (*Session, error) {
+	var session Session
+	if err := db.First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func GetRefreshTokenByHash(db *gorm.DB, tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := db.First(&token, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken marks the old token as used and stores its successor.
+// It returns false without changing anything when the old token was already
+// used, which happens when a stolen token races the legitimate client.
+func RotateRefreshToken(db *gorm.DB, old *RefreshToken, newHash string, expiresAt time.Time) (bool, error) {
+	rotated := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", old.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&RefreshToken{
+			SessionID: old.SessionID,
+			TokenHash: newHash,
+			ExpiresAt: expiresAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Session{}).
+			Where("id = ?", old.SessionID).
+			Updates(map[string]any{"expires_at": expiresAt, "last_used_at": now}).Error; err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+
+	return rotated, err
+}
+
+func RevokeSession(db *gorm.DB, id UUID) error {
+	return db.Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes every active session of the user and returns
+// how many were revoked.
+func RevokeUserSessions(db *gorm.DB, userID uint) (int64, error) {
+	result := db.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
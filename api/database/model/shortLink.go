@@ -1,13 +1,66 @@
 package model
 
 import (
+	"errors"
+	"go-api/internal/shortcode"
+	"strconv"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type ShortLink struct {
 	gorm.Model
 	UserID int    `gorm:"type:int;index"` // Ensure UUID consistency
+	Code   string `json:"code" gorm:"size:64;uniqueIndex:idx_short_links_domain_code"`
 	URL    string `json:"url"`
+	// DomainID is the custom domain the link is served on, 0 for the
+	// default host. Codes are unique per domain.
+	DomainID uint `json:"domainId" gorm:"not null;default:0;uniqueIndex:idx_short_links_domain_code"`
+	// WorkspaceID is set for links owned by a workspace rather than by
+	// UserID alone; UserID then records who created the link.
+	WorkspaceID *uint `json:"workspaceId" gorm:"index"`
+
+	ActivatesAt *time.Time `json:"activatesAt"`
+	ExpiresAt   *time.Time `json:"expiresAt" gorm:"index"`
+	MaxClicks   int        `json:"maxClicks" gorm:"not null;default:0"`  // 0 means unlimited
+	ClickCount  int        `json:"clickCount" gorm:"not null;default:0"` // only tracked for links with MaxClicks
+	Disabled    bool       `json:"disabled" gorm:"not null;default:false"`
+
+	// TakenDownAt is set while an admin has taken the link down. Unlike
+	// Disabled, the owner cannot undo it.
+	TakenDownAt    *time.Time `json:"takenDownAt"`
+	TakedownReason string     `json:"takedownReason" gorm:"size:500"`
+
+	// Legacy marks links created before codes existed, which still resolve
+	// by their numeric ID.
+	Legacy bool `json:"-" gorm:"not null;default:false"`
+}
+
+// IsPending reports whether the link is scheduled to activate after now.
+func (l *ShortLink) IsPending(now time.Time) bool {
+	return l.ActivatesAt != nil && now.Before(*l.ActivatesAt)
+}
+
+// IsExpired reports whether the link's expiry date has passed.
+func (l *ShortLink) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// IsExhausted reports whether the link has used up its click allowance.
+func (l *ShortLink) IsExhausted() bool {
+	return l.MaxClicks > 0 && l.ClickCount >= l.MaxClicks
+}
+
+// IsTakenDown reports whether an admin took the link down.
+func (l *ShortLink) IsTakenDown() bool {
+	return l.TakenDownAt != nil
+}
+
+// HasLimits reports whether the link can stop resolving at some point.
+func (l *ShortLink) HasLimits() bool {
+	return l.ActivatesAt != nil || l.ExpiresAt != nil || l.MaxClicks > 0
 }
 
 func GetShortLinkByID(db *gorm.DB, id uint) (*ShortLink, error) {
@@ -16,14 +69,313 @@ func GetShortLinkByID(db *gorm.DB, id uint) (*ShortLink, error) {
 		return nil, err
 	}
 
-	// TODO: ADD CACHING
+	return &shortLink, nil
+}
+
+func GetShortLinkByCode(db *gorm.DB, domainID uint, code string) (*ShortLink, error) {
+	var shortLink ShortLink
+	if err := db.First(&shortLink, "domain_id = ? AND code = ?", domainID, code).Error; err != nil {
+		return nil, err
+	}
 
 	return &shortLink, nil
 }
 
+// ResolveShortLink looks a link up by its domain and code. Links the sweeper
+// deleted after they expired are still found, so they keep answering as
+// expired links do. On the default host purely numeric codes that do not
+// match a stored code fall back to the ID of a legacy link, so links handed
+// out before codes existed keep working without making newer ones
+// enumerable.
+func ResolveShortLink(db *gorm.DB, domainID uint, code string) (*ShortLink, error) {
+	shortLink, err := GetShortLinkByCode(db, domainID, code)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return shortLink, err
+	}
+
+	shortLink, err = getSweptShortLink(db, domainID, code)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) || domainID != 0 || !shortcode.IsNumeric(code) {
+		return shortLink, err
+	}
+
+	id, parseErr := strconv.ParseUint(code, 10, 64)
+	if parseErr != nil {
+		return nil, err
+	}
+
+	var legacy ShortLink
+	if err := db.First(&legacy, "id = ? AND domain_id = 0 AND legacy = ?", uint(id), true).Error; err != nil {
+		return nil, err
+	}
+	return &legacy, nil
+}
+
+// getSweptShortLink finds a link that was deleted once it had expired,
+// rather than by its owner before.
+func getSweptShortLink(db *gorm.DB, domainID uint, code string) (*ShortLink, error) {
+	var shortLink ShortLink
+	err := db.Unscoped().
+		Where("domain_id = ? AND code = ?", domainID, code).
+		Where("deleted_at IS NOT NULL AND expires_at IS NOT NULL AND expires_at <= deleted_at").
+		First(&shortLink).Error
+	if err != nil {
+		return nil, err
+	}
+	return &shortLink, nil
+}
+
+func ShortLinkCodeExists(db *gorm.DB, domainID uint, code string) (bool, error) {
+	var count int64
+	if err := db.Model(&ShortLink{}).Unscoped().Where("domain_id = ? AND code = ?", domainID, code).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func CreateShortLink(db *gorm.DB, shortLink *ShortLink) (*ShortLink, error) {
 	if err := db.Create(shortLink).Error; err != nil {
 		return nil, err
 	}
 	return shortLink, nil
 }
+
+// CreateShortLinks inserts all links in a single transaction.
+func CreateShortLinks(db *gorm.DB, shortLinks []ShortLink) error {
+	if len(shortLinks) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(shortLinks, 500).Error
+	})
+}
+
+// CreateShortLinksEach inserts the links one by one, so one that fails does
+// not keep the others out. It returns the error of every link, nil for the
+// ones created.
+func CreateShortLinksEach(db *gorm.DB, shortLinks []ShortLink) []error {
+	errs := make([]error, len(shortLinks))
+	for i := range shortLinks {
+		errs[i] = db.Create(&shortLinks[i]).Error
+	}
+	return errs
+}
+
+// ExistingShortLinkCodes returns which of the given codes are already taken
+// on the domain, including by deleted links.
+func ExistingShortLinkCodes(db *gorm.DB, domainID uint, codes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(codes) == 0 {
+		return existing, nil
+	}
+
+	var taken []string
+	if err := db.Model(&ShortLink{}).Unscoped().Where("domain_id = ? AND code IN ?", domainID, codes).Pluck("code", &taken).Error; err != nil {
+		return nil, err
+	}
+
+	for _, code := range taken {
+		existing[code] = true
+	}
+	return existing, nil
+}
+
+// ShortLinkScope selects the links of a workspace, or the personal links of a
+// user when WorkspaceID is nil.
+type ShortLinkScope struct {
+	UserID      uint
+	WorkspaceID *uint
+}
+
+func (s ShortLinkScope) apply(tx *gorm.DB, table string) *gorm.DB {
+	if s.WorkspaceID != nil {
+		return tx.Where(table+".workspace_id = ?", *s.WorkspaceID)
+	}
+	return tx.Where(table+".user_id = ? AND "+table+".workspace_id IS NULL", s.UserID)
+}
+
+// ShortLinkExport is a link together with the number of recorded clicks.
+type ShortLinkExport struct {
+	ShortLink
+	TotalClicks int64
+}
+
+// ExportShortLinks walks every link in scope, oldest first, calling fn for
+// each row as it is read so large exports do not have to fit in memory.
+func ExportShortLinks(db *gorm.DB, scope ShortLinkScope, fn func(ShortLinkExport) error) error {
+	clicks := db.Model(&ClickEvent{}).
+		Select("short_link_id, COUNT(*) AS total").
+		Group("short_link_id")
+
+	tx := db.Model(&ShortLink{}).
+		Select("short_links.*, COALESCE(clicks.total, 0) AS total_clicks").
+		Joins("LEFT JOIN (?) AS clicks ON clicks.short_link_id = short_links.id", clicks)
+
+	rows, err := scope.apply(tx, "short_links").
+		Order("short_links.id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row ShortLinkExport
+		if err := db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func UpdateShortLink(db *gorm.DB, shortLink *ShortLink, updates map[string]any) error {
+	return db.Model(shortLink).Updates(updates).Error
+}
+
+// SetShortLinkTakedown takes the link down with the given reason, or restores
+// it when at is nil.
+func SetShortLinkTakedown(db *gorm.DB, shortLink *ShortLink, at *time.Time, reason string) error {
+	return db.Model(shortLink).Updates(map[string]any{
+		"taken_down_at":   at,
+		"takedown_reason": reason,
+	}).Error
+}
+
+func DeleteShortLink(db *gorm.DB, shortLink *ShortLink) error {
+	return db.Delete(shortLink).Error
+}
+
+// shortLinkSortColumns maps the public sort keys to columns.
+var shortLinkSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"code":      "code",
+	"url":       "url",
+}
+
+// ShortLinkSortKeys lists the accepted values for ListShortLinksQuery.Sort,
+// each of which may be prefixed with '-' for descending order.
+func ShortLinkSortKeys() []string {
+	return []string{"createdAt", "code", "url"}
+}
+
+type ListShortLinksQuery struct {
+	Scope  ShortLinkScope
+	Search string
+	// Sort is one of ShortLinkSortKeys, optionally prefixed with '-'.
+	Sort   string
+	Cursor *Cursor
+	Limit  int
+}
+
+// ListShortLinks returns one page of the links in scope ordered by the requested
+// column and ID. It returns the cursor for the next page, or nil on the last.
+func ListShortLinks(db *gorm.DB, query ListShortLinksQuery) ([]ShortLink, *Cursor, error) {
+	sortKey, desc := strings.TrimPrefix(query.Sort, "-"), strings.HasPrefix(query.Sort, "-")
+	column, ok := shortLinkSortColumns[sortKey]
+	if !ok {
+		return nil, nil, errors.New("unknown sort key " + query.Sort)
+	}
+
+	tx := query.Scope.apply(db.Model(&ShortLink{}), "short_links")
+
+	if query.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(query.Search)) + "%"
+		tx = tx.Where(`(LOWER(url) LIKE ? ESCAPE '!' OR LOWER(code) LIKE ? ESCAPE '!')`, pattern, pattern)
+	}
+
+	if query.Cursor != nil {
+		var value any = query.Cursor.Value
+		if column == "created_at" {
+			t, err := time.Parse(time.RFC3339Nano, query.Cursor.Value)
+			if err != nil {
+				return nil, nil, ErrInvalidCursor
+			}
+			value = t
+		}
+
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		tx = tx.Where(
+			"("+column+" "+op+" ? OR ("+column+" = ? AND id "+op+" ?))",
+			value, value, query.Cursor.ID,
+		)
+	}
+
+	order := column + ", id"
+	if desc {
+		order = column + " DESC, id DESC"
+	}
+
+	var links []ShortLink
+	if err := tx.Order(order).Limit(query.Limit + 1).Find(&links).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(links) <= query.Limit {
+		return links, nil, nil
+	}
+
+	links = links[:query.Limit]
+	last := links[len(links)-1]
+
+	next := &Cursor{ID: last.ID}
+	switch column {
+	case "created_at":
+		next.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "code":
+		next.Value = last.Code
+	case "url":
+		next.Value = last.URL
+	}
+
+	return links, next, nil
+}
+
+// likeEscape is the escape character for LIKE patterns. A backslash would
+// need escaping itself in MySQL string literals, which it does not in
+// standard SQL.
+const likeEscape = "!"
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
+
+// RecordShortLinkClick atomically counts a click against the link. It returns
+// false when the link already reached its click limit.
+func RecordShortLinkClick(db *gorm.DB, id uint) (bool, error) {
+	result := db.Model(&ShortLink{}).
+		Where("id = ? AND (max_clicks = 0 OR click_count < max_clicks)", id).
+		UpdateColumn("click_count", gorm.Expr("click_count + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SoftDeleteExpiredShortLinks soft-deletes every link whose expiry date lies
+// before now and returns the removed links.
+func SoftDeleteExpiredShortLinks(db *gorm.DB, now time.Time) ([]ShortLink, error) {
+	var expired []ShortLink
+	if err := db.Select("id", "code", "domain_id").Where("expires_at IS NOT NULL AND expires_at <= ?", now).Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(expired))
+	for i, link := range expired {
+		ids[i] = link.ID
+	}
+
+	if err := db.Where("id IN ?", ids).Delete(&ShortLink{}).Error; err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
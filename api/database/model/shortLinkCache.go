@@ -0,0 +1,190 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-api/internal/cache"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ShortLinkCache is a read-through cache in front of ResolveShortLink. Codes
+// that do not resolve are cached as well, for a shorter time, so scanning for
+// random codes does not reach the database either.
+type ShortLinkCache struct {
+	db          *gorm.DB
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+	loads        atomic.Uint64
+	failures     atomic.Uint64
+}
+
+// ShortLinkCacheStats counts how resolutions were answered. Loads is the
+// number of database queries, so a hot path served from cache keeps it flat.
+type ShortLinkCacheStats struct {
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negativeHits"`
+	Misses       uint64  `json:"misses"`
+	Loads        uint64  `json:"loads"`
+	Errors       uint64  `json:"errors"`
+	HitRatio     float64 `json:"hitRatio"`
+}
+
+func NewShortLinkCache(db *gorm.DB, c cache.Cache, ttl, negativeTTL time.Duration) *ShortLinkCache {
+	return &ShortLinkCache{
+		db:          db,
+		cache:       c,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+	}
+}
+
+func shortLinkCacheKey(domainID uint, code string) string {
+	if domainID == 0 {
+		return "shortlink:code:" + code
+	}
+	return "shortlink:domain:" + strconv.FormatUint(uint64(domainID), 10) + ":code:" + code
+}
+
+func shortLinkHostCacheKey(host string) string {
+	return "shortlink:host:" + host
+}
+
+// ResolveHost returns the ID of the verified domain serving host, or 0 when
+// host is not a custom domain and links are looked up on the default host.
+func (sc *ShortLinkCache) ResolveHost(ctx context.Context, host string) (uint, error) {
+	key := shortLinkHostCacheKey(host)
+
+	data, found, err := sc.cache.Get(ctx, key)
+	if err != nil {
+		sc.failures.Add(1)
+		log.Printf("Short link cache get failed: %v", err)
+	}
+	if found {
+		if len(data) == 0 {
+			return 0, nil
+		}
+		if id, err := strconv.ParseUint(string(data), 10, 64); err == nil {
+			return uint(id), nil
+		}
+		sc.failures.Add(1)
+	}
+
+	domain, err := GetVerifiedDomainByHostname(sc.db, host)
+	switch {
+	case err == nil:
+		sc.set(ctx, key, []byte(strconv.FormatUint(uint64(domain.ID), 10)), sc.ttl)
+		return domain.ID, nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sc.set(ctx, key, []byte{}, sc.negativeTTL)
+		return 0, nil
+	default:
+		return 0, err
+	}
+}
+
+// InvalidateHost drops the cached domain lookup for host. It must be called
+// after a domain is verified or deleted.
+func (sc *ShortLinkCache) InvalidateHost(ctx context.Context, host string) {
+	if err := sc.cache.Delete(ctx, shortLinkHostCacheKey(host)); err != nil {
+		sc.failures.Add(1)
+		log.Printf("Short link cache invalidation failed: %v", err)
+	}
+}
+
+// Resolve behaves like ResolveShortLink but consults the cache first.
+func (sc *ShortLinkCache) Resolve(ctx context.Context, domainID uint, code string) (*ShortLink, error) {
+	key := shortLinkCacheKey(domainID, code)
+
+	data, found, err := sc.cache.Get(ctx, key)
+	if err != nil {
+		// A broken cache must not take redirects down with it.
+		sc.failures.Add(1)
+		log.Printf("Short link cache get failed: %v", err)
+	}
+
+	if found {
+		if len(data) == 0 {
+			sc.negativeHits.Add(1)
+			return nil, gorm.ErrRecordNotFound
+		}
+
+		var link ShortLink
+		if err := json.Unmarshal(data, &link); err == nil {
+			sc.hits.Add(1)
+			return &link, nil
+		}
+		sc.failures.Add(1)
+	}
+
+	sc.misses.Add(1)
+	sc.loads.Add(1)
+
+	link, err := ResolveShortLink(sc.db, domainID, code)
+	switch {
+	case err == nil:
+		if data, err := json.Marshal(link); err == nil {
+			sc.set(ctx, key, data, sc.ttl)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sc.set(ctx, key, []byte{}, sc.negativeTTL)
+	}
+
+	return link, err
+}
+
+// Invalidate drops every cache entry that may point at link. It must be
+// called after a link is created, changed or deleted.
+func (sc *ShortLinkCache) Invalidate(ctx context.Context, links ...ShortLink) {
+	keys := make([]string, 0, len(links)*2)
+	for _, link := range links {
+		if link.Code != "" {
+			keys = append(keys, shortLinkCacheKey(link.DomainID, link.Code))
+		}
+		// Legacy numeric links are cached under their ID.
+		if link.DomainID == 0 {
+			keys = append(keys, shortLinkCacheKey(0, strconv.FormatUint(uint64(link.ID), 10)))
+		}
+	}
+
+	if err := sc.cache.Delete(ctx, keys...); err != nil {
+		sc.failures.Add(1)
+		log.Printf("Short link cache invalidation failed: %v", err)
+	}
+}
+
+func (sc *ShortLinkCache) Stats() ShortLinkCacheStats {
+	stats := ShortLinkCacheStats{
+		Hits:         sc.hits.Load(),
+		NegativeHits: sc.negativeHits.Load(),
+		Misses:       sc.misses.Load(),
+		Loads:        sc.loads.Load(),
+		Errors:       sc.failures.Load(),
+	}
+
+	if total := stats.Hits + stats.NegativeHits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.NegativeHits) / float64(total)
+	}
+	return stats
+}
+
+func (sc *ShortLinkCache) set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	if err := sc.cache.Set(ctx, key, data, ttl); err != nil {
+		sc.failures.Add(1)
+		log.Printf("Short link cache set failed: %v", err)
+	}
+}
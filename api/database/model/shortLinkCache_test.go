@@ -0,0 +1,104 @@
+package model_test
+
+import (
+	"context"
+	"errors"
+	"go-api/database/migrations"
+	"go-api/database/model"
+	"go-api/internal/cache"
+	"go-api/internal/cache/redistest"
+	"go-api/internal/database"
+	"go-api/internal/migrator"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Open(database.SQLite, database.Memory, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrator.New(db, all, migrator.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestShortLinkCacheOnRedis(t *testing.T) {
+	db := newTestDB(t)
+
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	client := cache.NewRedis(cache.RedisOptions{Addr: srv.Addr(), Prefix: "test:"})
+	t.Cleanup(func() { client.Close() })
+
+	links := model.NewShortLinkCache(db, client, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	// Unknown codes are remembered, so asking again does not load.
+	for range 2 {
+		if _, err := links.Resolve(ctx, 0, "abc1234"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("Resolve(unknown) = %v", err)
+		}
+	}
+	if stats := links.Stats(); stats.Loads != 1 || stats.NegativeHits != 1 {
+		t.Errorf("after unknown code: %+v", stats)
+	}
+
+	link := model.ShortLink{Code: "abc1234", URL: "https://example.com/"}
+	if _, err := model.CreateShortLink(db, &link); err != nil {
+		t.Fatal(err)
+	}
+	links.Invalidate(ctx, link)
+
+	for range 2 {
+		got, err := links.Resolve(ctx, 0, "abc1234")
+		if err != nil || got.ID != link.ID || got.URL != link.URL {
+			t.Fatalf("Resolve = %+v, %v", got, err)
+		}
+	}
+	if stats := links.Stats(); stats.Loads != 2 || stats.Hits != 1 || stats.Errors != 0 {
+		t.Errorf("after created link: %+v", stats)
+	}
+}
+
+func TestShortLinkCacheSurvivesRedisOutage(t *testing.T) {
+	db := newTestDB(t)
+
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := cache.NewRedis(cache.RedisOptions{Addr: srv.Addr(), DialTimeout: time.Second})
+	srv.Close()
+
+	link := model.ShortLink{Code: "xyz7890", URL: "https://example.com/"}
+	if _, err := model.CreateShortLink(db, &link); err != nil {
+		t.Fatal(err)
+	}
+
+	links := model.NewShortLinkCache(db, client, time.Minute, time.Minute)
+	got, err := links.Resolve(context.Background(), 0, "xyz7890")
+	if err != nil || got.ID != link.ID {
+		t.Fatalf("Resolve without Redis = %+v, %v", got, err)
+	}
+	if stats := links.Stats(); stats.Errors == 0 {
+		t.Errorf("cache failures not counted: %+v", stats)
+	}
+}
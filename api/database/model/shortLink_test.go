@@ -0,0 +1,41 @@
+package model_test
+
+import (
+	"errors"
+	"go-api/database/model"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCreateShortLinksEach(t *testing.T) {
+	db := newTestDB(t)
+
+	if _, err := model.CreateShortLink(db, &model.ShortLink{Code: "taken", URL: "https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	links := []model.ShortLink{
+		{Code: "first", URL: "https://example.com/1"},
+		{Code: "taken", URL: "https://example.com/2"},
+		{Code: "taken", DomainID: 3, URL: "https://example.com/3"},
+	}
+	errs := model.CreateShortLinksEach(db, links)
+
+	// The conflict only fails its own link.
+	if errs[0] != nil || errs[2] != nil {
+		t.Errorf("errors %v, want only the second link to fail", errs)
+	}
+	if !errors.Is(errs[1], gorm.ErrDuplicatedKey) {
+		t.Errorf("conflict = %v, want ErrDuplicatedKey", errs[1])
+	}
+	if links[0].ID == 0 || links[2].ID == 0 {
+		t.Errorf("created links have no IDs: %+v", links)
+	}
+
+	var count int64
+	db.Model(&model.ShortLink{}).Count(&count)
+	if count != 3 {
+		t.Errorf("%d links stored, want 3", count)
+	}
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SystemStats are totals across all users.
+type SystemStats struct {
+	Users          int64 `json:"users"`
+	DisabledUsers  int64 `json:"disabledUsers"`
+	Admins         int64 `json:"admins"`
+	Links          int64 `json:"links"`
+	DisabledLinks  int64 `json:"disabledLinks"`
+	TakenDownLinks int64 `json:"takenDownLinks"`
+	Clicks         int64 `json:"clicks"`
+	ClicksLastDay  int64 `json:"clicksLastDay"`
+	LinksLastDay   int64 `json:"linksLastDay"`
+}
+
+func GetSystemStats(db *gorm.DB, now time.Time) (*SystemStats, error) {
+	var stats SystemStats
+	dayAgo := now.Add(-24 * time.Hour)
+
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&stats.Users, db.Model(&User{})},
+		{&stats.DisabledUsers, db.Model(&User{}).Where("disabled_at IS NOT NULL")},
+		{&stats.Admins, db.Model(&User{}).Where("role = ?", RoleAdmin)},
+		{&stats.Links, db.Model(&ShortLink{})},
+		{&stats.DisabledLinks, db.Model(&ShortLink{}).Where("disabled = ?", true)},
+		{&stats.TakenDownLinks, db.Model(&ShortLink{}).Where("taken_down_at IS NOT NULL")},
+		{&stats.LinksLastDay, db.Model(&ShortLink{}).Where("created_at >= ?", dayAgo)},
+		{&stats.Clicks, db.Model(&ClickEvent{})},
+		{&stats.ClicksLastDay, db.Model(&ClickEvent{}).Where("occurred_at >= ?", dayAgo)},
+	}
+
+	for _, count := range counts {
+		if err := count.query.Count(count.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a one-time code that stands in for a TOTP code when the
+// user lost their authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// StartTOTPEnrollment stores a new secret for the user. It replaces any
+// unconfirmed secret but is refused by the caller once 2FA is enabled.
+func StartTOTPEnrollment(db *gorm.DB, userID uint, secret string) error {
+	return db.Model(&User{}).
+		Where("id = ? AND totp_enabled_at IS NULL", userID).
+		Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0}).Error
+}
+
+// EnableTOTP turns on two-factor login and replaces the recovery codes.
+func EnableTOTP(db *gorm.DB, userID uint, step int64, codeHashes []string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{"totp_enabled_at": now, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		return ReplaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// DisableTOTP turns off two-factor login and drops the secret and recovery
+// codes.
+func DisableTOTP(db *gorm.DB, userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).
+			Where("id = ?", userID).
+			Updates(map[string]any{"totp_secret": "", "totp_enabled_at": nil, "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep records that the code of step was accepted. It reports false
+// when that step or a later one was used before, which means the code is
+// being replayed.
+func UseTOTPStep(db *gorm.DB, userID uint, step int64) (bool, error) {
+	result := db.Model(&User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func ReplaceRecoveryCodes(db *gorm.DB, userID uint, codeHashes []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode marks the user's unused code with the given hash as used.
+// It reports whether there was such a code.
+func UseRecoveryCode(db *gorm.DB, userID uint, codeHash string, now time.Time) (bool, error) {
+	result := db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	return result.RowsAffected == 1, result.Error
+}
+
+func CountUnusedRecoveryCodes(db *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
@@ -1,15 +1,60 @@
 package model
 
 import (
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// Roles a user can have. Every account starts as a user.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
 type User struct {
 	gorm.Model
 	Name       string      `json:"name"`
 	Email      string      `json:"email" gorm:"unique"`
 	Password   string      `json:"password"`
 	ShortLinks []ShortLink `gorm:"foreignKey:UserID;references:ID"`
+	// EmailVerifiedAt is nil until the user confirmed their address.
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	// FailedLoginCount counts wrong passwords since the last successful
+	// login; LockedUntil blocks logins while it is in the future.
+	FailedLoginCount int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil      *time.Time `json:"-"`
+	Role             string     `json:"role" gorm:"size:16;not null;default:user"`
+	// DisabledAt is set while an admin has disabled the account.
+	DisabledAt *time.Time `json:"disabledAt"`
+	// TOTPSecret is set once enrollment started; two-factor logins are only
+	// required after TOTPEnabledAt confirmed the user can produce codes.
+	// TOTPLastStep is the time step of the last accepted code, so a code
+	// cannot be used twice.
+	TOTPSecret    string     `json:"-" gorm:"size:64"`
+	TOTPEnabledAt *time.Time `json:"-"`
+	TOTPLastStep  int64      `json:"-" gorm:"not null;default:0"`
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) HasTwoFactor() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
 }
 
 func GetUserByID(db *gorm.DB, id uint) (*User, error) {
@@ -40,3 +85,108 @@ func CreateUserAndReturnID(db *gorm.DB, user *User) (uint, error) {
 	}
 	return user.ID, nil
 }
+
+// MarkEmailVerified records that the user confirmed their address. Users that
+// are already verified keep their original timestamp.
+func MarkEmailVerified(db *gorm.DB, userID uint, at time.Time) error {
+	return db.Model(&User{}).
+		Where("id = ? AND email_verified_at IS NULL", userID).
+		Update("email_verified_at", at).Error
+}
+
+func UpdateUserPassword(db *gorm.DB, userID uint, passwordHash string) error {
+	return db.Model(&User{}).
+		Where("id = ?", userID).
+		Update("password", passwordHash).Error
+}
+
+// RecordFailedLogin counts a wrong password for the user and locks the
+// account for lockFor(count) when that is positive. It returns the new count
+// and the end of the lock, if any.
+func RecordFailedLogin(db *gorm.DB, userID uint, now time.Time, lockFor func(count int) time.Duration) (int, *time.Time, error) {
+	var count int
+	var lockedUntil *time.Time
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&User{}).
+			Where("id = ?", userID).
+			Update("failed_login_count", gorm.Expr("failed_login_count + 1")).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&User{}).
+			Select("failed_login_count").
+			Where("id = ?", userID).
+			Scan(&count).Error; err != nil {
+			return err
+		}
+
+		if d := lockFor(count); d > 0 {
+			until := now.Add(d)
+			lockedUntil = &until
+			return tx.Model(&User{}).
+				Where("id = ?", userID).
+				Update("locked_until", until).Error
+		}
+		return nil
+	})
+
+	return count, lockedUntil, err
+}
+
+// ResetFailedLogins clears the failed login counter and any lock.
+func ResetFailedLogins(db *gorm.DB, userID uint) error {
+	return db.Model(&User{}).
+		Where("id = ? AND (failed_login_count > 0 OR locked_until IS NOT NULL)", userID).
+		Updates(map[string]any{"failed_login_count": 0, "locked_until": nil}).Error
+}
+
+// SetUserDisabled disables the account, or enables it again when at is nil.
+func SetUserDisabled(db *gorm.DB, userID uint, at *time.Time) error {
+	return db.Model(&User{}).
+		Where("id = ?", userID).
+		Update("disabled_at", at).Error
+}
+
+func SetUserRole(db *gorm.DB, userID uint, role string) error {
+	return db.Model(&User{}).
+		Where("id = ?", userID).
+		Update("role", role).Error
+}
+
+type ListUsersQuery struct {
+	// Search matches part of the email address or name.
+	Search string
+	Role   string
+	Cursor *Cursor
+	Limit  int
+}
+
+// ListUsers returns one page of users, newest first. It returns the cursor
+// for the next page, or nil on the last.
+func ListUsers(db *gorm.DB, query ListUsersQuery) ([]User, *Cursor, error) {
+	tx := db.Model(&User{})
+
+	if query.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(query.Search)) + "%"
+		tx = tx.Where(`(LOWER(email) LIKE ? ESCAPE '!' OR LOWER(name) LIKE ? ESCAPE '!')`, pattern, pattern)
+	}
+	if query.Role != "" {
+		tx = tx.Where("role = ?", query.Role)
+	}
+	if query.Cursor != nil {
+		tx = tx.Where("id < ?", query.Cursor.ID)
+	}
+
+	var users []User
+	if err := tx.Order("id DESC").Limit(query.Limit + 1).Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if len(users) <= query.Limit {
+		return users, nil, nil
+	}
+
+	users = users[:query.Limit]
+	return users, &Cursor{ID: users[len(users)-1].ID}, nil
+}
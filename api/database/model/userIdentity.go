@@ -0,0 +1,108 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrIdentityEmailUnverified is returned when an external identity would be
+// matched to an account by an address the provider did not verify.
+var ErrIdentityEmailUnverified = errors.New("identity provider did not verify the email address")
+
+// UserIdentity links an account at an external OpenID provider to a user.
+// Subject is the provider's stable ID for the account.
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"userId"`
+	Provider    string    `gorm:"size:64;not null;uniqueIndex:idx_user_identities_provider_subject" json:"provider"`
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_provider_subject" json:"-"`
+	Email       string    `gorm:"size:255" json:"email"`
+	LastLoginAt time.Time `json:"lastLoginAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ExternalLogin describes a user the provider vouched for.
+type ExternalLogin struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// LoginWithIdentity returns the user linked to the external identity. An
+// unknown identity is linked to the account with the same, provider
+// verified, email address, or to a new account when there is none.
+//
+// Linking to an account whose address was never verified locally clears its
+// password and revokes its sessions and keys: whoever registered it did not
+// prove owning the address, so they must not keep access to it.
+func LoginWithIdentity(db *gorm.DB, login ExternalLogin, now time.Time) (*User, error) {
+	var user User
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var identity UserIdentity
+		err := tx.First(&identity, "provider = ? AND subject = ?", login.Provider, login.Subject).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]any{"email": login.Email, "last_login_at": now}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if login.Email == "" || !login.EmailVerified {
+			return ErrIdentityEmailUnverified
+		}
+
+		err = tx.First(&user, "email = ?", login.Email).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			name := login.Name
+			if name == "" {
+				name = login.Email
+			}
+			user = User{Name: name, Email: login.Email, EmailVerifiedAt: &now}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !user.IsEmailVerified():
+			if err := tx.Model(&user).Updates(map[string]any{"password": "", "email_verified_at": now}).Error; err != nil {
+				return err
+			}
+			if _, err := RevokeUserSessions(tx, user.ID); err != nil {
+				return err
+			}
+			if err := tx.Model(&APIKey{}).
+				Where("user_id = ? AND revoked_at IS NULL", user.ID).
+				Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&UserIdentity{
+			UserID:      user.ID,
+			Provider:    login.Provider,
+			Subject:     login.Subject,
+			Email:       login.Email,
+			LastLoginAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func ListUserIdentities(db *gorm.DB, userID uint) ([]UserIdentity, error) {
+	identities := []UserIdentity{}
+	err := db.Where("user_id = ?", userID).Order("id").Find(&identities).Error
+	return identities, err
+}
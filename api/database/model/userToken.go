@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserToken purposes.
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposePasswordReset = "password_reset"
+	// TokenPurposeLoginChallenge is handed out instead of a session when the
+	// password was right but a second factor is still missing.
+	TokenPurposeLoginChallenge = "login_challenge"
+)
+
+// UserToken is a single-use secret mailed to a user, e.g. to verify their
+// email address. Only the hash of the token is stored.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"size:32;not null"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// CreateUserToken stores a new token and discards the user's unused tokens
+// for the same purpose, so only the most recent email works.
+func CreateUserToken(db *gorm.DB, token *UserToken) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", token.UserID, token.Purpose).
+			Delete(&UserToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(token).Error
+	})
+}
+
+// GetActiveUserToken returns the unused, unexpired token with the given hash
+// without using it up. It returns gorm.ErrRecordNotFound otherwise.
+func GetActiveUserToken(db *gorm.DB, purpose, tokenHash string) (*UserToken, error) {
+	var token UserToken
+	if err := db.First(&token, "token_hash = ? AND purpose = ?", tokenHash, purpose).Error; err != nil {
+		return nil, err
+	}
+
+	if token.UsedAt != nil || !time.Now().Before(token.ExpiresAt) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+// ConsumeUserToken marks the token with the given hash as used and returns
+// it. It returns gorm.ErrRecordNotFound when the token does not exist, has a
+// different purpose, expired or was already used.
+func ConsumeUserToken(db *gorm.DB, purpose, tokenHash string) (*UserToken, error) {
+	var token UserToken
+	if err := db.First(&token, "token_hash = ? AND purpose = ?", tokenHash, purpose).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	result := db.Model(&UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	token.UsedAt = &now
+	return &token, nil
+}
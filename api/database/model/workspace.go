@@ -0,0 +1,298 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Workspace member roles, from most to least privileged. Owners manage the
+// workspace and its members, editors manage links and viewers can only read.
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+var workspaceRoleRanks = map[string]int{
+	WorkspaceRoleViewer: 1,
+	WorkspaceRoleEditor: 2,
+	WorkspaceRoleOwner:  3,
+}
+
+// ErrLastWorkspaceOwner is returned when a change would leave a workspace
+// without an owner.
+var ErrLastWorkspaceOwner = errors.New("a workspace needs at least one owner")
+
+// Workspace shares ownership of short links between its members.
+type Workspace struct {
+	gorm.Model
+	Name        string `gorm:"size:100;not null"`
+	CreatedByID uint   `gorm:"not null"`
+}
+
+type WorkspaceMember struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user;index"`
+	Role        string `gorm:"size:16;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+
+	User *User `gorm:"foreignKey:UserID"`
+}
+
+// WorkspaceInvitation asks someone to join a workspace. The token is mailed
+// to Email and only its hash is stored.
+type WorkspaceInvitation struct {
+	ID          uint      `gorm:"primaryKey"`
+	WorkspaceID uint      `gorm:"not null;index"`
+	Email       string    `gorm:"size:255;not null"`
+	Role        string    `gorm:"size:16;not null"`
+	TokenHash   string    `gorm:"size:64;not null;uniqueIndex"`
+	InvitedByID uint      `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	AcceptedAt  *time.Time
+	CreatedAt   time.Time
+}
+
+func IsValidWorkspaceRole(role string) bool {
+	_, ok := workspaceRoleRanks[role]
+	return ok
+}
+
+// Can reports whether the member's role is at least role.
+func (m *WorkspaceMember) Can(role string) bool {
+	return workspaceRoleRanks[m.Role] >= workspaceRoleRanks[role]
+}
+
+func (i *WorkspaceInvitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
+
+// CreateWorkspace stores the workspace and makes its creator the owner.
+func CreateWorkspace(db *gorm.DB, workspace *Workspace) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      workspace.CreatedByID,
+			Role:        WorkspaceRoleOwner,
+		}).Error
+	})
+}
+
+func GetWorkspaceByID(db *gorm.DB, id uint) (*Workspace, error) {
+	var workspace Workspace
+	if err := db.First(&workspace, id).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// UserWorkspace is a workspace together with the user's role in it.
+type UserWorkspace struct {
+	Workspace
+	Role string
+}
+
+// ListUserWorkspaces returns every workspace the user is a member of.
+func ListUserWorkspaces(db *gorm.DB, userID uint) ([]UserWorkspace, error) {
+	var workspaces []UserWorkspace
+	err := db.Model(&Workspace{}).
+		Select("workspaces.*, workspace_members.role").
+		Joins("JOIN workspace_members ON workspace_members.workspace_id = workspaces.id").
+		Where("workspace_members.user_id = ?", userID).
+		Order("workspaces.name, workspaces.id").
+		Find(&workspaces).Error
+	return workspaces, err
+}
+
+func UpdateWorkspace(db *gorm.DB, workspace *Workspace, updates map[string]any) error {
+	return db.Model(workspace).Updates(updates).Error
+}
+
+// DeleteWorkspace soft-deletes the workspace together with its links, and
+// removes its members, invitations and domains. It returns the deleted
+// links.
+func DeleteWorkspace(db *gorm.DB, workspace *Workspace) ([]ShortLink, error) {
+	var links []ShortLink
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "code", "domain_id").Where("workspace_id = ?", workspace.ID).Find(&links).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&ShortLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&Domain{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(workspace).Error
+	})
+
+	return links, err
+}
+
+func GetWorkspaceMember(db *gorm.DB, workspaceID, userID uint) (*WorkspaceMember, error) {
+	var member WorkspaceMember
+	if err := db.First(&member, "workspace_id = ? AND user_id = ?", workspaceID, userID).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// ListWorkspaceMembers returns the members of a workspace with their users
+// loaded, in the order they joined.
+func ListWorkspaceMembers(db *gorm.DB, workspaceID uint) ([]WorkspaceMember, error) {
+	var members []WorkspaceMember
+	err := db.Preload("User").
+		Where("workspace_id = ?", workspaceID).
+		Order("id").
+		Find(&members).Error
+	return members, err
+}
+
+// UpdateWorkspaceMemberRole changes a member's role, refusing to demote the
+// last owner.
+func UpdateWorkspaceMemberRole(db *gorm.DB, member *WorkspaceMember, role string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if role != WorkspaceRoleOwner {
+			if err := ensureAnotherOwner(tx, member); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(member).Update("role", role).Error
+	})
+}
+
+// RemoveWorkspaceMember removes a member, refusing to remove the last owner.
+func RemoveWorkspaceMember(db *gorm.DB, member *WorkspaceMember) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureAnotherOwner(tx, member); err != nil {
+			return err
+		}
+
+		return tx.Delete(member).Error
+	})
+}
+
+// ensureAnotherOwner fails when member is the only owner of its workspace.
+// The owners are read from the database, not from member, and stay locked
+// until tx ends, so that two owners demoting or removing each other at once
+// cannot both count the other. SQLite has no row locks, but lets only one
+// transaction write at a time and fails the others.
+func ensureAnotherOwner(tx *gorm.DB, member *WorkspaceMember) error {
+	var owners []uint
+	if err := tx.Model(&WorkspaceMember{}).
+		Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("workspace_id = ? AND role = ?", member.WorkspaceID, WorkspaceRoleOwner).
+		Order("id").
+		Pluck("id", &owners).Error; err != nil {
+		return err
+	}
+	if slices.Equal(owners, []uint{member.ID}) {
+		return ErrLastWorkspaceOwner
+	}
+	return nil
+}
+
+// CreateWorkspaceInvitation stores an invitation, replacing pending ones for
+// the same address.
+func CreateWorkspaceInvitation(db *gorm.DB, invitation *WorkspaceInvitation) error {
+	invitation.Email = strings.ToLower(invitation.Email)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("workspace_id = ? AND email = ? AND accepted_at IS NULL", invitation.WorkspaceID, invitation.Email).
+			Delete(&WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(invitation).Error
+	})
+}
+
+// ListPendingWorkspaceInvitations returns the invitations that can still be
+// accepted.
+func ListPendingWorkspaceInvitations(db *gorm.DB, workspaceID uint, now time.Time) ([]WorkspaceInvitation, error) {
+	var invitations []WorkspaceInvitation
+	err := db.Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", workspaceID, now).
+		Order("id").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+func GetWorkspaceInvitation(db *gorm.DB, workspaceID, id uint) (*WorkspaceInvitation, error) {
+	var invitation WorkspaceInvitation
+	if err := db.First(&invitation, "workspace_id = ? AND id = ?", workspaceID, id).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func GetWorkspaceInvitationByHash(db *gorm.DB, tokenHash string) (*WorkspaceInvitation, error) {
+	var invitation WorkspaceInvitation
+	if err := db.First(&invitation, "token_hash = ?", tokenHash).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func DeleteWorkspaceInvitation(db *gorm.DB, invitation *WorkspaceInvitation) error {
+	return db.Delete(invitation).Error
+}
+
+// AcceptWorkspaceInvitation marks the invitation as used and adds the user to
+// the workspace. Users that already are members keep the higher of both
+// roles.
+func AcceptWorkspaceInvitation(db *gorm.DB, invitation *WorkspaceInvitation, userID uint) (*WorkspaceMember, error) {
+	var member *WorkspaceMember
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&WorkspaceInvitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Update("accepted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		existing, err := GetWorkspaceMember(tx, invitation.WorkspaceID, userID)
+		if err == nil {
+			member = existing
+			if workspaceRoleRanks[invitation.Role] > workspaceRoleRanks[existing.Role] {
+				return tx.Model(existing).Update("role", invitation.Role).Error
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		member = &WorkspaceMember{
+			WorkspaceID: invitation.WorkspaceID,
+			UserID:      userID,
+			Role:        invitation.Role,
+		}
+		return tx.Create(member).Error
+	})
+
+	return member, err
+}
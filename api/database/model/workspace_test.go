@@ -0,0 +1,84 @@
+package model_test
+
+import (
+	"errors"
+	"go-api/database/model"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// newWorkspace creates a workspace owned by its creator and returns its
+// members, the creator first, with the given roles for the others.
+func newWorkspace(t *testing.T, db *gorm.DB, roles ...string) []*model.WorkspaceMember {
+	t.Helper()
+
+	var members []*model.WorkspaceMember
+	for i := range len(roles) + 1 {
+		user := &model.User{Email: string(rune('a'+i)) + "@example.com", Name: "Test"}
+		if err := model.CreateUser(db, user); err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 {
+			workspace := &model.Workspace{Name: "Team", CreatedByID: user.ID}
+			if err := model.CreateWorkspace(db, workspace); err != nil {
+				t.Fatal(err)
+			}
+			member, err := model.GetWorkspaceMember(db, workspace.ID, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			members = append(members, member)
+			continue
+		}
+
+		member := &model.WorkspaceMember{WorkspaceID: members[0].WorkspaceID, UserID: user.ID, Role: roles[i-1]}
+		if err := db.Create(member).Error; err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, member)
+	}
+	return members
+}
+
+func TestLastWorkspaceOwner(t *testing.T) {
+	db := newTestDB(t)
+	members := newWorkspace(t, db, model.WorkspaceRoleOwner, model.WorkspaceRoleEditor)
+	first, second, editor := members[0], members[1], members[2]
+
+	if err := model.UpdateWorkspaceMemberRole(db, first, model.WorkspaceRoleViewer); err != nil {
+		t.Fatalf("demoting one of two owners: %v", err)
+	}
+
+	// Handlers pass members loaded earlier, so the check reads the owners
+	// again rather than trusting their roles: stale is first as it was
+	// before the demotion.
+	stale := *first
+	stale.Role = model.WorkspaceRoleOwner
+	if err := model.UpdateWorkspaceMemberRole(db, second, model.WorkspaceRoleEditor); !errors.Is(err, model.ErrLastWorkspaceOwner) {
+		t.Errorf("demoting the last owner = %v, want %v", err, model.ErrLastWorkspaceOwner)
+	}
+	if err := model.RemoveWorkspaceMember(db, second); !errors.Is(err, model.ErrLastWorkspaceOwner) {
+		t.Errorf("removing the last owner = %v, want %v", err, model.ErrLastWorkspaceOwner)
+	}
+	if err := model.RemoveWorkspaceMember(db, &stale); err != nil {
+		t.Errorf("removing a former owner: %v", err)
+	}
+
+	// Other members come and go, and the last owner can keep their role.
+	if err := model.UpdateWorkspaceMemberRole(db, editor, model.WorkspaceRoleViewer); err != nil {
+		t.Error(err)
+	}
+	if err := model.UpdateWorkspaceMemberRole(db, second, model.WorkspaceRoleOwner); err != nil {
+		t.Error(err)
+	}
+	if err := model.RemoveWorkspaceMember(db, editor); err != nil {
+		t.Error(err)
+	}
+
+	got, err := model.GetWorkspaceMember(db, second.WorkspaceID, second.UserID)
+	if err != nil || got.Role != model.WorkspaceRoleOwner {
+		t.Errorf("last owner = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,65 @@
+package entities
+
+import "time"
+
+type AdminUserListQuery struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Search string `form:"q" binding:"omitempty,max=200"`
+	Role   string `form:"role" binding:"omitempty,oneof=user admin"`
+}
+
+type AdminIDParams struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+type AdminReasonBody struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type AdminRoleBody struct {
+	Role   string `json:"role" binding:"required,oneof=user admin"`
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+type AdminUserResponse struct {
+	ID              uint       `json:"id"`
+	Email           string     `json:"email"`
+	Name            string     `json:"name"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	DisabledAt      *time.Time `json:"disabledAt"`
+	LockedUntil     *time.Time `json:"lockedUntil"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+type AdminUserListResponse struct {
+	Items      []AdminUserResponse `json:"items"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+type AdminAuditQuery struct {
+	Cursor     string `form:"cursor"`
+	Limit      int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	ActorID    uint   `form:"actorId"`
+	Action     string `form:"action" binding:"omitempty,max=64"`
+	TargetType string `form:"targetType" binding:"omitempty,oneof=user link"`
+	TargetID   uint   `form:"targetId"`
+}
+
+type AdminAuditLogResponse struct {
+	ID         uint           `json:"id"`
+	ActorID    uint           `json:"actorId"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"targetType"`
+	TargetID   uint           `json:"targetId"`
+	Reason     string         `json:"reason"`
+	Details    map[string]any `json:"details,omitempty"`
+	IP         string         `json:"ip"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+type AdminAuditListResponse struct {
+	Items      []AdminAuditLogResponse `json:"items"`
+	NextCursor string                  `json:"nextCursor,omitempty"`
+}
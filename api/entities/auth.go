@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 type AuthRegisterRequestBody struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8,max=64"`
@@ -9,3 +11,118 @@ type AuthLoginRequestBody struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8,max=64"`
 }
+
+type AuthRefreshRequestBody struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type AuthRegisterResponse struct {
+	UserID uint `json:"userId"`
+}
+
+type AuthLogoutAllResponse struct {
+	RevokedSessions int64 `json:"revokedSessions"`
+}
+
+type AuthTokenResponse struct {
+	UserID       uint   `json:"userId"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
+
+type AuthAPIKeyCreateRequestBody struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read links:create links:write"`
+}
+
+type AuthAPIKeyParams struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+type AuthAPIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	// Key is only returned once, when the key is created.
+	Key string `json:"key,omitempty"`
+}
+
+type AuthAPIKeyListResponse struct {
+	Keys []AuthAPIKeyResponse `json:"keys"`
+	// Scopes describes every scope a key can be given, by name.
+	Scopes map[string]string `json:"scopes"`
+}
+
+type AuthEmailRequestBody struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type AuthVerifyEmailRequestBody struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type AuthPasswordResetRequestBody struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=64"`
+}
+
+type AuthOIDCParams struct {
+	Provider string `uri:"provider" binding:"required"`
+}
+
+type AuthOIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// AuthOIDCCallbackQuery is what the provider appends to the callback URL,
+// either a code or an error.
+type AuthOIDCCallbackQuery struct {
+	Code             string `form:"code"`
+	State            string `form:"state" binding:"required"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// AuthTwoFactorCodeBody carries a code from the authenticator app, or one of
+// the recovery codes.
+type AuthTwoFactorCodeBody struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+type AuthTwoFactorVerifyBody struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required,max=32"`
+}
+
+type AuthTwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+	// QRPayload is the text to render as a QR code for authenticator apps
+	// to scan.
+	QRPayload string `json:"qrPayload"`
+}
+
+// AuthTwoFactorChallengeResponse answers a correct password of an account
+// with two-factor login. The challenge token is exchanged for a session at
+// /auth/2fa/verify.
+type AuthTwoFactorChallengeResponse struct {
+	UserID            uint   `json:"userId"`
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	ExpiresIn         int    `json:"expiresIn"`
+}
+
+type AuthTwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recoveryCodesRemaining"`
+}
+
+type AuthRecoveryCodesResponse struct {
+	// RecoveryCodes are only shown once.
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,30 @@
+package entities
+
+import "time"
+
+type DomainBody struct {
+	Hostname string `json:"hostname" binding:"required,max=253"`
+}
+
+type DomainParams struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// DomainVerificationRecord is the DNS TXT record that proves control of a
+// domain.
+type DomainVerificationRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type DomainResponse struct {
+	ID            uint                     `json:"id"`
+	Hostname      string                   `json:"hostname"`
+	WorkspaceID   *uint                    `json:"workspaceId,omitempty"`
+	Verified      bool                     `json:"verified"`
+	VerifiedAt    *time.Time               `json:"verifiedAt"`
+	LastCheckedAt *time.Time               `json:"lastCheckedAt"`
+	Verification  DomainVerificationRecord `json:"verification"`
+	CreatedAt     time.Time                `json:"createdAt"`
+}
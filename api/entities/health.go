@@ -7,3 +7,15 @@ type HealthPost struct {
 type HealthParams struct {
 	Quantity int `uri:"quantity" binding:"required"`
 }
+
+type HealthStatusResponse struct {
+	Status string `json:"status"`
+}
+
+type HealthMessageResponse struct {
+	Message string `json:"message"`
+}
+
+type HealthQuantityResponse struct {
+	Message int `json:"message"`
+}
@@ -0,0 +1,32 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OptionalTime tells a missing JSON field apart from an explicit null. Set
+// is true whenever the field was present; Value is nil for null.
+type OptionalTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (o *OptionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(data, &t); err != nil {
+		return err
+	}
+	o.Value = &t
+	return nil
+}
+
+func (o OptionalTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Value)
+}
@@ -1,9 +1,174 @@
 package entities
 
+import "time"
+
 type ShortenerParams struct {
-	UID int `uri:"uid" binding:"required"`
+	Code string `uri:"code" binding:"required"`
 }
 
 type ShortenerPost struct {
-	Url string `json:"url" binding:"required"`
+	Url  string `json:"url" binding:"required"`
+	Slug string `json:"slug" binding:"omitempty,min=3,max=64"`
+
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	ActivatesAt *time.Time `json:"activatesAt"`
+	MaxClicks   int        `json:"maxClicks" binding:"omitempty,min=0"`
+
+	// DomainID serves the link on a verified custom domain instead of the
+	// default host.
+	DomainID uint `json:"domainId"`
+}
+
+type ShortenerCreateResponse struct {
+	LongUrl  string `json:"longUrl"`
+	Code     string `json:"code"`
+	ShortUrl string `json:"shortUrl"`
+
+	ActivatesAt *time.Time `json:"activatesAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	MaxClicks   int        `json:"maxClicks"`
+	WorkspaceID *uint      `json:"workspaceId"`
+	DomainID    uint       `json:"domainId"`
+}
+
+type ShortenerIDParams struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+type ShortenerStatsQuery struct {
+	Interval  string    `form:"interval" binding:"omitempty,oneof=hour day week"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Referrers int       `form:"referrers" binding:"omitempty,min=1,max=100"`
+}
+
+// ShortenerStatsBucket counts the clicks of one interval starting at Start.
+type ShortenerStatsBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+type ShortenerReferrerCount struct {
+	Referrer string `json:"referrer"`
+	Count    int64  `json:"count"`
+}
+
+type ShortenerStatsResponse struct {
+	ID             uint                     `json:"id"`
+	Code           string                   `json:"code"`
+	From           time.Time                `json:"from"`
+	To             time.Time                `json:"to"`
+	Interval       string                   `json:"interval"`
+	TotalClicks    int                      `json:"totalClicks"`
+	UniqueVisitors int64                    `json:"uniqueVisitors"`
+	Buckets        []ShortenerStatsBucket   `json:"buckets"`
+	TopReferrers   []ShortenerReferrerCount `json:"topReferrers"`
+}
+
+type ShortenerListQuery struct {
+	ShortenerScopeQuery
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Search string `form:"q" binding:"omitempty,max=200"`
+	Sort   string `form:"sort" binding:"omitempty,oneof=createdAt -createdAt code -code url -url"`
+}
+
+// ShortenerPatch only changes the fields present in the request body. The
+// schedule fields accept null to remove the limit.
+type ShortenerPatch struct {
+	Url         *string      `json:"url" binding:"omitempty,min=1"`
+	Slug        *string      `json:"slug" binding:"omitempty,min=3,max=64"`
+	ExpiresAt   OptionalTime `json:"expiresAt"`
+	ActivatesAt OptionalTime `json:"activatesAt"`
+	MaxClicks   *int         `json:"maxClicks" binding:"omitempty,min=0"`
+	Disabled    *bool        `json:"disabled"`
+}
+
+type ShortLinkResponse struct {
+	ID          uint       `json:"id"`
+	Code        string     `json:"code"`
+	Url         string     `json:"url"`
+	ShortUrl    string     `json:"shortUrl"`
+	ActivatesAt *time.Time `json:"activatesAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	MaxClicks   int        `json:"maxClicks"`
+	ClickCount  int        `json:"clickCount"`
+	Disabled    bool       `json:"disabled"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+
+	TakenDownAt    *time.Time `json:"takenDownAt,omitempty"`
+	TakedownReason string     `json:"takedownReason,omitempty"`
+	WorkspaceID    *uint      `json:"workspaceId,omitempty"`
+	DomainID       uint       `json:"domainId,omitempty"`
+}
+
+type ShortLinkListResponse struct {
+	Items      []ShortLinkResponse `json:"items"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
+
+// ShortenerScopeQuery selects a workspace to work in. Without it requests
+// act on the user's personal links.
+type ShortenerScopeQuery struct {
+	Workspace uint `form:"workspace"`
+}
+
+type ShortenerBulkQuery struct {
+	ShortenerScopeQuery
+	// Mode is "atomic" (all rows or none) or "partial" (insert every valid row).
+	Mode string `form:"mode" binding:"omitempty,oneof=atomic partial"`
+}
+
+type ShortenerBulkRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+type ShortenerBulkCreated struct {
+	Row      int    `json:"row"`
+	ID       uint   `json:"id"`
+	Code     string `json:"code"`
+	ShortUrl string `json:"shortUrl"`
+}
+
+type ShortenerBulkResponse struct {
+	Mode    string                  `json:"mode"`
+	Created []ShortenerBulkCreated  `json:"created"`
+	Errors  []ShortenerBulkRowError `json:"errors"`
+}
+
+type ShortenerExportQuery struct {
+	ShortenerScopeQuery
+	Format string `form:"format" binding:"omitempty,oneof=csv ndjson"`
+}
+
+// ShortenerQRQuery styles the QR code of a link. Colours are hex, like
+// 1a2b3c, and may carry an alpha channel.
+type ShortenerQRQuery struct {
+	Format string `form:"format" binding:"omitempty,oneof=png svg"`
+	// Size is the width and height in pixels.
+	Size int `form:"size" binding:"omitempty,min=64,max=2048"`
+	// Level is the error correction level, from L (7%) to H (30%).
+	Level string `form:"level" binding:"omitempty,oneof=L M Q H"`
+	// Margin is the quiet zone around the code, in modules.
+	Margin     *int   `form:"margin" binding:"omitempty,min=0,max=16"`
+	Foreground string `form:"fg"`
+	Background string `form:"bg"`
+}
+
+type ShortenerExportRow struct {
+	ID          uint       `json:"id"`
+	Code        string     `json:"code"`
+	Url         string     `json:"url"`
+	ShortUrl    string     `json:"shortUrl"`
+	ActivatesAt *time.Time `json:"activatesAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	MaxClicks   int        `json:"maxClicks"`
+	Disabled    bool       `json:"disabled"`
+	TotalClicks int64      `json:"totalClicks"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	DomainID    uint       `json:"domainId,omitempty"`
 }
@@ -0,0 +1,58 @@
+package entities
+
+import "time"
+
+type WorkspaceBody struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type WorkspaceParams struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+type WorkspaceMemberParams struct {
+	ID     uint `uri:"id" binding:"required"`
+	UserID uint `uri:"userId" binding:"required"`
+}
+
+type WorkspaceInvitationParams struct {
+	ID           uint `uri:"id" binding:"required"`
+	InvitationID uint `uri:"invitationId" binding:"required"`
+}
+
+type WorkspaceMemberRoleBody struct {
+	Role string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+type WorkspaceInviteBody struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner editor viewer"`
+}
+
+type WorkspaceAcceptBody struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type WorkspaceResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WorkspaceMemberResponse struct {
+	UserID   uint      `json:"userId"`
+	Email    string    `json:"email"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+type WorkspaceInvitationResponse struct {
+	ID          uint      `json:"id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	InvitedByID uint      `json:"invitedById"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxBuckets caps the size of a time series response.
+const MaxBuckets = 1000
+
+var ErrTooManyBuckets = errors.New("requested range contains too many buckets for the interval")
+
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// IntervalDuration maps an interval name to its bucket width.
+func IntervalDuration(interval string) time.Duration {
+	switch interval {
+	case "hour":
+		return time.Hour
+	case "week":
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// DefaultRange returns the window used when a stats request omits from/to.
+func DefaultRange(interval string, now time.Time) (time.Time, time.Time) {
+	switch interval {
+	case "hour":
+		return now.Add(-48 * time.Hour), now
+	case "week":
+		return now.AddDate(0, 0, -7*12), now
+	default:
+		return now.AddDate(0, 0, -30), now
+	}
+}
+
+// Series returns the consecutive UTC buckets of the given width covering
+// [from, to), all empty, so the series can be plotted directly once counted.
+func Series(from, to time.Time, width time.Duration) ([]Bucket, error) {
+	start := from.UTC().Truncate(width)
+	end := to.UTC()
+
+	n := int(end.Sub(start)/width) + 1
+	if n > MaxBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	buckets := make([]Bucket, 0, n)
+	for t := start; t.Before(end); t = t.Add(width) {
+		buckets = append(buckets, Bucket{Start: t})
+	}
+	return buckets, nil
+}
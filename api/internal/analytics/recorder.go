@@ -0,0 +1,175 @@
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"go-api/database/model"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Options struct {
+	// BufferSize is how many events may be queued before new ones are dropped.
+	BufferSize int
+	// BatchSize is the number of events written per insert.
+	BatchSize int
+	// FlushInterval bounds how long an event waits in a partial batch.
+	FlushInterval time.Duration
+	// IPSalt is mixed into visitor IPs before hashing.
+	IPSalt string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		BufferSize:    10000,
+		BatchSize:     200,
+		FlushInterval: 2 * time.Second,
+	}
+}
+
+// Recorder collects click events and writes them to the database in batches
+// from a background goroutine so redirects never wait on an insert.
+type Recorder struct {
+	db     *gorm.DB
+	opts   Options
+	events chan model.ClickEvent
+
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+}
+
+func NewRecorder(db *gorm.DB, opts Options) *Recorder {
+	defaults := DefaultOptions()
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaults.BufferSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaults.BatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaults.FlushInterval
+	}
+
+	r := &Recorder{
+		db:     db,
+		opts:   opts,
+		events: make(chan model.ClickEvent, opts.BufferSize),
+		done:   make(chan struct{}),
+	}
+	go r.run()
+
+	return r
+}
+
+// Click describes a redirect as seen by the HTTP layer.
+type Click struct {
+	ShortLinkID uint
+	At          time.Time
+	Referrer    string
+	UserAgent   string
+	IP          string
+}
+
+// Record queues a click without blocking. Events are dropped when the buffer
+// is full.
+func (r *Recorder) Record(click Click) {
+	client := ParseUserAgent(click.UserAgent)
+
+	event := model.ClickEvent{
+		ShortLinkID: click.ShortLinkID,
+		OccurredAt:  click.At.UTC(),
+		Referrer:    truncate(click.Referrer, 2048),
+		UserAgent:   truncate(click.UserAgent, 1024),
+		IPHash:      HashIP(click.IP, r.opts.IPSalt),
+		Device:      client.Device,
+		Browser:     client.Browser,
+		OS:          client.OS,
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		r.dropped.Add(1)
+		return
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many events were discarded because the buffer was full.
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close stops accepting events and flushes everything still buffered.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	<-r.done
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.ClickEvent, 0, r.opts.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := model.CreateClickEvents(r.db, batch); err != nil {
+			log.Printf("Failed to write %d click events: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, event)
+			if len(batch) >= r.opts.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// HashIP returns a salted SHA-256 of the address so unique visitors can be
+// counted without storing IPs.
+func HashIP(ip, salt string) string {
+	sum := sha256.Sum256([]byte(salt + ip))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,92 @@
+package analytics
+
+import "strings"
+
+// Client describes the device family a click came from.
+type Client struct {
+	Device  string
+	Browser string
+	OS      string
+}
+
+const unknown = "Other"
+
+// ParseUserAgent derives device, browser and OS families from a User-Agent
+// header. It only looks for well known tokens and is deliberately cheap
+// enough to run on every redirect.
+func ParseUserAgent(ua string) Client {
+	s := strings.ToLower(ua)
+
+	return Client{
+		Device:  parseDevice(s),
+		Browser: parseBrowser(s),
+		OS:      parseOS(s),
+	}
+}
+
+func parseDevice(s string) string {
+	switch {
+	case s == "":
+		return unknown
+	case containsAny(s, "bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests", "go-http-client"):
+		return "Bot"
+	case containsAny(s, "ipad", "tablet") || (strings.Contains(s, "android") && !strings.Contains(s, "mobile")):
+		return "Tablet"
+	case containsAny(s, "mobi", "iphone", "ipod", "android"):
+		return "Mobile"
+	default:
+		return "Desktop"
+	}
+}
+
+func parseBrowser(s string) string {
+	// Order matters: most browsers also claim to be Safari or Chrome.
+	switch {
+	case strings.Contains(s, "edg/") || strings.Contains(s, "edge/"):
+		return "Edge"
+	case strings.Contains(s, "opr/") || strings.Contains(s, "opera"):
+		return "Opera"
+	case strings.Contains(s, "samsungbrowser"):
+		return "Samsung Internet"
+	case strings.Contains(s, "firefox/") || strings.Contains(s, "fxios"):
+		return "Firefox"
+	case strings.Contains(s, "chrome/") || strings.Contains(s, "crios"):
+		return "Chrome"
+	case strings.Contains(s, "safari/"):
+		return "Safari"
+	case strings.Contains(s, "msie") || strings.Contains(s, "trident/"):
+		return "Internet Explorer"
+	case strings.Contains(s, "curl/"):
+		return "curl"
+	default:
+		return unknown
+	}
+}
+
+func parseOS(s string) string {
+	switch {
+	case strings.Contains(s, "windows"):
+		return "Windows"
+	case containsAny(s, "iphone", "ipad", "ipod"):
+		return "iOS"
+	case strings.Contains(s, "mac os x") || strings.Contains(s, "macintosh"):
+		return "macOS"
+	case strings.Contains(s, "android"):
+		return "Android"
+	case strings.Contains(s, "cros"):
+		return "Chrome OS"
+	case strings.Contains(s, "linux"):
+		return "Linux"
+	default:
+		return unknown
+	}
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+// Package apiversion serves several versions of the API side by side. Each
+// version lives under /api/<version>; requests to /api paths that name no
+// version are served by the version in their Accept-Version header, or by
+// the default version.
+package apiversion
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Header is the request header that picks a version for paths
+	// without one.
+	Header = "Accept-Version"
+	// ServedHeader tells the client which version answered.
+	ServedHeader = "API-Version"
+)
+
+// Known lists the versions the routers implement, oldest first.
+var Known = []string{"v1", "v2"}
+
+// Version is one version of the API.
+type Version struct {
+	Name string
+	// Number orders versions: v2 is 2.
+	Number int
+	// Deprecated is when the version was deprecated, zero while it is not.
+	Deprecated time.Time
+	// Sunset is when the version is going to be turned off, zero when no
+	// date is set.
+	Sunset time.Time
+}
+
+// Prefix is the path the routes of v are registered under.
+func (v Version) Prefix() string {
+	return "/api/" + v.Name
+}
+
+func (v Version) IsDeprecated() bool {
+	return !v.Deprecated.IsZero()
+}
+
+func (v Version) String() string {
+	s := v.Name
+	if v.IsDeprecated() {
+		s += " deprecated=" + v.Deprecated.Format(time.DateOnly)
+	}
+	if !v.Sunset.IsZero() {
+		s += " sunset=" + v.Sunset.Format(time.DateOnly)
+	}
+	return s
+}
+
+// Normalize returns the canonical name of a version written as v2, V2 or 2,
+// and its number.
+func Normalize(name string) (string, int, bool) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(name), "v"), "V")
+	if digits == "" || digits[0] == '0' || digits[0] == '+' {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 {
+		return "", 0, false
+	}
+	return "v" + digits, n, true
+}
+
+// IsKnown reports whether the routers implement the version name.
+func IsKnown(name string) bool {
+	return slices.Contains(Known, name)
+}
+
+// ParseDeprecations parses a semicolon separated list of deprecated
+// versions such as
+//
+//	v1 deprecated=2026-06-01 sunset=2027-01-01
+//
+// Dates are days or RFC 3339 times. A version may have a sunset without
+// being deprecated yet.
+func ParseDeprecations(spec string) (map[string]Version, error) {
+	versions := make(map[string]Version)
+
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		v, err := parseDeprecation(item)
+		if err != nil {
+			return nil, fmt.Errorf("deprecation %q: %w", item, err)
+		}
+		if _, ok := versions[v.Name]; ok {
+			return nil, fmt.Errorf("deprecation %q: %s is listed twice", item, v.Name)
+		}
+		versions[v.Name] = v
+	}
+
+	return versions, nil
+}
+
+func parseDeprecation(item string) (Version, error) {
+	fields := strings.Fields(item)
+
+	name, number, ok := Normalize(fields[0])
+	if !ok {
+		return Version{}, fmt.Errorf("expected a version like v1, got %q", fields[0])
+	}
+	if !IsKnown(name) {
+		return Version{}, fmt.Errorf("unknown version %s, expected one of %s", name, strings.Join(Known, ", "))
+	}
+	if len(fields) == 1 {
+		return Version{}, fmt.Errorf("expected deprecated=date or sunset=date")
+	}
+
+	v := Version{Name: name, Number: number}
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		date, err := parseDate(value)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid %s date %q", key, value)
+		}
+		switch key {
+		case "deprecated":
+			v.Deprecated = date
+		case "sunset":
+			v.Sunset = date
+		default:
+			return Version{}, fmt.Errorf("unknown option %q", field)
+		}
+	}
+
+	if v.IsDeprecated() && !v.Sunset.IsZero() && v.Sunset.Before(v.Deprecated) {
+		return Version{}, fmt.Errorf("sunset is before the deprecation")
+	}
+	return v, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
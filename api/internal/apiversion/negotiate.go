@@ -0,0 +1,152 @@
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// How a request picked its version, as counted in the usage metric.
+const (
+	ByPath    = "path"
+	ByHeader  = "header"
+	ByDefault = "default"
+)
+
+// Versions are the versions served, oldest first.
+type Versions struct {
+	all []Version
+	def Version
+}
+
+// New serves the versions named, which must be known, deprecated as given
+// by ParseDeprecations. Requests that do not ask for a version get
+// defaultName.
+func New(names []string, defaultName string, deprecations map[string]Version) (*Versions, error) {
+	vs := &Versions{}
+	for _, given := range names {
+		name, number, ok := Normalize(given)
+		if !ok || !IsKnown(name) {
+			return nil, fmt.Errorf("unknown version %q, expected one of %s", given, strings.Join(Known, ", "))
+		}
+		if _, served := vs.Lookup(name); served {
+			return nil, fmt.Errorf("version %s is listed twice", name)
+		}
+
+		v := Version{Name: name, Number: number}
+		if d, ok := deprecations[name]; ok {
+			v.Deprecated, v.Sunset = d.Deprecated, d.Sunset
+		}
+		vs.all = append(vs.all, v)
+	}
+	if len(vs.all) == 0 {
+		return nil, fmt.Errorf("no version to serve")
+	}
+	slices.SortFunc(vs.all, func(a, b Version) int { return a.Number - b.Number })
+
+	def, ok := vs.Lookup(defaultName)
+	if !ok {
+		return nil, fmt.Errorf("default version %q is not served", defaultName)
+	}
+	vs.def = def
+	return vs, nil
+}
+
+// All returns the versions served, oldest first.
+func (vs *Versions) All() []Version {
+	return vs.all
+}
+
+// Default returns the version of requests that do not ask for one.
+func (vs *Versions) Default() Version {
+	return vs.def
+}
+
+// Names returns the names of the versions served.
+func (vs *Versions) Names() []string {
+	names := make([]string, len(vs.all))
+	for i, v := range vs.all {
+		names[i] = v.Name
+	}
+	return names
+}
+
+// Lookup finds a served version written as v2, V2 or 2.
+func (vs *Versions) Lookup(name string) (Version, bool) {
+	name, _, ok := Normalize(name)
+	if !ok {
+		return Version{}, false
+	}
+	i := slices.IndexFunc(vs.all, func(v Version) bool { return v.Name == name })
+	if i < 0 {
+		return Version{}, false
+	}
+	return vs.all[i], true
+}
+
+// PathVersion returns the version segment of an /api path, which may name
+// a version that is not served. ok is false for paths that name none.
+func PathVersion(path string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return "", false
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	if _, _, ok := Normalize(segment); !ok || segment[0] != 'v' {
+		return "", false
+	}
+	return segment, true
+}
+
+type selectedByKey struct{}
+
+// SelectedBy returns how the version of r was picked: ByPath, ByHeader or
+// ByDefault.
+func SelectedBy(r *http.Request) string {
+	if by, ok := r.Context().Value(selectedByKey{}).(string); ok {
+		return by
+	}
+	return ByPath
+}
+
+// Negotiate serves requests to /api paths that name no version with the
+// version asked for in the Accept-Version header, or the default one, by
+// adding it to the path before next routes the request. Paths that name a
+// version, even one that is not served, and requests asking for a version
+// that is not served are passed on unchanged for next to reject.
+func (vs *Versions) Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, named := PathVersion(r.URL.Path); named {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The answer depends on the header, so caches must keep it apart.
+		w.Header().Add("Vary", Header)
+
+		version, by := vs.def, ByDefault
+		if asked := r.Header.Get(Header); asked != "" {
+			var ok bool
+			if version, ok = vs.Lookup(asked); !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			by = ByHeader
+		}
+
+		u := *r.URL
+		u.Path = version.Prefix() + strings.TrimPrefix(u.Path, "/api")
+		if u.RawPath != "" {
+			u.RawPath = version.Prefix() + strings.TrimPrefix(u.RawPath, "/api")
+		}
+		versioned := r.WithContext(context.WithValue(r.Context(), selectedByKey{}, by))
+		versioned.URL = &u
+		next.ServeHTTP(w, versioned)
+	})
+}
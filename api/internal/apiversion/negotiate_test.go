@@ -0,0 +1,189 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newVersions(t *testing.T) *Versions {
+	t.Helper()
+	vs, err := New([]string{"v2", "1"}, "v1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs
+}
+
+func TestNegotiate(t *testing.T) {
+	vs := newVersions(t)
+
+	for _, tt := range []struct {
+		name    string
+		path    string
+		header  string
+		want    string
+		wantRaw string
+		wantBy  string
+		vary    bool
+	}{
+		{name: "outside api", path: "/short/abc", want: "/short/abc", wantBy: ByPath},
+		{name: "api root", path: "/api", want: "/api", wantBy: ByPath},
+		{name: "by path", path: "/api/v2/short", header: "v1", want: "/api/v2/short", wantBy: ByPath},
+		{name: "unserved path", path: "/api/v9/short", want: "/api/v9/short", wantBy: ByPath},
+		{name: "default", path: "/api/short", want: "/api/v1/short", wantBy: ByDefault, vary: true},
+		{name: "header", path: "/api/short", header: "v2", want: "/api/v2/short", wantBy: ByHeader, vary: true},
+		{name: "header number", path: "/api/short", header: "2", want: "/api/v2/short", wantBy: ByHeader, vary: true},
+		{name: "header upper case", path: "/api/short", header: "V2", want: "/api/v2/short", wantBy: ByHeader, vary: true},
+		{name: "unserved header", path: "/api/short", header: "v9", want: "/api/short", wantBy: ByPath, vary: true},
+		{name: "not a version", path: "/api/vendors/2", want: "/api/v1/vendors/2", wantBy: ByDefault, vary: true},
+		{name: "bare number", path: "/api/2/short", want: "/api/v1/2/short", wantBy: ByDefault, vary: true},
+		{name: "escaped path", path: "/api/short/a%2Fb", want: "/api/v1/short/a/b", wantRaw: "/api/v1/short/a%2Fb", wantBy: ByDefault, vary: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			h := vs.Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r }))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(Header, tt.header)
+			}
+			original := req.URL.String()
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if got == nil {
+				t.Fatal("request was not passed on")
+			}
+			if got.URL.Path != tt.want || got.URL.RawPath != tt.wantRaw {
+				t.Errorf("path = %q (raw %q), want %q (raw %q)", got.URL.Path, got.URL.RawPath, tt.want, tt.wantRaw)
+			}
+			if by := SelectedBy(got); by != tt.wantBy {
+				t.Errorf("selected by %s, want %s", by, tt.wantBy)
+			}
+			if vary := w.Header().Get("Vary") == Header; vary != tt.vary {
+				t.Errorf("Vary = %q", w.Header().Get("Vary"))
+			}
+			if req.URL.String() != original {
+				t.Errorf("original request was changed to %s", req.URL)
+			}
+		})
+	}
+}
+
+func TestPathVersion(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"/api/v1/short", "v1", true},
+		{"/api/v1", "v1", true},
+		// Versions in paths are lower case, like the routes.
+		{"/api/V2/short", "", false},
+		{"/api/v9/short", "v9", true},
+		{"/api/short", "", false},
+		{"/api/2/short", "", false},
+		{"/api/v0/short", "", false},
+		{"/api/v01/short", "", false},
+		{"/api/vx/short", "", false},
+		{"/api/", "", false},
+		{"/short/v1", "", false},
+		{"/apiv1/short", "", false},
+	} {
+		got, ok := PathVersion(tt.path)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("PathVersion(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	deprecated := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	vs, err := New([]string{"v2", "v1"}, "2", map[string]Version{"v1": {Name: "v1", Deprecated: deprecated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := vs.Names(); len(names) != 2 || names[0] != "v1" || names[1] != "v2" {
+		t.Errorf("Names = %v, want oldest first", names)
+	}
+	if vs.Default().Name != "v2" {
+		t.Errorf("Default = %s", vs.Default())
+	}
+	if v, _ := vs.Lookup("V1"); !v.Deprecated.Equal(deprecated) {
+		t.Errorf("v1 = %s, want it deprecated", v)
+	}
+	if _, ok := vs.Lookup("v3"); ok {
+		t.Error("v3 is served")
+	}
+
+	for _, tt := range []struct {
+		names []string
+		def   string
+	}{
+		{nil, "v1"},
+		{[]string{"v3"}, "v3"},
+		{[]string{"v1", "1"}, "v1"},
+		{[]string{"v1"}, "v2"},
+		{[]string{"latest"}, "v1"},
+	} {
+		if _, err := New(tt.names, tt.def, nil); err == nil {
+			t.Errorf("New(%v, %q) succeeded", tt.names, tt.def)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range []struct {
+		in     string
+		want   string
+		number int
+		ok     bool
+	}{
+		{"v1", "v1", 1, true},
+		{"V2", "v2", 2, true},
+		{" 2 ", "v2", 2, true},
+		{"v10", "v10", 10, true},
+		{"v0", "", 0, false},
+		{"v02", "", 0, false},
+		{"v+2", "", 0, false},
+		{"v-1", "", 0, false},
+		{"vv1", "", 0, false},
+		{"", "", 0, false},
+	} {
+		name, number, ok := Normalize(tt.in)
+		if name != tt.want || number != tt.number || ok != tt.ok {
+			t.Errorf("Normalize(%q) = %q, %d, %v", tt.in, name, number, ok)
+		}
+	}
+}
+
+func TestParseDeprecations(t *testing.T) {
+	versions, err := ParseDeprecations("v1 deprecated=2026-06-01 sunset=2027-01-01T12:00:00Z; 2 sunset=2028-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v1 := versions["v1"]
+	if !v1.Deprecated.Equal(time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)) || !v1.Sunset.Equal(time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("v1 = %s", v1)
+	}
+	if v2 := versions["v2"]; v2.IsDeprecated() || v2.Sunset.IsZero() {
+		t.Errorf("v2 = %s, want a sunset only", v2)
+	}
+
+	for _, spec := range []string{
+		"v1",
+		"v9 deprecated=2026-06-01",
+		"v1 deprecated=June",
+		"v1 retired=2026-06-01",
+		"v1 deprecated=2026-06-01 sunset=2026-01-01",
+		"v1 sunset=2027-01-01; V1 sunset=2028-01-01",
+	} {
+		if _, err := ParseDeprecations(spec); err == nil {
+			t.Errorf("ParseDeprecations(%q) succeeded", spec)
+		}
+	}
+}
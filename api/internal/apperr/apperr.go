@@ -0,0 +1,169 @@
+// Package apperr is the error model of the API. Handlers return an *Error
+// with the status, a stable machine readable code and a message for
+// people; the Errors middleware renders it as an RFC 7807 problem. Any
+// other error is reported as an internal error without its details.
+package apperr
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes shared by every part of the API. Routers define codes for their
+// own errors next to them.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidBody          = "invalid_body"
+	CodeInvalidPath          = "invalid_path"
+	CodeInvalidQuery         = "invalid_query"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+	CodeBadGateway           = "bad_gateway"
+)
+
+// Error is an error reported to the client.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Fields details which parts of the request were rejected.
+	Fields []FieldError
+
+	// cause is logged but never shown to clients.
+	cause error
+}
+
+// FieldError describes a rejected field of the request.
+type FieldError struct {
+	// Field is the name of the field as sent: its JSON, query or path name.
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(http.StatusUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(http.StatusForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+func Gone(code, message string) *Error {
+	return New(http.StatusGone, code, message)
+}
+
+func TooManyRequests(code, message string) *Error {
+	return New(http.StatusTooManyRequests, code, message)
+}
+
+func BadGateway(code, message string) *Error {
+	return New(http.StatusBadGateway, code, message)
+}
+
+// Internal reports an unexpected failure. The cause is kept for the logs;
+// clients only learn that something went wrong.
+func Internal(cause error) *Error {
+	return &Error{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: "Something went wrong.",
+		cause:   cause,
+	}
+}
+
+// Validation reports request fields that did not pass validation.
+func Validation(fields ...FieldError) *Error {
+	return BadRequest(CodeValidation, "The request is invalid.").WithFields(fields...)
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Code + ": " + e.Message + ": " + e.cause.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code, so errors.Is finds a predefined
+// error even after WithFields or WithCause copied it.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Status == e.Status
+}
+
+// WithFields returns a copy of e with fields added.
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	copied := *e
+	copied.Fields = append(append([]FieldError(nil), e.Fields...), fields...)
+	return &copied
+}
+
+// WithCause returns a copy of e that records cause for the logs.
+func (e *Error) WithCause(cause error) *Error {
+	copied := *e
+	copied.cause = cause
+	return &copied
+}
+
+// WithMessage returns a copy of e with another message.
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// From returns err as an *Error. Errors of any other type become internal
+// errors.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err)
+}
+
+// Handle adapts a handler that returns its error to gin. A returned error
+// aborts the request and is left for the Errors middleware to render.
+func Handle(fn func(c *gin.Context) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := fn(c); err != nil {
+			Abort(c, err)
+		}
+	}
+}
+
+// Abort stops the handler chain with err, for middleware.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,37 @@
+package apperr
+
+import "net/http"
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// TypePrefix starts the type URI of a problem, followed by its code.
+const TypePrefix = "urn:go-api:problem:"
+
+// Problem is the RFC 7807 body of an error response, with the code, the
+// request ID and the rejected fields as extension members.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"`
+
+	Code      string       `json:"code"`
+	RequestID string       `json:"requestId,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Problem describes e as it happened on the request to instance.
+func (e *Error) Problem(instance, requestID string) Problem {
+	return Problem{
+		Type:      TypePrefix + e.Code,
+		Title:     http.StatusText(e.Status),
+		Status:    e.Status,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestID: requestID,
+		Errors:    e.Fields,
+	}
+}
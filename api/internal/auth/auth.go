@@ -9,7 +9,8 @@ import (
 )
 
 var (
-	UserIdKey = "userID"
+	UserIdKey    = "userID"
+	SessionIDKey = "sessionID"
 )
 
 func GetCurrentUser(c *gin.Context, db *gorm.DB) (*model.User, bool) {
@@ -21,7 +22,7 @@ func GetCurrentUser(c *gin.Context, db *gorm.DB) (*model.User, bool) {
 
 	user, err := model.GetUserByID(db, uint(userId))
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("Failed to load current user %d: %v", userId, err)
 		return nil, false
 	}
 
@@ -31,3 +32,9 @@ func GetCurrentUser(c *gin.Context, db *gorm.DB) (*model.User, bool) {
 func GetCurrentUserID(c *gin.Context) uint {
 	return uint(c.GetInt(UserIdKey))
 }
+
+// GetCurrentSessionID returns the session the request's access token belongs
+// to, or an empty string.
+func GetCurrentSessionID(c *gin.Context) string {
+	return c.GetString(SessionIDKey)
+}
@@ -0,0 +1,11 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+var RoleKey = "role"
+
+// GetCurrentRole returns the role of the authenticated user, one of the
+// model.Role constants.
+func GetCurrentRole(c *gin.Context) string {
+	return c.GetString(RoleKey)
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authentication methods stored under AuthMethodKey.
+const (
+	MethodSession = "session"
+	MethodAPIKey  = "api_key"
+)
+
+// Scopes that can be granted to API keys. Sessions are never limited.
+const (
+	ScopeRead        = "read"
+	ScopeLinksCreate = "links:create"
+	ScopeLinksWrite  = "links:write"
+)
+
+// APIKeyPrefix marks bearer tokens that are API keys rather than JWTs.
+const APIKeyPrefix = "gak_"
+
+var (
+	AuthMethodKey = "authMethod"
+	ScopesKey     = "scopes"
+	APIKeyIDKey   = "apiKeyID"
+)
+
+// Scopes lists every scope with a short description.
+var Scopes = map[string]string{
+	ScopeRead:        "List links and read their stats",
+	ScopeLinksCreate: "Create links, one at a time or in bulk",
+	ScopeLinksWrite:  "Update, disable and delete links",
+}
+
+func IsValidScope(scope string) bool {
+	_, ok := Scopes[scope]
+	return ok
+}
+
+// GetAuthMethod returns how the current request was authenticated.
+func GetAuthMethod(c *gin.Context) string {
+	return c.GetString(AuthMethodKey)
+}
+
+// HasScope reports whether the current credentials grant scope.
+func HasScope(c *gin.Context, scope string) bool {
+	if GetAuthMethod(c) == MethodSession {
+		return true
+	}
+
+	return slices.Contains(c.GetStringSlice(ScopesKey), scope)
+}
+
+// GenerateAPIKey returns a new key and the prefix that identifies it.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	secret := strings.NewReplacer("-", "", "_", "").Replace(base64.RawURLEncoding.EncodeToString(b))
+	key = APIKeyPrefix + secret
+	return key, key[:len(APIKeyPrefix)+8], nil
+}
@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is a byte oriented key/value store with per-entry expiry. A ttl of
+// zero means the entry never expires.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Noop is a Cache that stores nothing. It is used when caching is disabled.
+type Noop struct{}
+
+func (Noop) Get(context.Context, string) ([]byte, bool, error) { return nil, false, nil }
+
+func (Noop) Set(context.Context, string, []byte, time.Duration) error { return nil }
+
+func (Noop) Delete(context.Context, ...string) error { return nil }
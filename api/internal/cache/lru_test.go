@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestLRU returns an LRU whose clock is moved by the returned function.
+func newTestLRU(capacity int) (*LRU, func(time.Duration)) {
+	c := NewLRU(capacity)
+	now := time.Unix(1_700_000_000, 0)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c, advance := newTestLRU(10)
+
+	c.Set(ctx, "short", []byte("a"), time.Minute)
+	c.Set(ctx, "forever", []byte("b"), 0)
+
+	advance(time.Minute - time.Nanosecond)
+	if value, ok, _ := c.Get(ctx, "short"); !ok || string(value) != "a" {
+		t.Errorf("before expiry: %q, %v", value, ok)
+	}
+
+	advance(time.Nanosecond)
+	if _, ok, _ := c.Get(ctx, "short"); ok {
+		t.Error("entry served after its ttl")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len = %d, want the expired entry removed", c.Len())
+	}
+
+	advance(24 * time.Hour)
+	if _, ok, _ := c.Get(ctx, "forever"); !ok {
+		t.Error("entry without a ttl expired")
+	}
+
+	// Setting an entry again replaces its ttl.
+	c.Set(ctx, "forever", []byte("c"), time.Second)
+	advance(time.Second)
+	if _, ok, _ := c.Get(ctx, "forever"); ok {
+		t.Error("entry kept its old ttl")
+	}
+}
+
+func TestLRUNegativeEntries(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestLRU(10)
+
+	// An empty value is stored and found, unlike a missing key.
+	c.Set(ctx, "missing-link", nil, time.Minute)
+	if value, ok, err := c.Get(ctx, "missing-link"); !ok || len(value) != 0 || err != nil {
+		t.Errorf("negative entry: %q, %v, %v", value, ok, err)
+	}
+	if _, ok, _ := c.Get(ctx, "unknown"); ok {
+		t.Error("unknown key found")
+	}
+
+	c.Delete(ctx, "missing-link", "unknown")
+	if _, ok, _ := c.Get(ctx, "missing-link"); ok {
+		t.Error("deleted entry found")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestLRU(3)
+
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(ctx, key, []byte(key), 0)
+	}
+
+	// Reading a and rewriting b leaves c the least recently used.
+	c.Get(ctx, "a")
+	c.Set(ctx, "b", []byte("b2"), 0)
+	c.Set(ctx, "d", []byte("d"), 0)
+
+	if _, ok, _ := c.Get(ctx, "c"); ok {
+		t.Error("c was kept, want it evicted first")
+	}
+	for _, key := range []string{"a", "b", "d"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+
+	// Order is now a, b, d from least recently used; misses do not count.
+	c.Get(ctx, "c")
+	c.Set(ctx, "e", nil, 0)
+	c.Set(ctx, "f", nil, 0)
+	for key, want := range map[string]bool{"a": false, "b": false, "d": true, "e": true, "f": true} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("%s present = %v, want %v", key, ok, want)
+		}
+	}
+
+	if c.Len() != 3 || c.Evictions() != 3 {
+		t.Errorf("Len = %d, Evictions = %d, want 3 and 3", c.Len(), c.Evictions())
+	}
+}
+
+func TestLRUDefaultCapacity(t *testing.T) {
+	if c := NewLRU(0); c.capacity != 10000 {
+		t.Errorf("capacity = %d", c.capacity)
+	}
+}
@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go-api/internal/resp"
+	"net"
+	"strconv"
+	"time"
+)
+
+type RedisOptions struct {
+	Addr        string
+	Password    string
+	DB          int
+	PoolSize    int
+	DialTimeout time.Duration
+	// Prefix is prepended to every key so several apps can share a server.
+	Prefix string
+}
+
+// Redis is a Cache backed by any server speaking the Redis protocol.
+type Redis struct {
+	opts RedisOptions
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func NewRedis(opts RedisOptions) *Redis {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = 2 * time.Second
+	}
+
+	return &Redis{
+		opts: opts,
+		pool: make(chan *redisConn, opts.PoolSize),
+	}
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Do(ctx, "GET", c.opts.Prefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %T", reply)
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", c.opts.Prefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, c.opts.Prefix+key)
+	}
+
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+// Do sends a raw command and returns the decoded reply. Error replies are
+// returned as resp.Error.
+func (c *Redis) Do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, args)
+	if err != nil {
+		conn.conn.Close()
+		return nil, err
+	}
+	c.put(conn)
+
+	if replyErr, ok := reply.(resp.Error); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+// Doer sends raw commands to a Redis server.
+type Doer interface {
+	Do(ctx context.Context, args ...string) (any, error)
+}
+
+// WithConn runs fn on a single pooled connection, which commands that keep
+// per-connection state such as WATCH and MULTI need. The connection is
+// discarded instead of reused when fn fails.
+func (c *Redis) WithConn(ctx context.Context, fn func(conn Doer) error) error {
+	conn, err := c.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(conn); err != nil {
+		conn.conn.Close()
+		return err
+	}
+	c.put(conn)
+	return nil
+}
+
+// Key returns key with the configured prefix, for callers building raw
+// commands.
+func (c *Redis) Key(key string) string {
+	return c.opts.Prefix + key
+}
+
+// Ping checks that the server answers.
+func (c *Redis) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close closes all idle connections.
+func (c *Redis) Close() error {
+	for {
+		select {
+		case conn := <-c.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: c.opts.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{
+		conn: netConn,
+		r:    bufio.NewReader(netConn),
+		w:    bufio.NewWriter(netConn),
+	}
+
+	if c.opts.Password != "" {
+		if err := conn.expectOK(ctx, "AUTH", c.opts.Password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.DB != 0 {
+		if err := conn.expectOK(ctx, "SELECT", strconv.Itoa(c.opts.DB)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Redis) put(conn *redisConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (c *redisConn) do(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := resp.WriteCommand(c.w, args...); err != nil {
+		return nil, err
+	}
+	return resp.ReadValue(c.r)
+}
+
+// Do implements Doer for a pinned connection.
+func (c *redisConn) Do(ctx context.Context, args ...string) (any, error) {
+	reply, err := c.do(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if replyErr, ok := reply.(resp.Error); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+func (c *redisConn) expectOK(ctx context.Context, args ...string) error {
+	reply, err := c.do(ctx, args)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(resp.Error); ok {
+		return replyErr
+	}
+	if reply != resp.SimpleString("OK") {
+		return errors.New("redis: unexpected reply to " + args[0])
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"go-api/internal/cache"
+	"go-api/internal/cache/redistest"
+	"go-api/internal/resp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newRedis(t *testing.T, opts cache.RedisOptions) (*cache.Redis, *redistest.Server) {
+	t.Helper()
+
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	opts.Addr = srv.Addr()
+	client := cache.NewRedis(opts)
+	t.Cleanup(func() { client.Close() })
+	return client, srv
+}
+
+func TestRedisGetSetDelete(t *testing.T) {
+	client, _ := newRedis(t, cache.RedisOptions{})
+	ctx := context.Background()
+
+	if _, found, err := client.Get(ctx, "missing"); err != nil || found {
+		t.Fatalf("Get(missing) = found %v, %v", found, err)
+	}
+
+	if err := client.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set(ctx, "b", []byte{}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	value, found, err := client.Get(ctx, "a")
+	if err != nil || !found || string(value) != "1" {
+		t.Errorf("Get(a) = %q, %v, %v", value, found, err)
+	}
+	// Empty values are how the link cache remembers unknown codes, so they
+	// must be told apart from misses.
+	value, found, err = client.Get(ctx, "b")
+	if err != nil || !found || len(value) != 0 {
+		t.Errorf("Get(b) = %q, %v, %v", value, found, err)
+	}
+
+	if err := client.Delete(ctx, "a", "b", "missing"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, found, _ := client.Get(ctx, key); found {
+			t.Errorf("%s survived Delete", key)
+		}
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	client, _ := newRedis(t, cache.RedisOptions{})
+	ctx := context.Background()
+
+	if err := client.Set(ctx, "short", []byte("x"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set(ctx, "long", []byte("y"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := client.Get(ctx, "short"); !found {
+		t.Fatal("entry expired right away")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, found, _ := client.Get(ctx, "short"); found {
+		t.Error("entry outlived its ttl")
+	}
+	if _, found, _ := client.Get(ctx, "long"); !found {
+		t.Error("entry with a long ttl expired")
+	}
+}
+
+func TestRedisPrefix(t *testing.T) {
+	client, srv := newRedis(t, cache.RedisOptions{Prefix: "app:"})
+	ctx := context.Background()
+
+	if err := client.Set(ctx, "k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if keys := srv.Keys(); !slices.Equal(keys, []string{"app:k"}) {
+		t.Errorf("server keys = %v, want [app:k]", keys)
+	}
+	if got := client.Key("k"); got != "app:k" {
+		t.Errorf("Key(k) = %q", got)
+	}
+
+	if err := client.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := srv.Keys(); len(keys) != 0 {
+		t.Errorf("server keys after Delete = %v", keys)
+	}
+}
+
+func TestRedisReusesConnections(t *testing.T) {
+	client, srv := newRedis(t, cache.RedisOptions{Password: "secret", DB: 2})
+	ctx := context.Background()
+
+	for range 5 {
+		if err := client.Ping(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// AUTH and SELECT run once per new connection.
+	if n := srv.CommandCount("AUTH"); n != 1 {
+		t.Errorf("AUTH sent %d times, want 1", n)
+	}
+	if n := srv.CommandCount("SELECT"); n != 1 {
+		t.Errorf("SELECT sent %d times, want 1", n)
+	}
+}
+
+func TestRedisErrorReply(t *testing.T) {
+	client, _ := newRedis(t, cache.RedisOptions{})
+	ctx := context.Background()
+
+	_, err := client.Do(ctx, "NOPE")
+	var replyErr resp.Error
+	if !errors.As(err, &replyErr) {
+		t.Fatalf("Do(NOPE) = %v, want a resp.Error", err)
+	}
+
+	// The connection stays usable after an error reply.
+	if err := client.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRedisUnreachable(t *testing.T) {
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := srv.Addr()
+	srv.Close()
+
+	client := cache.NewRedis(cache.RedisOptions{Addr: addr, DialTimeout: time.Second})
+	if _, _, err := client.Get(context.Background(), "k"); err == nil {
+		t.Error("Get succeeded without a server")
+	}
+}
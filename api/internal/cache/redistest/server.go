@@ -0,0 +1,428 @@
+// Package redistest provides an in-memory server that speaks enough of the
+// Redis protocol to exercise the Redis backed components without a real
+// Redis instance.
+package redistest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"go-api/internal/resp"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// session is the per-connection state of WATCH and MULTI.
+type session struct {
+	watched map[string]uint64
+	inMulti bool
+	queued  [][]string
+}
+
+// Server is an in-memory stand-in for Redis.
+type Server struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	data map[string]entry
+	wg   sync.WaitGroup
+
+	// versions counts writes per key so EXEC can detect changes to watched
+	// keys.
+	versions map[string]uint64
+
+	commands map[string]int
+
+	// scripts holds the stand-ins for Lua scripts by SHA1. Like Redis,
+	// EVALSHA only runs the loaded ones, those sent with EVAL or SCRIPT LOAD.
+	scripts map[string]ScriptFunc
+	loaded  map[string]bool
+}
+
+// ScriptFunc stands in for a Lua script, which the server cannot run. It
+// runs under the server lock, so it is atomic like the script would be, and
+// reaches the keys through kv. The reply is encoded like that of a script:
+// int64, string, []byte, []any, nil or an error.
+type ScriptFunc func(kv *KV, keys, args []string) any
+
+// KV gives a ScriptFunc access to the keys.
+type KV struct {
+	s *Server
+}
+
+// Get returns the value of key.
+func (kv *KV) Get(key string) ([]byte, bool) {
+	e, ok := kv.s.lookup(key)
+	return e.value, ok
+}
+
+// Set stores value under key, expiring after ttl unless it is zero.
+func (kv *KV) Set(key string, value []byte, ttl time.Duration) {
+	e := entry{value: value}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	kv.s.data[key] = e
+	kv.s.versions[key]++
+}
+
+// NewServer starts a server on a random local port.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		ln:       ln,
+		data:     make(map[string]entry),
+		versions: make(map[string]uint64),
+		commands: make(map[string]int),
+		scripts:  make(map[string]ScriptFunc),
+		loaded:   make(map[string]bool),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the address clients should dial.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close stops accepting connections and waits for the listener to exit.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Script registers fn to run in place of the Lua script src.
+func (s *Server) Script(src string, fn ScriptFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[scriptSHA(src)] = fn
+}
+
+// CommandCount returns how many times the named command was received.
+func (s *Server) CommandCount(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands[strings.ToUpper(name)]
+}
+
+// Keys returns every live key.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if _, ok := s.lookup(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	sess := &session{}
+
+	for {
+		args, err := resp.ReadCommand(r)
+		if err != nil {
+			return
+		}
+
+		s.exec(w, sess, args)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, sess *session, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.ToUpper(args[0])
+	s.commands[name]++
+
+	switch name {
+	case "WATCH":
+		if sess.inMulti {
+			resp.WriteError(w, "ERR WATCH inside MULTI is not allowed")
+			return
+		}
+		if sess.watched == nil {
+			sess.watched = make(map[string]uint64)
+		}
+		for _, key := range args[1:] {
+			s.lookup(key)
+			sess.watched[key] = s.versions[key]
+		}
+		resp.WriteSimple(w, "OK")
+	case "UNWATCH":
+		sess.watched = nil
+		resp.WriteSimple(w, "OK")
+	case "MULTI":
+		if sess.inMulti {
+			resp.WriteError(w, "ERR MULTI calls can not be nested")
+			return
+		}
+		sess.inMulti = true
+		resp.WriteSimple(w, "OK")
+	case "DISCARD":
+		if !sess.inMulti {
+			resp.WriteError(w, "ERR DISCARD without MULTI")
+			return
+		}
+		*sess = session{}
+		resp.WriteSimple(w, "OK")
+	case "EXEC":
+		if !sess.inMulti {
+			resp.WriteError(w, "ERR EXEC without MULTI")
+			return
+		}
+		queued, watched := sess.queued, sess.watched
+		*sess = session{}
+
+		for key, version := range watched {
+			s.lookup(key)
+			if s.versions[key] != version {
+				resp.WriteNilArray(w)
+				return
+			}
+		}
+
+		resp.WriteArrayHeader(w, len(queued))
+		for _, cmd := range queued {
+			s.run(w, strings.ToUpper(cmd[0]), cmd[1:])
+		}
+	default:
+		if sess.inMulti {
+			sess.queued = append(sess.queued, args)
+			resp.WriteSimple(w, "QUEUED")
+			return
+		}
+		s.run(w, name, args[1:])
+	}
+}
+
+// run executes a single data command. The caller must hold s.mu.
+func (s *Server) run(w *bufio.Writer, name string, args []string) {
+	switch name {
+	case "PING":
+		resp.WriteSimple(w, "PONG")
+	case "AUTH", "SELECT":
+		resp.WriteSimple(w, "OK")
+	case "GET":
+		if len(args) != 1 {
+			wrongArgs(w, name)
+			return
+		}
+		if e, ok := s.lookup(args[0]); ok {
+			resp.WriteBulk(w, e.value)
+		} else {
+			resp.WriteNil(w)
+		}
+	case "SET":
+		s.set(w, args)
+	case "DEL":
+		var n int64
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				n++
+				s.versions[key]++
+			}
+			delete(s.data, key)
+		}
+		resp.WriteInt(w, n)
+	case "EXISTS":
+		var n int64
+		for _, key := range args {
+			if _, ok := s.lookup(key); ok {
+				n++
+			}
+		}
+		resp.WriteInt(w, n)
+	case "EVAL":
+		if len(args) < 2 {
+			wrongArgs(w, name)
+			return
+		}
+		sha := scriptSHA(args[0])
+		if _, ok := s.scripts[sha]; !ok {
+			resp.WriteError(w, "ERR redistest: script not registered")
+			return
+		}
+		s.loaded[sha] = true
+		s.eval(w, sha, args[1:])
+	case "EVALSHA":
+		if len(args) < 2 {
+			wrongArgs(w, name)
+			return
+		}
+		sha := strings.ToLower(args[0])
+		if !s.loaded[sha] {
+			resp.WriteError(w, "NOSCRIPT No matching script. Please use EVAL.")
+			return
+		}
+		s.eval(w, sha, args[1:])
+	case "SCRIPT":
+		if len(args) == 2 && strings.EqualFold(args[0], "LOAD") {
+			sha := scriptSHA(args[1])
+			s.loaded[sha] = true
+			resp.WriteBulk(w, []byte(sha))
+			return
+		}
+		resp.WriteError(w, "ERR redistest: unsupported SCRIPT subcommand")
+	case "FLUSHALL", "FLUSHDB":
+		for key := range s.data {
+			s.versions[key]++
+		}
+		s.data = make(map[string]entry)
+		resp.WriteSimple(w, "OK")
+	default:
+		resp.WriteError(w, "ERR unknown command '"+name+"'")
+	}
+}
+
+// eval runs the script sha with args of the form numkeys key... arg...
+func (s *Server) eval(w *bufio.Writer, sha string, args []string) {
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 || n > len(args)-1 {
+		resp.WriteError(w, "ERR Number of keys can't be greater than number of args")
+		return
+	}
+
+	fn, ok := s.scripts[sha]
+	if !ok {
+		resp.WriteError(w, "ERR redistest: script not registered")
+		return
+	}
+	writeReply(w, fn(&KV{s: s}, args[1:1+n], args[1+n:]))
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		resp.WriteNil(w)
+	case int64:
+		resp.WriteInt(w, v)
+	case string:
+		resp.WriteBulk(w, []byte(v))
+	case []byte:
+		resp.WriteBulk(w, v)
+	case []any:
+		resp.WriteArrayHeader(w, len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	case error:
+		resp.WriteError(w, v.Error())
+	default:
+		resp.WriteError(w, fmt.Sprintf("ERR redistest: unsupported script reply %T", reply))
+	}
+}
+
+func scriptSHA(src string) string {
+	sum := sha1.Sum([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) set(w *bufio.Writer, args []string) {
+	if len(args) < 2 {
+		wrongArgs(w, "SET")
+		return
+	}
+
+	key := args[0]
+	e := entry{value: []byte(args[1])}
+	var nx, xx bool
+
+	for i := 2; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				resp.WriteError(w, "ERR syntax error")
+				return
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				resp.WriteError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Millisecond
+			if opt == "EX" {
+				unit = time.Second
+			}
+			e.expiresAt = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			resp.WriteError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	_, exists := s.lookup(key)
+	if (nx && exists) || (xx && !exists) {
+		resp.WriteNil(w)
+		return
+	}
+
+	s.data[key] = e
+	s.versions[key]++
+	resp.WriteSimple(w, "OK")
+}
+
+// lookup returns the entry for key, dropping it if it expired. The caller
+// must hold s.mu.
+func (s *Server) lookup(key string) (entry, bool) {
+	e, ok := s.data[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(s.data, key)
+		s.versions[key]++
+		return entry{}, false
+	}
+	return e, true
+}
+
+func wrongArgs(w *bufio.Writer, name string) {
+	resp.WriteError(w, "ERR wrong number of arguments for '"+strings.ToLower(name)+"' command")
+}
@@ -0,0 +1,206 @@
+// Package config holds the settings of the API in one typed struct. Every
+// setting is read from the environment, then an optional .env file, then an
+// optional YAML file, and falls back to its default; see Load.
+//
+// Fields are described by struct tags: env is the variable name, yaml the
+// key within its section of the YAML file, default the value used when no
+// source sets it, and secret marks values that must not be printed.
+// Durations given as plain numbers are in seconds unless unit says ms.
+package config
+
+import (
+	"time"
+)
+
+type Config struct {
+	Server     Server     `yaml:"server"`
+	API        API        `yaml:"api"`
+	Log        Log        `yaml:"log"`
+	Database   Database   `yaml:"database"`
+	Cache      Cache      `yaml:"cache"`
+	Redis      Redis      `yaml:"redis"`
+	Auth       Auth       `yaml:"auth"`
+	Mail       Mail       `yaml:"mail"`
+	RateLimit  RateLimit  `yaml:"rate_limit"`
+	Shortener  Shortener  `yaml:"shortener"`
+	URLCheck   URLCheck   `yaml:"url_check"`
+	Domains    Domains    `yaml:"domains"`
+	Workspaces Workspaces `yaml:"workspaces"`
+	Analytics  Analytics  `yaml:"analytics"`
+	// OIDC comes last so the per-provider settings follow it.
+	OIDC OIDC `yaml:"oidc"`
+
+	settings []Setting
+}
+
+type Server struct {
+	Port    string `env:"PORT" yaml:"port" default:":8080"`
+	GinMode string `env:"GIN_MODE" yaml:"gin_mode" default:"release"`
+	// ShutdownTimeout is how long in-flight requests, and then the shutdown
+	// hooks, get to finish.
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" default:"30"`
+	// ShutdownDelay keeps the listener open after readiness starts failing,
+	// so load balancers notice first.
+	ShutdownDelay    time.Duration `env:"SHUTDOWN_DELAY" yaml:"shutdown_delay" default:"0"`
+	ReadinessTimeout time.Duration `env:"READINESS_TIMEOUT" yaml:"readiness_timeout" default:"2"`
+	// MetricsToken, when set, must be sent as a bearer token to /metrics.
+	MetricsToken string `env:"METRICS_TOKEN" yaml:"metrics_token" secret:"true"`
+}
+
+// API picks the versions of the API served side by side.
+type API struct {
+	// Versions are served under /api/<version>; each must be one the
+	// routers implement.
+	Versions []string `env:"API_VERSIONS" yaml:"versions" default:"v1,v2"`
+	// DefaultVersion serves /api paths without a version when the request
+	// has no Accept-Version header.
+	DefaultVersion string `env:"API_DEFAULT_VERSION" yaml:"default_version" default:"v1"`
+	// Deprecations lists deprecated versions, like
+	// "v1 deprecated=2026-06-01 sunset=2027-01-01"; see
+	// apiversion.ParseDeprecations.
+	Deprecations string `env:"API_DEPRECATIONS" yaml:"deprecations"`
+}
+
+type Log struct {
+	Level  string `env:"LOG_LEVEL" yaml:"level" default:"info"`
+	Format string `env:"LOG_FORMAT" yaml:"format" default:"json"`
+}
+
+type Database struct {
+	Driver string `env:"DB_DRIVER" yaml:"driver" default:"postgres"`
+	// DSN defaults to database.DefaultDSN of the driver.
+	DSN         string        `env:"DB_CONNECTION_STRING" yaml:"connection_string" secret:"true"`
+	AutoMigrate bool          `env:"DB_AUTO_MIGRATE" yaml:"auto_migrate" default:"false"`
+	SlowQuery   time.Duration `env:"DB_SLOW_QUERY_MS" yaml:"slow_query_ms" default:"200" unit:"ms"`
+	// MigrationLockTimeout is how long the migrate command waits for another
+	// instance to finish.
+	MigrationLockTimeout time.Duration `env:"MIGRATION_LOCK_TIMEOUT_SECONDS" yaml:"migration_lock_timeout" default:"60"`
+}
+
+type Cache struct {
+	// Driver is memory, redis or none.
+	Driver      string        `env:"CACHE_DRIVER" yaml:"driver" default:"memory"`
+	Size        int           `env:"CACHE_SIZE" yaml:"size" default:"10000"`
+	TTL         time.Duration `env:"CACHE_TTL" yaml:"ttl" default:"300"`
+	NegativeTTL time.Duration `env:"CACHE_NEGATIVE_TTL" yaml:"negative_ttl" default:"30"`
+	Prefix      string        `env:"CACHE_PREFIX" yaml:"prefix" default:"go-api:"`
+}
+
+// Redis is the server used by the redis cache and rate limit store.
+type Redis struct {
+	Addr     string `env:"REDIS_ADDR" yaml:"addr" default:"localhost:6379"`
+	Password string `env:"REDIS_PASSWORD" yaml:"password" secret:"true"`
+	DB       int    `env:"REDIS_DB" yaml:"db" default:"0"`
+}
+
+type Auth struct {
+	// JWTSecret signs access tokens. It has no default and must be at
+	// least MinJWTSecretLength bytes.
+	JWTSecret       string        `env:"JWT_SECRET_KEY" yaml:"jwt_secret_key" secret:"true"`
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" yaml:"access_token_ttl" default:"900"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" yaml:"refresh_token_ttl" default:"2592000"`
+	// Reaching LockoutThreshold failed logins in a row locks an account for
+	// LockoutBase, doubling with every further failure up to LockoutMax.
+	LockoutThreshold     int           `env:"LOGIN_LOCKOUT_THRESHOLD" yaml:"lockout_threshold" default:"5"`
+	LockoutBase          time.Duration `env:"LOGIN_LOCKOUT_BASE" yaml:"lockout_base" default:"30"`
+	LockoutMax           time.Duration `env:"LOGIN_LOCKOUT_MAX" yaml:"lockout_max" default:"3600"`
+	EmailVerificationTTL time.Duration `env:"EMAIL_VERIFICATION_TTL" yaml:"email_verification_ttl" default:"86400"`
+	PasswordResetTTL     time.Duration `env:"PASSWORD_RESET_TTL" yaml:"password_reset_ttl" default:"3600"`
+	LoginChallengeTTL    time.Duration `env:"LOGIN_CHALLENGE_TTL" yaml:"login_challenge_ttl" default:"300"`
+	TOTPIssuer           string        `env:"TOTP_ISSUER" yaml:"totp_issuer" default:"go-api"`
+	// RequireVerifiedEmail keeps users with unconfirmed addresses from
+	// creating links.
+	RequireVerifiedEmail bool `env:"REQUIRE_VERIFIED_EMAIL" yaml:"require_verified_email" default:"false"`
+}
+
+type OIDC struct {
+	// Providers names the identity providers users can log in with. Each
+	// one is configured by OIDC_<NAME>_* variables, or the oidc.<name>
+	// section of the YAML file.
+	Providers []string      `env:"OIDC_PROVIDERS" yaml:"providers"`
+	LoginTTL  time.Duration `env:"OIDC_LOGIN_TTL" yaml:"login_ttl" default:"600"`
+
+	Provider map[string]OIDCProvider `env:"-"`
+}
+
+type OIDCProvider struct {
+	Issuer       string   `env:"ISSUER" yaml:"issuer"`
+	ClientID     string   `env:"CLIENT_ID" yaml:"client_id"`
+	ClientSecret string   `env:"CLIENT_SECRET" yaml:"client_secret" secret:"true"`
+	RedirectURL  string   `env:"REDIRECT_URL" yaml:"redirect_url"`
+	Scopes       []string `env:"SCOPES" yaml:"scopes"`
+}
+
+type Mail struct {
+	// Driver is log, which writes messages to LogFile or the standard
+	// logger, or smtp.
+	Driver       string `env:"MAIL_DRIVER" yaml:"driver" default:"log"`
+	LogFile      string `env:"MAIL_LOG_FILE" yaml:"log_file"`
+	From         string `env:"MAIL_FROM" yaml:"from" default:"no-reply@localhost"`
+	SMTPHost     string `env:"SMTP_HOST" yaml:"smtp_host" default:"localhost"`
+	SMTPPort     int    `env:"SMTP_PORT" yaml:"smtp_port" default:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME" yaml:"smtp_username"`
+	SMTPPassword string `env:"SMTP_PASSWORD" yaml:"smtp_password" secret:"true"`
+	// AppURL is the frontend that links in emails point to. It is required:
+	// the request's Host is chosen by the client, so links cannot be built
+	// from it.
+	AppURL string `env:"APP_URL" yaml:"app_url"`
+}
+
+type RateLimit struct {
+	// Rules are the per-route policies; an empty string disables rate
+	// limiting.
+	Rules string `env:"RATE_LIMITS" yaml:"rules" default:"login=10/1m by=ip; auth=20/1m by=ip; shorten=60/1m burst=20 by=key; bulk=5/1m by=key; qr=60/1m by=ip"`
+	// Store is memory, or redis which shares the cache's server when the
+	// cache uses Redis.
+	Store string `env:"RATE_LIMIT_STORE" yaml:"store" default:"memory"`
+}
+
+type Shortener struct {
+	// FallbackURL is where disabled, expired and exhausted links redirect to
+	// instead of answering 410 Gone. Unknown codes are not redirected.
+	FallbackURL string `env:"SHORTENER_FALLBACK_URL" yaml:"fallback_url"`
+	// Hosts are the API's own hosts, which links cannot point at and
+	// custom domains cannot claim.
+	Hosts         []string      `env:"SHORTENER_HOSTS" yaml:"hosts"`
+	SweepInterval time.Duration `env:"LINK_SWEEP_INTERVAL" yaml:"sweep_interval" default:"300"`
+}
+
+type URLCheck struct {
+	AllowedSchemes []string `env:"URL_ALLOWED_SCHEMES" yaml:"allowed_schemes" default:"http,https"`
+	BlocklistFile  string   `env:"URL_BLOCKLIST_FILE" yaml:"blocklist_file"`
+	CheckRedirects bool     `env:"URL_CHECK_REDIRECTS" yaml:"check_redirects" default:"false"`
+	MaxRedirects   int      `env:"URL_MAX_REDIRECTS" yaml:"max_redirects" default:"10"`
+}
+
+type Domains struct {
+	// DNSServer answers verification lookups instead of the system
+	// resolver, as host:port.
+	DNSServer    string `env:"DOMAIN_DNS_SERVER" yaml:"dns_server"`
+	VerifyPrefix string `env:"DOMAIN_VERIFY_PREFIX" yaml:"verify_prefix" default:"_go-api-verify"`
+}
+
+type Workspaces struct {
+	InvitationTTL time.Duration `env:"WORKSPACE_INVITATION_TTL" yaml:"invitation_ttl" default:"604800"`
+}
+
+type Analytics struct {
+	// IPSalt is mixed into hashed visitor IPs. It is required, and the
+	// example value is only accepted in debug mode.
+	IPSalt string `env:"ANALYTICS_IP_SALT" yaml:"ip_salt" secret:"true"`
+}
+
+// Setting is one resolved value, as shown by config print.
+type Setting struct {
+	Key string
+	// Value is formatted as it would be written in the environment, and
+	// Redacted for secrets that are set.
+	Value  string
+	Source string
+	Secret bool
+}
+
+// Settings lists every setting in declaration order.
+func (c *Config) Settings() []Setting {
+	return c.settings
+}
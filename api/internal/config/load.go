@@ -0,0 +1,313 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-api/internal/database"
+	"io/fs"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultEnvFile = ".env"
+	DefaultFile    = "config.yaml"
+
+	sourceEnv     = "env"
+	sourceDefault = "default"
+)
+
+// Options name the files Load reads. A file named explicitly must exist;
+// the default ones are skipped when they do not.
+type Options struct {
+	// EnvFile is a .env file, DefaultEnvFile when empty.
+	EnvFile string
+	// File is a YAML file, DefaultFile when empty. Its sections and keys
+	// match the yaml tags, for example database.driver for DB_DRIVER.
+	File string
+	// LookupEnv reads the environment, os.LookupEnv when nil.
+	LookupEnv func(key string) (string, bool)
+}
+
+// EnvOptions reads the files named by ENV_FILE and CONFIG_FILE, or the
+// default ones.
+func EnvOptions() Options {
+	return Options{
+		EnvFile: os.Getenv("ENV_FILE"),
+		File:    os.Getenv("CONFIG_FILE"),
+	}
+}
+
+// Load resolves every setting from, in order of precedence, the
+// environment, the .env file, the YAML file and the defaults, then
+// validates the result. All problems found are returned together, joined
+// in one error. The config is returned even when it is invalid, so it can
+// still be printed.
+func Load(opts Options) (*Config, error) {
+	l := &loader{
+		lookupEnv: opts.LookupEnv,
+		usedYAML:  make(map[string]bool),
+		sources:   make(map[string]string),
+	}
+	if l.lookupEnv == nil {
+		l.lookupEnv = os.LookupEnv
+	}
+	l.readEnvFile(opts.EnvFile)
+	l.readYAML(opts.File)
+
+	cfg := &Config{}
+	walk(reflect.ValueOf(cfg).Elem(), "", "", l.set)
+
+	cfg.OIDC.Provider = make(map[string]OIDCProvider, len(cfg.OIDC.Providers))
+	for _, name := range cfg.OIDC.Providers {
+		var provider OIDCProvider
+		walk(reflect.ValueOf(&provider).Elem(), oidcPrefix(name), "oidc."+name+".", l.set)
+		cfg.OIDC.Provider[name] = provider
+	}
+
+	if l.sources["DB_CONNECTION_STRING"] == sourceDefault {
+		cfg.Database.DSN = database.DefaultDSN(cfg.Database.Driver)
+	}
+
+	l.checkUnusedYAML()
+	cfg.settings = settings(cfg, l.sources)
+
+	errs := l.errs
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// oidcPrefix is the prefix of the variables configuring a provider, which
+// for "azure-ad" is OIDC_AZURE_AD_.
+func oidcPrefix(name string) string {
+	return "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+type loader struct {
+	lookupEnv func(string) (string, bool)
+
+	envFile     map[string]string
+	envFileName string
+
+	yaml     map[string]string
+	yamlName string
+	usedYAML map[string]bool
+
+	// sources records where each setting came from, by variable name.
+	sources map[string]string
+	errs    []error
+}
+
+func (l *loader) readEnvFile(path string) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultEnvFile
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		l.errs = append(l.errs, fmt.Errorf("reading %s: %w", path, err))
+		return
+	}
+	l.envFile, l.envFileName = values, path
+}
+
+func (l *loader) readYAML(path string) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		l.errs = append(l.errs, fmt.Errorf("reading %s: %w", path, err))
+		return
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("parsing %s: %w", path, err))
+		return
+	}
+
+	l.yaml, l.yamlName = make(map[string]string), path
+	for key, value := range doc {
+		l.flatten(key, value)
+	}
+}
+
+// flatten stores the scalars of the YAML document by their dotted path.
+// Lists become comma separated, as they are written in the environment.
+func (l *loader) flatten(path string, value any) {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			l.flatten(path+"."+key, child)
+		}
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			switch item.(type) {
+			case map[string]any, []any:
+				l.errs = append(l.errs, fmt.Errorf("%s in %s: lists may only hold plain values", path, l.yamlName))
+				return
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		l.yaml[path] = strings.Join(items, ",")
+	case nil:
+		l.yaml[path] = ""
+	default:
+		l.yaml[path] = fmt.Sprint(value)
+	}
+}
+
+// checkUnusedYAML reports keys of the YAML file that match no setting, which
+// are most likely typos.
+func (l *loader) checkUnusedYAML() {
+	var unknown []string
+	for path := range l.yaml {
+		if !l.usedYAML[path] {
+			unknown = append(unknown, path)
+		}
+	}
+	slices.Sort(unknown)
+
+	for _, path := range unknown {
+		l.errs = append(l.errs, fmt.Errorf("%s in %s: unknown setting", path, l.yamlName))
+	}
+}
+
+func (l *loader) lookup(key, path string) (string, string, bool) {
+	yamlValue, inYAML := l.yaml[path]
+	if inYAML {
+		l.usedYAML[path] = true
+	}
+
+	if value, ok := l.lookupEnv(key); ok {
+		return value, sourceEnv, true
+	}
+	if value, ok := l.envFile[key]; ok {
+		return value, l.envFileName, true
+	}
+	if inYAML {
+		return yamlValue, l.yamlName, true
+	}
+	return "", "", false
+}
+
+func (l *loader) set(f field) {
+	raw, source, ok := l.lookup(f.key, f.path)
+	if !ok {
+		raw, source = f.tag.Get("default"), sourceDefault
+	}
+	l.sources[f.key] = source
+
+	if err := parse(f.value, raw, f.tag.Get("unit")); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: %v (from %s)", f.key, err, source))
+	}
+}
+
+// field is a setting found by walk.
+type field struct {
+	// key is the environment variable and path the YAML key.
+	key   string
+	path  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+// walk calls fn for every setting of the struct v, descending into
+// sections.
+func walk(v reflect.Value, envPrefix, yamlPrefix string, fn func(field)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("env") == "-" {
+			continue
+		}
+
+		if sf.Type.Kind() == reflect.Struct {
+			walk(v.Field(i), envPrefix, yamlPrefix+sf.Tag.Get("yaml")+".", fn)
+			continue
+		}
+		fn(field{
+			key:   envPrefix + sf.Tag.Get("env"),
+			path:  yamlPrefix + sf.Tag.Get("yaml"),
+			value: v.Field(i),
+			tag:   sf.Tag,
+		})
+	}
+}
+
+func parse(v reflect.Value, raw, unit string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		v.SetInt(int64(n))
+	case time.Duration:
+		d, err := parseDuration(raw, unit)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case []string:
+		v.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %s", v.Type()))
+	}
+	return nil
+}
+
+// parseDuration reads plain numbers in unit, seconds unless it is ms, and
+// anything else as a Go duration like 15m.
+func parseDuration(raw, unit string) (time.Duration, error) {
+	scale, unitName := time.Second, "seconds"
+	if unit == "ms" {
+		scale, unitName = time.Millisecond, "milliseconds"
+	}
+
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Duration(n) * scale, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration, give %s or a value like 15m", raw, unitName)
+	}
+	return d, nil
+}
+
+// splitList splits on commas and white space, so both "http,https" and
+// "openid email" are lists.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"go-api/internal/logging"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Print writes the effective settings and where each came from. Secrets
+// are redacted.
+func Print(w io.Writer, c *Config) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range c.Settings() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	return tw.Flush()
+}
+
+func settings(c *Config, sources map[string]string) []Setting {
+	var out []Setting
+	add := func(f field) {
+		s := Setting{
+			Key:    f.key,
+			Value:  format(f.value),
+			Source: sources[f.key],
+			Secret: f.tag.Get("secret") == "true",
+		}
+		if s.Secret && s.Value != "" {
+			s.Value = logging.Redacted
+		}
+		out = append(out, s)
+	}
+
+	walk(reflect.ValueOf(c).Elem(), "", "", add)
+	for _, name := range c.OIDC.Providers {
+		provider := c.OIDC.Provider[name]
+		walk(reflect.ValueOf(&provider).Elem(), oidcPrefix(name), "", add)
+	}
+	return out
+}
+
+func format(v reflect.Value) string {
+	switch v := v.Interface().(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
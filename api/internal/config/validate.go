@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-api/internal/apiversion"
+	"go-api/internal/database"
+	"go-api/internal/ratelimit"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MinJWTSecretLength is the shortest JWT secret accepted, the key size of
+// HMAC-SHA256.
+const MinJWTSecretLength = 32
+
+// exampleIPSalt is the salt the example .env ships with, only good enough
+// for development.
+const exampleIPSalt = "change-me"
+
+// Validate checks the settings against each other and their allowed
+// values, and returns every problem found joined in one error.
+func (c *Config) Validate() error {
+	var p problems
+
+	p.check("PORT", c.Server.Port != "", "must not be empty")
+	p.in("GIN_MODE", c.Server.GinMode, "debug", "release", "test")
+	p.positive("SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+	p.notNegative("SHUTDOWN_DELAY", c.Server.ShutdownDelay)
+	p.positive("READINESS_TIMEOUT", c.Server.ReadinessTimeout)
+
+	p.check("API_VERSIONS", len(c.API.Versions) > 0, "must name at least one version")
+	for i, name := range c.API.Versions {
+		if slices.Contains(c.API.Versions[:i], name) {
+			p.add("API_VERSIONS", "lists %s twice", name)
+			continue
+		}
+		p.in("API_VERSIONS", name, apiversion.Known...)
+	}
+	p.in("API_DEFAULT_VERSION", c.API.DefaultVersion, c.API.Versions...)
+	if _, err := apiversion.ParseDeprecations(c.API.Deprecations); err != nil {
+		p.add("API_DEPRECATIONS", "%v", err)
+	}
+
+	p.in("LOG_LEVEL", strings.ToLower(c.Log.Level), "debug", "info", "warn", "warning", "error")
+	p.in("LOG_FORMAT", c.Log.Format, "json", "text")
+
+	p.in("DB_DRIVER", c.Database.Driver, database.Postgres, database.MySQL, database.SQLite)
+	p.check("DB_CONNECTION_STRING", c.Database.DSN != "", "must not be empty")
+	p.notNegative("DB_SLOW_QUERY_MS", c.Database.SlowQuery)
+	p.positive("MIGRATION_LOCK_TIMEOUT_SECONDS", c.Database.MigrationLockTimeout)
+
+	p.in("CACHE_DRIVER", c.Cache.Driver, "memory", "redis", "none")
+	if c.Cache.Driver == "memory" {
+		p.check("CACHE_SIZE", c.Cache.Size > 0, "must be positive for the memory cache")
+	}
+	p.notNegative("CACHE_TTL", c.Cache.TTL)
+	p.notNegative("CACHE_NEGATIVE_TTL", c.Cache.NegativeTTL)
+
+	if c.Cache.Driver == "redis" || c.RateLimit.Store == "redis" {
+		p.check("REDIS_ADDR", c.Redis.Addr != "", "must not be empty when Redis is used")
+		p.check("REDIS_DB", c.Redis.DB >= 0, "must not be negative")
+	}
+
+	switch {
+	case c.Auth.JWTSecret == "":
+		p.add("JWT_SECRET_KEY", "must be set")
+	case len(c.Auth.JWTSecret) < MinJWTSecretLength:
+		p.add("JWT_SECRET_KEY", "must be at least %d bytes, got %d", MinJWTSecretLength, len(c.Auth.JWTSecret))
+	}
+	p.positive("ACCESS_TOKEN_TTL", c.Auth.AccessTokenTTL)
+	p.positive("REFRESH_TOKEN_TTL", c.Auth.RefreshTokenTTL)
+	p.check("LOGIN_LOCKOUT_THRESHOLD", c.Auth.LockoutThreshold >= 0, "must not be negative, 0 disables the lockout")
+	if c.Auth.LockoutThreshold > 0 {
+		p.positive("LOGIN_LOCKOUT_BASE", c.Auth.LockoutBase)
+		p.check("LOGIN_LOCKOUT_MAX", c.Auth.LockoutMax >= c.Auth.LockoutBase, "must not be less than LOGIN_LOCKOUT_BASE")
+	}
+	p.positive("EMAIL_VERIFICATION_TTL", c.Auth.EmailVerificationTTL)
+	p.positive("PASSWORD_RESET_TTL", c.Auth.PasswordResetTTL)
+	p.positive("LOGIN_CHALLENGE_TTL", c.Auth.LoginChallengeTTL)
+	p.check("TOTP_ISSUER", c.Auth.TOTPIssuer != "", "must not be empty")
+
+	p.in("MAIL_DRIVER", c.Mail.Driver, "log", "smtp")
+	p.check("MAIL_FROM", c.Mail.From != "", "must not be empty")
+	if c.Mail.Driver == "smtp" {
+		p.check("SMTP_HOST", c.Mail.SMTPHost != "", "must not be empty when MAIL_DRIVER is smtp")
+		p.check("SMTP_PORT", c.Mail.SMTPPort > 0 && c.Mail.SMTPPort <= 65535, "must be a port number")
+	}
+	p.check("APP_URL", c.Mail.AppURL != "", "must be set")
+	p.url("APP_URL", c.Mail.AppURL)
+
+	if _, err := ratelimit.ParsePolicies(c.RateLimit.Rules); err != nil {
+		p.add("RATE_LIMITS", "%v", err)
+	}
+	p.in("RATE_LIMIT_STORE", c.RateLimit.Store, "memory", "redis")
+
+	// Visitor IPs hashed with a known salt can be recovered by hashing every
+	// address.
+	switch {
+	case c.Analytics.IPSalt == "":
+		p.add("ANALYTICS_IP_SALT", "must be set")
+	case c.Analytics.IPSalt == exampleIPSalt && c.Server.GinMode != "debug":
+		p.add("ANALYTICS_IP_SALT", "must be changed from the example value outside debug mode")
+	}
+
+	p.url("SHORTENER_FALLBACK_URL", c.Shortener.FallbackURL)
+	p.positive("LINK_SWEEP_INTERVAL", c.Shortener.SweepInterval)
+
+	p.check("URL_ALLOWED_SCHEMES", len(c.URLCheck.AllowedSchemes) > 0, "must name at least one scheme")
+	p.check("URL_MAX_REDIRECTS", c.URLCheck.MaxRedirects >= 0, "must not be negative")
+
+	p.check("DOMAIN_VERIFY_PREFIX", c.Domains.VerifyPrefix != "", "must not be empty")
+	p.positive("WORKSPACE_INVITATION_TTL", c.Workspaces.InvitationTTL)
+
+	p.positive("OIDC_LOGIN_TTL", c.OIDC.LoginTTL)
+	for i, name := range c.OIDC.Providers {
+		if slices.Contains(c.OIDC.Providers[:i], name) {
+			p.add("OIDC_PROVIDERS", "lists %s twice", name)
+			continue
+		}
+
+		prefix := oidcPrefix(name)
+		provider := c.OIDC.Provider[name]
+		if provider.Issuer == "" {
+			p.add(prefix+"ISSUER", "must be set for provider %s", name)
+		} else {
+			p.url(prefix+"ISSUER", provider.Issuer)
+		}
+		p.check(prefix+"CLIENT_ID", provider.ClientID != "", "must be set for provider "+name)
+		p.url(prefix+"REDIRECT_URL", provider.RedirectURL)
+	}
+
+	return errors.Join(p...)
+}
+
+type problems []error
+
+func (p *problems) add(key, format string, args ...any) {
+	*p = append(*p, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+// check adds msg for key unless ok holds.
+func (p *problems) check(key string, ok bool, msg string) {
+	if !ok {
+		p.add(key, "%s", msg)
+	}
+}
+
+func (p *problems) in(key, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		p.add(key, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func (p *problems) positive(key string, d time.Duration) {
+	p.check(key, d > 0, "must be positive")
+}
+
+func (p *problems) notNegative(key string, d time.Duration) {
+	p.check(key, d >= 0, "must not be negative")
+}
+
+// url checks that value, when set, is an absolute http or https URL.
+func (p *problems) url(key, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		p.add(key, "%q is not an absolute http or https URL", value)
+	}
+}
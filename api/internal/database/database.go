@@ -0,0 +1,114 @@
+// Package database opens the gorm connection for the configured driver.
+// PostgreSQL is what production runs; MySQL is supported as well, and SQLite
+// runs the API without a database server, either from a file or entirely in
+// memory for tests.
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	Postgres = "postgres"
+	MySQL    = "mysql"
+	SQLite   = "sqlite"
+
+	// Memory is the SQLite DSN for a private in-memory database.
+	Memory = ":memory:"
+)
+
+// DefaultDSN returns the connection string used when none is configured.
+func DefaultDSN(driver string) string {
+	switch driver {
+	case MySQL:
+		return "root:secret@tcp(localhost:3306)/mydb?charset=utf8mb4&parseTime=True&loc=UTC"
+	case SQLite:
+		return "go-api.db"
+	default:
+		return "host=localhost user=postgres password=secret dbname=mydb port=5432 sslmode=disable"
+	}
+}
+
+// IsMemory reports whether driver and dsn select an in-memory database,
+// which starts out empty every time it is opened.
+func IsMemory(driver, dsn string) bool {
+	return driver == SQLite && dsn == Memory
+}
+
+// Open connects to the database. For SQLite the DSN is a file path or
+// Memory.
+func Open(driver, dsn string, config *gorm.Config) (*gorm.DB, error) {
+	if config == nil {
+		config = &gorm.Config{}
+	}
+	// Unique violations come back as gorm.ErrDuplicatedKey whatever the
+	// driver.
+	config.TranslateError = true
+
+	switch driver {
+	case Postgres:
+		return gorm.Open(postgres.Open(dsn), config)
+	case MySQL:
+		// Short link owners are stored as signed ints, which MySQL refuses
+		// to reference the unsigned user IDs from.
+		config.DisableForeignKeyConstraintWhenMigrating = true
+		return gorm.Open(mysql.Open(dsn), config)
+	case SQLite:
+		return openSQLite(dsn, config)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+func openSQLite(dsn string, config *gorm.Config) (*gorm.DB, error) {
+	memory := dsn == Memory
+	if memory {
+		// Every connection to :memory: gets its own database, so connections
+		// of the pool share a named one instead. The random name keeps
+		// databases opened in the same process apart.
+		name := make([]byte, 8)
+		if _, err := rand.Read(name); err != nil {
+			return nil, err
+		}
+		dsn = "file:" + hex.EncodeToString(name) + "?mode=memory&cache=shared"
+	} else {
+		dsn = "file:" + dsn + "?_pragma=journal_mode(WAL)"
+	}
+	dsn += "&_pragma=busy_timeout(5000)"
+
+	db, err := gorm.Open(sqlite.Open(dsn), config)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := newUTCPool(db)
+	if err != nil {
+		return nil, err
+	}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+
+	if memory {
+		// Connections sharing the cache lock each other out of tables
+		// ("database table is locked") rather than waiting, so the pool
+		// keeps to a single connection. It never expires, since the
+		// database is dropped with its last connection.
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+
+	return db, nil
+}
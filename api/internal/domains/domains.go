@@ -0,0 +1,171 @@
+// Package domains checks that users control the custom domains they register
+// for their short links.
+package domains
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+const (
+	// DefaultRecordPrefix is prepended to a hostname to name the TXT record
+	// that proves ownership.
+	DefaultRecordPrefix = "_go-api-verify"
+	recordValuePrefix   = "go-api-verify="
+	maxHostnameLength   = 253
+)
+
+var (
+	ErrInvalidHostname  = errors.New("invalid hostname")
+	ErrReservedHostname = errors.New("hostname is reserved")
+)
+
+// Resolver looks up TXT records. *net.Resolver satisfies it; tests can
+// substitute a StaticResolver.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// StaticResolver answers TXT lookups from a map of record names to values.
+type StaticResolver map[string][]string
+
+func (r StaticResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := r[strings.TrimSuffix(name, ".")]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+type Options struct {
+	Resolver Resolver
+	// RecordPrefix overrides DefaultRecordPrefix.
+	RecordPrefix string
+	// ReservedHosts cannot be registered, typically the API's own hosts.
+	ReservedHosts []string
+}
+
+// Verifier validates hostnames and checks their verification records.
+type Verifier struct {
+	resolver Resolver
+	prefix   string
+	reserved map[string]bool
+}
+
+func NewVerifier(opts Options) *Verifier {
+	if opts.Resolver == nil {
+		opts.Resolver = net.DefaultResolver
+	}
+	if opts.RecordPrefix == "" {
+		opts.RecordPrefix = DefaultRecordPrefix
+	}
+
+	reserved := make(map[string]bool, len(opts.ReservedHosts))
+	for _, host := range opts.ReservedHosts {
+		if normalized, err := NormalizeHostname(host); err == nil {
+			reserved[normalized] = true
+		}
+	}
+
+	return &Verifier{
+		resolver: opts.Resolver,
+		prefix:   opts.RecordPrefix,
+		reserved: reserved,
+	}
+}
+
+// Validate normalizes a hostname a user wants to register.
+func (v *Verifier) Validate(hostname string) (string, error) {
+	normalized, err := NormalizeHostname(hostname)
+	if err != nil {
+		return "", err
+	}
+	if v.reserved[normalized] {
+		return "", ErrReservedHostname
+	}
+	return normalized, nil
+}
+
+// RecordName is the name of the TXT record that proves control of hostname.
+func (v *Verifier) RecordName(hostname string) string {
+	return v.prefix + "." + hostname
+}
+
+// RecordValue is the content the TXT record must have.
+func (v *Verifier) RecordValue(token string) string {
+	return recordValuePrefix + token
+}
+
+// Verify reports whether the verification record of hostname contains token.
+// A missing record is not an error.
+func (v *Verifier) Verify(ctx context.Context, hostname, token string) (bool, error) {
+	records, err := v.resolver.LookupTXT(ctx, v.RecordName(hostname))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	want := v.RecordValue(token)
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NormalizeHostname lowercases a hostname, converts it to its ASCII form and
+// checks that it is a plain DNS name with at least two labels, without port
+// or IP address.
+func NormalizeHostname(hostname string) (string, error) {
+	hostname = strings.TrimSuffix(strings.TrimSpace(hostname), ".")
+	if hostname == "" || net.ParseIP(hostname) != nil || strings.ContainsAny(hostname, ":/@") {
+		return "", ErrInvalidHostname
+	}
+
+	ascii, err := idna.Lookup.ToASCII(hostname)
+	if err != nil || len(ascii) > maxHostnameLength {
+		return "", ErrInvalidHostname
+	}
+	ascii = strings.ToLower(ascii)
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", ErrInvalidHostname
+	}
+	for _, label := range labels {
+		if !validLabel(label) {
+			return "", ErrInvalidHostname
+		}
+	}
+
+	return ascii, nil
+}
+
+func validLabel(label string) bool {
+	if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for _, r := range label {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// HostOnly strips the port from a Host header value and normalizes it for
+// lookups. Values that are not valid hostnames are returned lowercased.
+func HostOnly(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,116 @@
+package domains_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"go-api/internal/domains"
+)
+
+type failingResolver struct{}
+
+func (failingResolver) LookupTXT(context.Context, string) ([]string, error) {
+	return nil, &net.DNSError{Err: "server misbehaving", Name: "x", IsTemporary: true}
+}
+
+func TestVerify(t *testing.T) {
+	v := domains.NewVerifier(domains.Options{Resolver: domains.StaticResolver{
+		"_go-api-verify.links.example.com":  {"unrelated", " go-api-verify=token123 "},
+		"_go-api-verify.other.example.com":  {"go-api-verify=another"},
+		"_go-api-verify.empty.example.com":  {},
+		"_go-api-verify.prefix.example.com": {"go-api-verify=token1234"},
+	}})
+	ctx := context.Background()
+
+	tests := []struct {
+		hostname string
+		want     bool
+	}{
+		{"links.example.com", true},
+		{"other.example.com", false},
+		{"empty.example.com", false},
+		{"prefix.example.com", false},
+		// A missing record is not verified, but not an error either.
+		{"missing.example.com", false},
+	}
+	for _, tt := range tests {
+		got, err := v.Verify(ctx, tt.hostname, "token123")
+		if err != nil || got != tt.want {
+			t.Errorf("Verify(%s) = %v, %v, want %v", tt.hostname, got, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyRecordPrefix(t *testing.T) {
+	v := domains.NewVerifier(domains.Options{
+		RecordPrefix: "_custom",
+		Resolver:     domains.StaticResolver{"_custom.example.com": {"go-api-verify=t"}},
+	})
+
+	if name := v.RecordName("example.com"); name != "_custom.example.com" {
+		t.Errorf("RecordName = %q", name)
+	}
+	if value := v.RecordValue("t"); value != "go-api-verify=t" {
+		t.Errorf("RecordValue = %q", value)
+	}
+	if ok, err := v.Verify(context.Background(), "example.com", "t"); !ok || err != nil {
+		t.Errorf("Verify = %v, %v", ok, err)
+	}
+}
+
+func TestVerifyLookupError(t *testing.T) {
+	v := domains.NewVerifier(domains.Options{Resolver: failingResolver{}})
+
+	// Resolver failures are reported, so the domain is checked again later
+	// rather than marked unverified.
+	if ok, err := v.Verify(context.Background(), "example.com", "t"); ok || err == nil {
+		t.Errorf("Verify = %v, %v, want an error", ok, err)
+	}
+}
+
+func TestValidateReservedHosts(t *testing.T) {
+	v := domains.NewVerifier(domains.Options{ReservedHosts: []string{"Short.Example.com.", "not a host"}})
+
+	for _, hostname := range []string{"short.example.com", "SHORT.example.com", "short.example.com."} {
+		if _, err := v.Validate(hostname); !errors.Is(err, domains.ErrReservedHostname) {
+			t.Errorf("Validate(%q) = %v, want ErrReservedHostname", hostname, err)
+		}
+	}
+	if got, err := v.Validate("links.example.com"); err != nil || got != "links.example.com" {
+		t.Errorf("Validate(links.example.com) = %q, %v", got, err)
+	}
+}
+
+func TestNormalizeHostname(t *testing.T) {
+	valid := map[string]string{
+		"Example.COM":          "example.com",
+		" links.example.com. ": "links.example.com",
+		"bücher.example":       "xn--bcher-kva.example",
+		"a-b.c1.example":       "a-b.c1.example",
+	}
+	for in, want := range valid {
+		if got, err := domains.NormalizeHostname(in); err != nil || got != want {
+			t.Errorf("NormalizeHostname(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "localhost", "127.0.0.1", "::1", "example.com:8080", "user@example.com", "example.com/path", "-a.example.com", "a_b.example.com", "a..example.com"} {
+		if _, err := domains.NormalizeHostname(in); !errors.Is(err, domains.ErrInvalidHostname) {
+			t.Errorf("NormalizeHostname(%q) = %v, want ErrInvalidHostname", in, err)
+		}
+	}
+}
+
+func TestHostOnly(t *testing.T) {
+	for in, want := range map[string]string{
+		"Example.com:8080": "example.com",
+		"example.com.":     "example.com",
+		"[::1]:80":         "::1",
+	} {
+		if got := domains.HostOnly(in); got != want {
+			t.Errorf("HostOnly(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
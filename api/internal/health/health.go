@@ -0,0 +1,130 @@
+// Package health runs the readiness checks of the server's dependencies.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Check reports whether a dependency is usable. It should return once ctx
+// is done.
+type Check func(ctx context.Context) error
+
+// Component is the result of one check.
+type Component struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latencyMs"`
+}
+
+// Report is the combined readiness of all components.
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker holds the registered checks. It also reports the server as not
+// ready once draining started, so load balancers stop sending traffic
+// before connections are closed.
+type Checker struct {
+	timeout  time.Duration
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewChecker returns a checker that gives each check timeout to answer.
+func NewChecker(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &Checker{timeout: timeout}
+}
+
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Drain marks the server as shutting down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Run executes all checks concurrently.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type result struct {
+		index     int
+		component Component
+	}
+	results := make(chan result, len(checks))
+	start := time.Now()
+
+	for i, nc := range checks {
+		go func() {
+			component := Component{Status: StatusOK}
+			if err := nc.check(ctx); err != nil {
+				component.Status = StatusUnavailable
+				component.Error = err.Error()
+			}
+			component.LatencyMS = time.Since(start).Milliseconds()
+			results <- result{index: i, component: component}
+		}()
+	}
+
+	components := make([]*Component, len(checks))
+collect:
+	for range checks {
+		select {
+		case r := <-results:
+			components[r.index] = &r.component
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(checks))}
+	for i, nc := range checks {
+		// Checks that ignore the context are reported without waiting for
+		// them.
+		component := components[i]
+		if component == nil {
+			component = &Component{Status: StatusUnavailable, Error: "check timed out", LatencyMS: c.timeout.Milliseconds()}
+		}
+
+		report.Components[nc.name] = *component
+		if component.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
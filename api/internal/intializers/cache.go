@@ -0,0 +1,53 @@
+package initializers
+
+import (
+	"go-api/internal/cache"
+	"go-api/internal/config"
+	"log"
+	"sync"
+)
+
+var (
+	cacheInstance cache.Cache
+	cacheOnce     sync.Once
+)
+
+// InitializeCache sets up the shared cache backend selected by the cache
+// driver (memory, redis or none).
+func InitializeCache(cfg *config.Config) {
+	cacheOnce.Do(func() {
+		driver := cfg.Cache.Driver
+
+		switch driver {
+		case "memory":
+			cacheInstance = cache.NewLRU(cfg.Cache.Size)
+		case "redis":
+			cacheInstance = cache.NewRedis(RedisOptions(cfg))
+		case "none":
+			cacheInstance = cache.Noop{}
+		default:
+			log.Fatalf("Unknown CACHE_DRIVER %q", driver)
+		}
+
+		log.Printf("Cache initialized with %s driver", driver)
+	})
+}
+
+// RedisOptions connects to the Redis server shared by the cache and the rate
+// limiter.
+func RedisOptions(cfg *config.Config) cache.RedisOptions {
+	return cache.RedisOptions{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		Prefix:   cfg.Cache.Prefix,
+	}
+}
+
+// GetCache returns the shared cache instance
+func GetCache() cache.Cache {
+	if cacheInstance == nil {
+		log.Fatal("Cache not initialized. Call InitializeCache() first.")
+	}
+	return cacheInstance
+}
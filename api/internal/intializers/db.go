@@ -1,11 +1,16 @@
 package initializers
 
 import (
-	"go-api/internal/env"
+	"context"
+	"go-api/database/migrations"
+	"go-api/internal/config"
+	"go-api/internal/database"
+	"go-api/internal/logging"
+	"go-api/internal/migrator"
 	"log"
+	"log/slog"
 	"sync"
 
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -14,26 +19,54 @@ var (
 	once       sync.Once
 )
 
-// InitializeDB initializes the singleton database connection
-func InitializeDB() {
+// InitializeDB initializes the singleton database connection for the
+// configured driver (postgres, mysql or sqlite). An in-memory SQLite database
+// is always migrated right away since it starts out empty; other databases
+// only when auto migration is enabled.
+func InitializeDB(cfg config.Database) {
 	once.Do(func() {
-		dsn := env.GetString("DB_CONNECTION_STRING", "host=localhost user=postgres password=secret dbname=mydb port=5432 sslmode=disable")
-		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		driver, dsn := cfg.Driver, cfg.DSN
+
+		db, err := database.Open(driver, dsn, &gorm.Config{
+			Logger: logging.NewGormLogger(slog.Default(), cfg.SlowQuery),
+		})
 		if err != nil {
 			log.Fatal("Failed to connect to database:", err)
 		}
 
-		// Configure connection pool
-		sqlDB, _ := db.DB()
-		sqlDB.SetMaxOpenConns(25)
-		sqlDB.SetMaxIdleConns(10)
-		sqlDB.SetConnMaxLifetime(0)
+		// Configure connection pool; an in-memory database keeps the single
+		// connection database.Open gave it.
+		if !database.IsMemory(driver, dsn) {
+			sqlDB, _ := db.DB()
+			sqlDB.SetMaxOpenConns(25)
+			sqlDB.SetMaxIdleConns(10)
+			sqlDB.SetConnMaxLifetime(0)
+		}
+
+		if database.IsMemory(driver, dsn) || cfg.AutoMigrate {
+			if err := migrate(db); err != nil {
+				log.Fatal("Failed to migrate database:", err)
+			}
+		}
 
 		dbInstance = db
-		log.Println("Database connected successfully")
+		log.Printf("Database connected successfully (%s)", driver)
 	})
 }
 
+func migrate(db *gorm.DB) error {
+	all, err := migrations.All(db.Dialector.Name())
+	if err != nil {
+		return err
+	}
+	m, err := migrator.New(db, all, migrator.Options{})
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(context.Background(), 0)
+	return err
+}
+
 // GetDB returns the singleton database instance
 func GetDB() *gorm.DB {
 	if dbInstance == nil {
@@ -1,18 +1,34 @@
 package initializers
 
 import (
+	"go-api/internal/config"
 	"log"
-	"os"
+	"sync"
+)
 
-	"github.com/joho/godotenv"
+var (
+	configInstance *config.Config
+	configOnce     sync.Once
 )
 
-func EnvironmentVariables() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-		panic(err)
-	}
+// LoadConfig loads the configuration from the environment, .env and
+// config.yaml, or the files named by ENV_FILE and CONFIG_FILE. An invalid
+// configuration stops the process with every problem listed.
+func LoadConfig() *config.Config {
+	configOnce.Do(func() {
+		cfg, err := config.Load(config.EnvOptions())
+		if err != nil {
+			log.Fatalf("Invalid configuration:\n%v", err)
+		}
+		configInstance = cfg
+	})
+	return configInstance
+}
 
-	print(os.Getenv("JWT_SECRET_KEY"))
+// GetConfig returns the loaded configuration
+func GetConfig() *config.Config {
+	if configInstance == nil {
+		log.Fatal("Config not loaded. Call LoadConfig() first.")
+	}
+	return configInstance
 }
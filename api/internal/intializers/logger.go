@@ -0,0 +1,16 @@
+package initializers
+
+import (
+	"go-api/internal/config"
+	"go-api/internal/logging"
+	"os"
+)
+
+// InitializeLogger makes structured logging the default, as JSON unless the
+// format is text.
+func InitializeLogger(cfg config.Log) {
+	logging.SetDefault(logging.New(os.Stderr, logging.Options{
+		Level:  cfg.Level,
+		Format: cfg.Format,
+	}))
+}
@@ -0,0 +1,101 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-api/internal/logging"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.Options{Format: "json"}).With("dsn", "postgres://u:p@db/app")
+
+	logger.Info("login",
+		"email", "a@example.com",
+		"password", "hunter2",
+		"refreshToken", "r-123",
+		"DB_PASSWORD", "p",
+		"Authorization", "Bearer abc",
+		"api_key", "k",
+		slog.Group("request", "cookie", "session=1", "path", "/login"),
+		slog.Any("recovery_codes", []string{"a", "b"}),
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decode %s: %v", buf.String(), err)
+	}
+	request, _ := record["request"].(map[string]any)
+
+	for name, got := range map[string]any{
+		"dsn":            record["dsn"],
+		"password":       record["password"],
+		"refreshToken":   record["refreshToken"],
+		"DB_PASSWORD":    record["DB_PASSWORD"],
+		"Authorization":  record["Authorization"],
+		"api_key":        record["api_key"],
+		"request.cookie": request["cookie"],
+		"recovery_codes": record["recovery_codes"],
+	} {
+		if got != logging.Redacted {
+			t.Errorf("%s = %v, want %s", name, got, logging.Redacted)
+		}
+	}
+	if record["email"] != "a@example.com" || request["path"] != "/login" {
+		t.Errorf("other attributes were changed: %s", buf.String())
+	}
+	for _, secret := range []string{"hunter2", "r-123", "Bearer abc", "session=1", "u:p@db"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("output contains %q: %s", secret, buf.String())
+		}
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	var buf bytes.Buffer
+	logging.New(&buf, logging.Options{Format: "text"}).Info("reset", "token", "t-1")
+
+	if out := buf.String(); strings.Contains(out, "t-1") || !strings.Contains(out, "token="+logging.Redacted) {
+		t.Errorf("output = %s", out)
+	}
+}
+
+func TestIsSensitive(t *testing.T) {
+	for key, want := range map[string]bool{
+		"password":          true,
+		"newPassword":       true,
+		"client_secret":     true,
+		"X-API-Key":         true,
+		"apiKey":            true,
+		"set-cookie":        true,
+		"connection_string": true,
+		"email":             false,
+		"user_id":           false,
+		"":                  false,
+	} {
+		if got := logging.IsSensitive(key); got != want {
+			t.Errorf("IsSensitive(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.Options{Format: "json", Level: "warn"})
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "below the level")
+	logger.WarnContext(ctx, "slow")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("decode %s: %v", buf.String(), err)
+	}
+	if record["msg"] != "slow" || record["request_id"] != "req-1" {
+		t.Errorf("record = %v", record)
+	}
+}
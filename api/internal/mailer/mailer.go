@@ -0,0 +1,16 @@
+// Package mailer sends transactional email such as verification and
+// password reset links.
+package mailer
+
+import "context"
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers a single message.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,98 @@
+package metrics_test
+
+import (
+	"bytes"
+	"flag"
+	"go-api/internal/metrics"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden exposition")
+
+const golden = "testdata/metrics.txt"
+
+func newRegistry() *metrics.Registry {
+	r := metrics.NewRegistry()
+
+	requests := r.Counter("http_requests_total", "Requests served,\nby route and status. Paths are C:\\like.", "route", "status")
+	requests.Inc("/short/:code", "302")
+	requests.Add(2, "/short/:code", "302")
+	requests.Inc("/api/v1/short", "201")
+	requests.Inc(`say "hi"`+"\n"+`C:\path`, "500")
+
+	latency := r.Histogram("http_request_duration_seconds", "Request latency.", []float64{0.1, 0.5, 1}, "route")
+	for _, v := range []float64{0.05, 0.1, 0.3, 0.7, 5} {
+		latency.Observe(v, "/short/:code")
+	}
+	latency.Observe(0.2, "/api/v1/short")
+
+	unlabelled := r.Histogram("job_seconds", "Job durations.", []float64{1})
+	unlabelled.Observe(2)
+
+	r.Counter("empty_total", "Not incremented yet.", "kind")
+	r.GaugeFunc("queue_length", "Jobs waiting.", func() float64 { return 3 })
+	r.GaugeFunc("temperature", "Not measured.", math.NaN)
+	r.CounterFunc("cache_hits_total", "Cache hits.", func() float64 { return 1e21 })
+	return r
+}
+
+func TestExposition(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := newRegistry().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("exposition differs from %s; run go test ./internal/metrics -update and review the diff\n%s", golden, buf.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := newRegistry()
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+	if !bytes.Equal(w.Body.Bytes(), buf.Bytes()) {
+		t.Error("served metrics differ from WriteTo")
+	}
+}
+
+func TestRegistryMisuse(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := r.Counter("a_total", "A.", "kind")
+
+	for name, fn := range map[string]func(){
+		"registered twice":   func() { r.GaugeFunc("a_total", "A.", func() float64 { return 0 }) },
+		"missing label":      func() { c.Inc() },
+		"extra label values": func() { c.Inc("x", "y") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -1,50 +1,131 @@
 package middleware
 
 import (
+	"go-api/database/model"
+	"go-api/internal/apperr"
 	"go-api/internal/auth"
 	"go-api/internal/utils"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+// apiKeyTouchInterval limits how often LastUsedAt is written for a key.
+const apiKeyTouchInterval = time.Minute
+
+// AuthMiddleware accepts an access token from the token cookie or an
+// Authorization: Bearer header. Bearer credentials may also be personal API
+// keys, which carry their own scopes.
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		token, err := c.Cookie("token")
-		if err != nil {
-			c.AbortWithStatusJSON(401, gin.H{
-				"message": "Unauthorized",
-			})
-			c.Abort()
-			return
+		token, fromHeader := bearerToken(c)
+		if !fromHeader {
+			token, _ = c.Cookie("token")
 		}
 
 		if token == "" {
-			c.AbortWithStatusJSON(401, gin.H{
-				"message": "Unauthorized",
-			})
-			c.Abort()
+			unauthorized(c)
+			return
+		}
+
+		if fromHeader && strings.HasPrefix(token, auth.APIKeyPrefix) {
+			authenticateAPIKey(c, db, token)
 			return
 		}
 
 		tokenClaims, err := utils.ValidateJWT(token)
 		if err != nil {
-			c.AbortWithStatusJSON(401, gin.H{
-				"message": "Unauthorized",
-			})
-			c.Abort()
+			unauthorized(c)
 			return
 		}
 
 		if tokenClaims.UserID == 0 {
-			c.AbortWithStatusJSON(401, gin.H{
-				"message": "Unauthorized",
-			})
-			c.Abort()
+			unauthorized(c)
+			return
+		}
+
+		// Access tokens are only as good as the session they were issued
+		// for, which may have been revoked by a logout.
+		sessionID, err := model.ParseUUID(tokenClaims.SessionID)
+		if err != nil {
+			unauthorized(c)
+			return
+		}
+
+		session, err := model.GetSessionByID(db, sessionID)
+		if err != nil || session.UserID != uint(tokenClaims.UserID) || !session.IsActive(time.Now()) {
+			unauthorized(c)
+			return
+		}
+
+		if !setActiveUser(c, db, session.UserID) {
 			return
 		}
 
-		println(tokenClaims.UserID)
 		c.Set(auth.UserIdKey, tokenClaims.UserID)
+		c.Set(auth.SessionIDKey, tokenClaims.SessionID)
+		c.Set(auth.AuthMethodKey, auth.MethodSession)
 		c.Next()
 	}
 }
+
+func authenticateAPIKey(c *gin.Context, db *gorm.DB, token string) {
+	key, err := model.GetAPIKeyByHash(db, utils.HashToken(token))
+	if err != nil || key.RevokedAt != nil {
+		unauthorized(c)
+		return
+	}
+
+	if !setActiveUser(c, db, key.UserID) {
+		return
+	}
+
+	now := time.Now()
+	if err := model.TouchAPIKey(db, key.ID, now, apiKeyTouchInterval); err != nil {
+		log.Printf("Failed to record use of API key %d: %v", key.ID, err)
+	}
+
+	c.Set(auth.UserIdKey, int(key.UserID))
+	c.Set(auth.AuthMethodKey, auth.MethodAPIKey)
+	c.Set(auth.APIKeyIDKey, key.ID)
+	c.Set(auth.ScopesKey, key.ScopeList())
+	c.Next()
+}
+
+// setActiveUser loads the authenticated user and stores their role. Disabled
+// accounts are rejected even while their credentials are still valid.
+func setActiveUser(c *gin.Context, db *gorm.DB, userID uint) bool {
+	user, err := model.GetUserByID(db, userID)
+	if err != nil || user.IsDisabled() {
+		unauthorized(c)
+		return false
+	}
+
+	c.Set(auth.RoleKey, user.Role)
+	return true
+}
+
+// bearerToken extracts the credentials of an Authorization: Bearer header.
+// The second result reports whether such a header was sent at all.
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		return "", false
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", true
+	}
+	return strings.TrimSpace(token), true
+}
+
+// ErrUnauthorized rejects requests without valid credentials.
+var ErrUnauthorized = apperr.Unauthorized(apperr.CodeUnauthorized, "Unauthorized")
+
+func unauthorized(c *gin.Context) {
+	apperr.Abort(c, ErrUnauthorized)
+}
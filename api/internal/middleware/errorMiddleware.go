@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"go-api/internal/apperr"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors renders the last error recorded on the request as an
+// application/problem+json response, unless a response was written
+// already. Errors other than *apperr.Error become internal errors, whose
+// details only reach the access log.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeProblem(c, apperr.From(c.Errors.Last().Err))
+	}
+}
+
+func writeProblem(c *gin.Context, err *apperr.Error) {
+	c.Header("Content-Type", apperr.ContentType)
+	c.JSON(err.Status, err.Problem(c.Request.URL.Path, GetRequestID(c)))
+}
+
+// NotFound answers requests no route matched.
+func NotFound() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apperr.Abort(c, apperr.NotFound(apperr.CodeNotFound, "No route matches "+c.Request.URL.Path))
+	}
+}
+
+// MethodNotAllowed answers requests to a route that lacks their method.
+func MethodNotAllowed() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apperr.Abort(c, apperr.New(http.StatusMethodNotAllowed, apperr.CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs every request once it completed. Query strings are left
+// out since they may carry tokens, such as on the OIDC callback.
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+		if userID := auth.GetCurrentUserID(c); userID != 0 {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(userID)))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		logger.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// Recover turns panics in handlers into internal errors and logs them with
+// their stack. It must run after Errors, which renders the error.
+func Recover(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				logger.ErrorContext(c.Request.Context(), "Handler panicked",
+					"panic", err,
+					"path", c.Request.URL.Path,
+					"stack", string(debug.Stack()),
+				)
+				apperr.Abort(c, apperr.Internal(fmt.Errorf("panic: %v", err)))
+			}
+		}()
+		c.Next()
+	}
+}
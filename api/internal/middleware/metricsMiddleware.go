@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"go-api/internal/metrics"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HTTPMetrics are the request metrics Metrics records.
+type HTTPMetrics struct {
+	// Duration is labelled by method and route.
+	Duration *metrics.HistogramVec
+	// Requests is labelled by method, route and status.
+	Requests *metrics.CounterVec
+}
+
+// Metrics records the latency and status of every request. Requests are
+// labelled with their route pattern rather than the path, so short codes do
+// not create a series each.
+func Metrics(m HTTPMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+
+		m.Duration.Observe(time.Since(start).Seconds(), method, route)
+		m.Requests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// knownMethods keeps made up methods from adding label values.
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
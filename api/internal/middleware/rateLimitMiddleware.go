@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/ratelimit"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit throttles requests with the named policy and reports the quota in
+// RateLimit-* headers. Routes whose policy is not configured are not limited.
+// Policies keyed by user or API key must run after AuthMiddleware.
+func RateLimit(limiter *ratelimit.Limiter, name string) gin.HandlerFunc {
+	policy, ok := limiter.Policy(name)
+	if !ok {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	return func(c *gin.Context) {
+		result, err := limiter.Take(c.Request.Context(), policy, rateLimitSubject(c, policy.By))
+		if err != nil {
+			// A broken store must not take the API down with it.
+			log.Printf("Rate limiter %s failed, letting request through: %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Burst, int(policy.Period.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			apperr.Abort(c, apperr.TooManyRequests(apperr.CodeRateLimited, "Too many requests"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func rateLimitSubject(c *gin.Context, by string) string {
+	switch by {
+	case ratelimit.KeyCredential:
+		if keyID, ok := c.Get(auth.APIKeyIDKey); ok {
+			return fmt.Sprintf("key:%v", keyID)
+		}
+		fallthrough
+	case ratelimit.KeyUser:
+		if userID := auth.GetCurrentUserID(c); userID != 0 {
+			return fmt.Sprintf("user:%d", userID)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets users with one of the given roles through. It must
+// run after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(roles, auth.GetCurrentRole(c)) {
+			apperr.Abort(c, apperr.Forbidden(apperr.CodeForbidden, "Insufficient permissions"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrMissingScope    = apperr.Forbidden("missing_scope", "Missing scope")
+	ErrSessionRequired = apperr.Forbidden("session_required", "This endpoint requires a login session")
+)
+
+// RequireScope rejects requests whose credentials do not grant scope. It must
+// run after AuthMiddleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.HasScope(c, scope) {
+			apperr.Abort(c, ErrMissingScope.WithMessage("Missing scope "+scope))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession only lets requests through that were authenticated by a
+// login session, not by an API key. It guards account management so a
+// leaked key cannot be used to mint new credentials.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.GetAuthMethod(c) != auth.MethodSession {
+			apperr.Abort(c, ErrSessionRequired)
+			return
+		}
+		c.Next()
+	}
+}
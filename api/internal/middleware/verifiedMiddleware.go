@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"go-api/database/model"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var ErrEmailNotVerified = apperr.Forbidden("email_not_verified", "Verify your email address first")
+
+// RequireVerifiedEmail rejects users that did not confirm their email
+// address yet. It must run after AuthMiddleware.
+func RequireVerifiedEmail(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := model.GetUserByID(db, auth.GetCurrentUserID(c))
+		if err != nil {
+			unauthorized(c)
+			return
+		}
+
+		if !user.IsEmailVerified() {
+			apperr.Abort(c, ErrEmailNotVerified)
+			return
+		}
+		c.Next()
+	}
+}
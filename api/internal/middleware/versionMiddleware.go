@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/metrics"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APIVersionKey          = "apiVersion"
+	codeUnsupportedVersion = "unsupported_api_version"
+)
+
+// APIVersion marks the requests of the routes of version. Deprecated
+// versions announce it in the Deprecation (RFC 9745) and Sunset (RFC 8594)
+// headers. usage counts requests by version and by how the version was
+// picked.
+func APIVersion(version apiversion.Version, usage *metrics.CounterVec) gin.HandlerFunc {
+	var deprecation, sunset string
+	if version.IsDeprecated() {
+		deprecation = fmt.Sprintf("@%d", version.Deprecated.Unix())
+	}
+	if !version.Sunset.IsZero() {
+		sunset = version.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, version)
+		c.Header(apiversion.ServedHeader, version.Name)
+		if deprecation != "" {
+			c.Header("Deprecation", deprecation)
+		}
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+
+		usage.Inc(version.Name, apiversion.SelectedBy(c.Request))
+		c.Next()
+	}
+}
+
+// GetAPIVersion returns the version of the API the request is served by.
+func GetAPIVersion(c *gin.Context) apiversion.Version {
+	value, _ := c.Get(APIVersionKey)
+	version, _ := value.(apiversion.Version)
+	return version
+}
+
+// UnsupportedVersion answers unmatched requests that ask for a version
+// that is not served, by path or by the Accept-Version header, before
+// NotFound does.
+func UnsupportedVersion(versions *apiversion.Versions) gin.HandlerFunc {
+	supported := strings.Join(versions.Names(), ", ")
+
+	return func(c *gin.Context) {
+		if name, ok := apiversion.PathVersion(c.Request.URL.Path); ok {
+			if _, served := versions.Lookup(name); !served {
+				apperr.Abort(c, apperr.NotFound(codeUnsupportedVersion,
+					fmt.Sprintf("API version %s is not supported; use one of %s", name, supported)))
+			}
+			return
+		}
+
+		asked := c.GetHeader(apiversion.Header)
+		if asked == "" || !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			return
+		}
+		if _, served := versions.Lookup(asked); !served {
+			apperr.Abort(c, apperr.BadRequest(codeUnsupportedVersion,
+				fmt.Sprintf("API version %q is not supported; use one of %s", asked, supported)))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const lockRetryInterval = time.Second
+
+var ErrLockTimeout = errors.New("timed out waiting for the migration lock")
+
+// locker serializes migrations between processes. It is used on a single
+// connection since database locks belong to the session that took them.
+type locker interface {
+	acquire(ctx context.Context, conn *gorm.DB) error
+	release(conn *gorm.DB) error
+}
+
+func newLocker(dialect, table string, timeout time.Duration) locker {
+	switch dialect {
+	case "postgres":
+		h := fnv.New64a()
+		h.Write([]byte(table))
+		return &postgresLock{key: int64(h.Sum64()), timeout: timeout}
+	case "mysql":
+		return &mysqlLock{name: table, timeout: timeout}
+	default:
+		// SQLite allows a single writer per database file, which is all the
+		// locking it needs.
+		return noLock{}
+	}
+}
+
+// postgresLock uses a session level advisory lock keyed by the table name.
+type postgresLock struct {
+	key     int64
+	timeout time.Duration
+}
+
+func (l *postgresLock) acquire(ctx context.Context, conn *gorm.DB) error {
+	deadline := time.Now().Add(l.timeout)
+	for {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", l.key).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		log.Printf("Waiting for another process to finish migrating...")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (l *postgresLock) release(conn *gorm.DB) error {
+	return conn.Exec("SELECT pg_advisory_unlock(?)", l.key).Error
+}
+
+// mysqlLock uses a named lock, which MySQL waits for by itself.
+type mysqlLock struct {
+	name    string
+	timeout time.Duration
+}
+
+func (l *mysqlLock) acquire(ctx context.Context, conn *gorm.DB) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Cancelling ctx interrupts the query, and its deadline shortens the
+	// wait so that the server does not hold the lock request past it.
+	var locked *int
+	if err := conn.WithContext(ctx).Raw("SELECT GET_LOCK(?, ?)", l.name, lockWaitSeconds(ctx, l.timeout)).Scan(&locked).Error; err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	if locked == nil {
+		return fmt.Errorf("acquiring the migration lock failed")
+	}
+	if *locked != 1 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+// lockWaitSeconds is how many whole seconds GET_LOCK may wait: timeout, or
+// less when ctx ends sooner. It is never negative, which MySQL would take
+// as forever.
+func lockWaitSeconds(ctx context.Context, timeout time.Duration) int {
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+	return max(0, int(timeout/time.Second))
+}
+
+func (l *mysqlLock) release(conn *gorm.DB) error {
+	return conn.Exec("SELECT RELEASE_LOCK(?)", l.name).Error
+}
+
+type noLock struct{}
+
+func (noLock) acquire(context.Context, *gorm.DB) error { return nil }
+func (noLock) release(*gorm.DB) error                  { return nil }
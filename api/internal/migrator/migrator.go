@@ -0,0 +1,320 @@
+// Package migrator applies versioned schema migrations and records them in
+// a schema_migrations table. Migrations are either SQL files or Go
+// functions; SQL migrations are checksummed so edits to an applied file are
+// noticed instead of silently diverging from the database.
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const DefaultTable = "schema_migrations"
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration was modified")
+	ErrUnknownMigration = errors.New("database has migrations this build does not know")
+	ErrNoDown           = errors.New("migration cannot be reverted")
+)
+
+// Migration is one step of the schema history. Versions order migrations;
+// they do not need to be contiguous.
+type Migration struct {
+	Version int64
+	Name    string
+	// Checksum identifies the content of SQL migrations. It is empty for Go
+	// migrations, which are not verified.
+	Checksum string
+	Up       func(tx *gorm.DB) error
+	Down     func(tx *gorm.DB) error
+}
+
+// AppliedMigration is a row of the migrations table.
+type AppliedMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	Checksum  string `gorm:"size:64;not null"`
+	AppliedAt time.Time
+}
+
+// Status describes a migration known to the build, the database, or both.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Modified is set when an applied SQL migration no longer matches its
+	// file.
+	Modified bool
+	// Missing is set for applied migrations this build does not have.
+	Missing bool
+}
+
+type Options struct {
+	// Table overrides DefaultTable.
+	Table string
+	// LockTimeout bounds how long Up and Down wait for another process
+	// holding the migration lock. Zero waits for a minute.
+	LockTimeout time.Duration
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	table      string
+	lock       locker
+}
+
+// New returns a migrator for the given migrations, which must have unique
+// versions.
+func New(db *gorm.DB, migrations []Migration, opts Options) (*Migrator, error) {
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	if opts.LockTimeout == 0 {
+		opts.LockTimeout = time.Minute
+	}
+
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		switch {
+		case a.Version < b.Version:
+			return -1
+		case a.Version > b.Version:
+			return 1
+		}
+		return 0
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", sorted[i].Version)
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+		table:      opts.Table,
+		lock:       newLocker(db.Dialector.Name(), opts.Table, opts.LockTimeout),
+	}, nil
+}
+
+// Status lists every migration, pending ones included, oldest first.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.WithContext(ctx)
+	if err := m.ensureTable(db); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.Modified = isModified(migration, row)
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	slices.SortFunc(statuses, func(a, b Status) int {
+		switch {
+		case a.Version < b.Version:
+			return -1
+		case a.Version > b.Version:
+			return 1
+		}
+		return 0
+	})
+
+	return statuses, nil
+}
+
+// Up applies pending migrations in order, up to and including target, or
+// all of them when target is zero. It returns the applied migrations.
+func (m *Migrator) Up(ctx context.Context, target int64) ([]Migration, error) {
+	var done []Migration
+
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if target > 0 && migration.Version > target {
+				break
+			}
+
+			if err := m.apply(db, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first. It returns
+// the reverted migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			if err := m.revert(db, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+func (m *Migrator) apply(db *gorm.DB, migration Migration) error {
+	start := time.Now()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+		return tx.Table(m.table).Create(&AppliedMigration{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now().UTC(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("applying migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+
+	log.Printf("Applied migration %d %s in %s", migration.Version, migration.Name, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func (m *Migrator) revert(db *gorm.DB, migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("reverting migration %d %s: %w", migration.Version, migration.Name, ErrNoDown)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Table(m.table).Where("version = ?", migration.Version).Delete(&AppliedMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("reverting migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+
+	log.Printf("Reverted migration %d %s", migration.Version, migration.Name)
+	return nil
+}
+
+// verify refuses to touch a database whose history does not match the
+// build: edited SQL files or applied migrations the build does not have.
+func (m *Migrator) verify(applied map[int64]AppliedMigration) error {
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		if row, ok := applied[migration.Version]; ok && isModified(migration, row) {
+			return fmt.Errorf("%w: %d %s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+
+	for version, row := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: %d %s", ErrUnknownMigration, version, row.Name)
+		}
+	}
+	return nil
+}
+
+// locked runs fn on a single connection that holds the migration lock, so
+// deploys that start several instances at once migrate one after another.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// Connection hands out a statement that chained calls would modify.
+		conn = conn.Session(&gorm.Session{})
+
+		if err := m.lock.acquire(ctx, conn); err != nil {
+			return err
+		}
+		defer func() {
+			if err := m.lock.release(conn); err != nil {
+				log.Printf("Releasing the migration lock failed: %v", err)
+			}
+		}()
+
+		// Instances starting together would otherwise all find the table
+		// missing and race to create it.
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) ensureTable(db *gorm.DB) error {
+	if db.Migrator().HasTable(m.table) {
+		return nil
+	}
+	return db.Table(m.table).Migrator().CreateTable(&AppliedMigration{})
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]AppliedMigration, error) {
+	var rows []AppliedMigration
+	if err := db.Table(m.table).Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]AppliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func isModified(migration Migration, row AppliedMigration) bool {
+	return migration.Checksum != "" && row.Checksum != "" && migration.Checksum != row.Checksum
+}
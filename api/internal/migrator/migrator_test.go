@@ -0,0 +1,276 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"go-api/internal/database"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := database.Open(database.SQLite, database.Memory, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// tableMigration creates a table named after the migration, and records
+// the order migrations ran in.
+func tableMigration(version int64, name string, ran *[]string) Migration {
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up: func(tx *gorm.DB) error {
+			*ran = append(*ran, "up "+name)
+			return tx.Exec("CREATE TABLE " + name + " (id integer)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			*ran = append(*ran, "down "+name)
+			return tx.Exec("DROP TABLE " + name).Error
+		},
+	}
+}
+
+func versions(migrations []Migration) []int64 {
+	var vs []int64
+	for _, m := range migrations {
+		vs = append(vs, m.Version)
+	}
+	return vs
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	var ran []string
+	m, err := New(db, []Migration{
+		tableMigration(30, "c", &ran),
+		tableMigration(10, "a", &ran),
+		tableMigration(20, "b", &ran),
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A target between versions applies up to the one before it.
+	done, err := m.Up(ctx, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !slices.Equal(got, []int64{10, 20}) {
+		t.Errorf("Up(25) applied %v, want [10 20]", got)
+	}
+	if done, _ := m.Up(ctx, 20); len(done) != 0 {
+		t.Errorf("Up(20) applied %v again", versions(done))
+	}
+
+	done, err = m.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !slices.Equal(got, []int64{30}) {
+		t.Errorf("Up(0) applied %v, want [30]", got)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil || s.Modified || s.Missing {
+			t.Errorf("status of %d = %+v, want applied", s.Version, s)
+		}
+	}
+
+	done, err = m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := versions(done); !slices.Equal(got, []int64{30, 20}) {
+		t.Errorf("Down(2) reverted %v, want [30 20]", got)
+	}
+	if db.Migrator().HasTable("b") || !db.Migrator().HasTable("a") {
+		t.Error("Down(2) left the wrong tables")
+	}
+
+	want := []string{"up a", "up b", "up c", "down c", "down b"}
+	if !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+
+	// Reverting more steps than were applied stops at the first migration.
+	if done, err := m.Down(ctx, 5); err != nil || !slices.Equal(versions(done), []int64{10}) {
+		t.Errorf("Down(5) = %v, %v", versions(done), err)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	var ran []string
+	broken := Migration{Version: 2, Name: "broken", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE TABLE half (id integer)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("NOT SQL").Error
+	}}
+	m, err := New(db, []Migration{tableMigration(1, "a", &ran), broken, tableMigration(3, "c", &ran)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done, err := m.Up(ctx, 0)
+	if err == nil {
+		t.Fatal("Up succeeded")
+	}
+	if got := versions(done); !slices.Equal(got, []int64{1}) {
+		t.Errorf("applied %v, want [1]", got)
+	}
+	if db.Migrator().HasTable("half") || db.Migrator().HasTable("c") {
+		t.Error("the failed migration or a later one left tables behind")
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[1].AppliedAt != nil {
+		t.Error("the failed migration was recorded")
+	}
+}
+
+func TestNoDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	m, err := New(db, []Migration{{Version: 1, Name: "once", Up: func(*gorm.DB) error { return nil }}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDown) {
+		t.Errorf("Down = %v, want %v", err, ErrNoDown)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	load := func(users string) *Migrator {
+		t.Helper()
+		migrations, err := LoadSQL(fstest.MapFS{
+			"1_users.up.sql": {Data: []byte(users)},
+			"2_posts.up.sql": {Data: []byte("CREATE TABLE posts (id integer);")},
+		}, database.SQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := New(db, migrations, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	if _, err := load("CREATE TABLE users (id integer);").Up(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Editing an applied file stops every later migration.
+	edited := load("CREATE TABLE users (id integer, name text);")
+	if _, err := edited.Up(ctx, 0); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Up = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if _, err := edited.Down(ctx, 1); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Down = %v, want %v", err, ErrChecksumMismatch)
+	}
+	if db.Migrator().HasTable("posts") {
+		t.Error("posts was created after the mismatch")
+	}
+
+	statuses, err := edited.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Modified || statuses[1].Modified || statuses[1].AppliedAt != nil {
+		t.Errorf("statuses = %+v, want 1 modified and 2 pending", statuses)
+	}
+}
+
+func TestUnknownMigration(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	var ran []string
+	newer, err := New(db, []Migration{tableMigration(1, "a", &ran), tableMigration(2, "b", &ran)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newer.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	older, err := New(db, []Migration{tableMigration(1, "a", &ran)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := older.Up(ctx, 0); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("Up = %v, want %v", err, ErrUnknownMigration)
+	}
+
+	statuses, err := older.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || !statuses[1].Missing || statuses[1].Name != "b" {
+		t.Errorf("statuses = %+v, want 2 missing", statuses)
+	}
+}
+
+func TestDuplicateVersion(t *testing.T) {
+	var ran []string
+	_, err := New(newTestDB(t), []Migration{tableMigration(1, "a", &ran), tableMigration(1, "b", &ran)}, Options{})
+	if err == nil {
+		t.Error("New accepted two migrations with version 1")
+	}
+}
+
+func TestLockWaitSeconds(t *testing.T) {
+	if got := lockWaitSeconds(context.Background(), time.Minute); got != 60 {
+		t.Errorf("without a deadline = %d, want 60", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second+500*time.Millisecond)
+	defer cancel()
+	if got := lockWaitSeconds(ctx, time.Minute); got != 10 {
+		t.Errorf("with a nearer deadline = %d, want 10", got)
+	}
+	if got := lockWaitSeconds(ctx, 5*time.Second); got != 5 {
+		t.Errorf("with a later deadline = %d, want 5", got)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if got := lockWaitSeconds(ctx, time.Minute); got != 0 {
+		t.Errorf("past the deadline = %d, want 0", got)
+	}
+}
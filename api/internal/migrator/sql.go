@@ -0,0 +1,222 @@
+package migrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// sqlFileName matches <version>_<name>.<up|down>[.<dialect>].sql. A file with
+// a dialect replaces the generic one on that database.
+var sqlFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)(?:\.([a-z]+))?\.sql$`)
+
+type sqlFile struct {
+	up, down               string
+	upDialect, downDialect bool
+}
+
+// LoadSQL reads the SQL migrations in the root of fsys for the given
+// dialect. Every version needs an up file; without a down file the
+// migration cannot be reverted.
+func LoadSQL(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[int64]*sqlFile)
+	names := make(map[int64]string)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		match := sqlFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s does not match <version>_<name>.<up|down>[.<dialect>].sql", entry.Name())
+		}
+		if match[4] != "" && match[4] != dialect {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: %w", entry.Name(), err)
+		}
+		if name, ok := names[version]; ok && name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, name, match[2])
+		}
+		names[version] = match[2]
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		file := files[version]
+		if file == nil {
+			file = &sqlFile{}
+			files[version] = file
+		}
+
+		specific := match[4] != ""
+		if match[3] == "up" {
+			if specific || !file.upDialect {
+				file.up, file.upDialect = string(content), specific
+			}
+		} else if specific || !file.downDialect {
+			file.down, file.downDialect = string(content), specific
+		}
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for version, file := range files {
+		if strings.TrimSpace(file.up) == "" {
+			return nil, fmt.Errorf("migration %d %s has no up file", version, names[version])
+		}
+
+		sum := sha256.Sum256([]byte(file.up))
+		migration := Migration{
+			Version:  version,
+			Name:     names[version],
+			Checksum: hex.EncodeToString(sum[:]),
+			Up:       execSQL(file.up),
+		}
+		if strings.TrimSpace(file.down) != "" {
+			migration.Down = execSQL(file.down)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, nil
+}
+
+func execSQL(script string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range SplitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// SplitStatements splits a script on semicolons that are not inside quotes,
+// comments or dollar quoted bodies, since not every driver runs several
+// statements in one call.
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" && !onlyComments(statement) {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	// closeAt returns the index after the end of a quote or comment whose
+	// terminator was searched for from start, or the end of the script when
+	// it is not terminated.
+	closeAt := func(start, found, length int) int {
+		if found < 0 {
+			return len(script)
+		}
+		return start + found + length
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			end := closeAt(i+1, strings.IndexByte(script[i+1:], ch), 1)
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == '-' && strings.HasPrefix(script[i:], "--"):
+			end := closeAt(i, strings.IndexByte(script[i:], '\n'), 0)
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := closeAt(i+2, strings.Index(script[i+2:], "*/"), 2)
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == '$':
+			tag := dollarTag(script[i:])
+			if tag == "" {
+				current.WriteByte(ch)
+				continue
+			}
+			end := closeAt(i+len(tag), strings.Index(script[i+len(tag):], tag), len(tag))
+			current.WriteString(script[i:end])
+			i = end - 1
+		case ch == ';':
+			flush()
+		default:
+			current.WriteByte(ch)
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// dollarTag returns the $tag$ opening a PostgreSQL dollar quoted string at
+// the start of s, if any.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '$':
+			return s[:i+1]
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || i > 1 && ch >= '0' && ch <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func onlyComments(statement string) bool {
+	for _, line := range strings.Split(statement, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateSQL writes empty up and down files for a new migration to dir. The
+// version is the current UTC time, so migrations written on different
+// branches do not collide.
+func CreateSQL(dir, name string, now time.Time) ([]string, error) {
+	name = strings.Trim(strings.ToLower(regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(name, "_")), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name must contain letters or digits")
+	}
+
+	base := now.UTC().Format("20060102150405") + "_" + name
+	paths := []string{
+		filepath.Join(dir, base+".up.sql"),
+		filepath.Join(dir, base+".down.sql"),
+	}
+
+	for _, p := range paths {
+		content := fmt.Sprintf("-- %s\n", path.Base(filepath.ToSlash(p)))
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,123 @@
+package migrator
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "statements",
+			script: "CREATE TABLE a (id int);\n\nCREATE TABLE b (id int)  ;\n",
+			want:   []string{"CREATE TABLE a (id int)", "CREATE TABLE b (id int)"},
+		},
+		{
+			name:   "quotes",
+			script: `INSERT INTO a VALUES ('x;y', "c;d", ` + "`e;f`" + `); SELECT 1`,
+			want:   []string{`INSERT INTO a VALUES ('x;y', "c;d", ` + "`e;f`" + `)`, "SELECT 1"},
+		},
+		{
+			name:   "escaped quotes",
+			script: `INSERT INTO a VALUES ('it''s; fine', ''); SELECT ';'''`,
+			want:   []string{`INSERT INTO a VALUES ('it''s; fine', '')`, `SELECT ';'''`},
+		},
+		{
+			name:   "line comments",
+			script: "-- create a; then b\nCREATE TABLE a (id int); -- trailing; comment\nCREATE TABLE b (id int);\n-- done;",
+			want:   []string{"-- create a; then b\nCREATE TABLE a (id int)", "-- trailing; comment\nCREATE TABLE b (id int)"},
+		},
+		{
+			name:   "block comments",
+			script: "CREATE /* a; b */ TABLE a (id int);/* x */ SELECT 1",
+			want:   []string{"CREATE /* a; b */ TABLE a (id int)", "/* x */ SELECT 1"},
+		},
+		{
+			name:   "dollar quotes",
+			script: "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql; SELECT $$a;b$$; SELECT $1",
+			want:   []string{"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $$a;b$$", "SELECT $1"},
+		},
+		{
+			name:   "unterminated quote",
+			script: "SELECT 1; SELECT 'a; b",
+			want:   []string{"SELECT 1", "SELECT 'a; b"},
+		},
+		{
+			name:   "unterminated comment",
+			script: "SELECT 1 /* a; b",
+			want:   []string{"SELECT 1 /* a; b"},
+		},
+		{
+			name:   "unterminated dollar quote",
+			script: "SELECT $x$ a; b",
+			want:   []string{"SELECT $x$ a; b"},
+		},
+		{
+			name:   "empty",
+			script: " ;\n; -- nothing\n",
+			want:   nil,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.script); !slices.Equal(got, tt.want) {
+				t.Errorf("SplitStatements(%q)\n got %q\nwant %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSQL(t *testing.T) {
+	fsys := fstest.MapFS{
+		"2_posts.up.sql":          {Data: []byte("CREATE TABLE posts (id int);")},
+		"2_posts.up.postgres.sql": {Data: []byte("CREATE TABLE posts (id serial);")},
+		"2_posts.down.sql":        {Data: []byte("DROP TABLE posts;")},
+		"1_users.up.sql":          {Data: []byte("CREATE TABLE users (id int);")},
+		"1_users.down.mysql.sql":  {Data: []byte("DROP TABLE users;")},
+		"3_tags.up.sqlite.sql":    {Data: []byte("CREATE TABLE tags (id int);")},
+		"README.md":               {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadSQL(fsys, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return int(a.Version - b.Version) })
+	if len(migrations) != 2 {
+		t.Fatalf("loaded %d migrations, want 1 and 2", len(migrations))
+	}
+
+	users, posts := migrations[0], migrations[1]
+	if users.Version != 1 || users.Name != "users" || users.Down != nil {
+		t.Errorf("1 = %d %s, want users without a down file", users.Version, users.Name)
+	}
+	if posts.Version != 2 || posts.Name != "posts" || posts.Down == nil {
+		t.Errorf("2 = %d %s, want posts with a down file", posts.Version, posts.Name)
+	}
+
+	// The dialect file replaces the generic one, and so does its checksum.
+	generic, err := LoadSQL(fsys, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range generic {
+		if m.Version == 2 && m.Checksum == posts.Checksum {
+			t.Error("postgres and sqlite share the checksum of 2")
+		}
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"no up file": {"1_users.down.sql": {Data: []byte("DROP TABLE users;")}},
+		"bad name":   {"users.up.sql": {Data: []byte("SELECT 1;")}},
+		"two names":  {"1_users.up.sql": {Data: []byte("SELECT 1;")}, "1_people.down.sql": {Data: []byte("SELECT 1;")}},
+		"empty up":   {"1_users.up.sql": {Data: []byte(" \n")}},
+	} {
+		if _, err := LoadSQL(fsys, "postgres"); err == nil {
+			t.Errorf("%s: LoadSQL succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go-api/internal/oidc"
+	"go-api/internal/oidc/oidctest"
+)
+
+const callback = "https://api.example.com/auth/oidc/test/callback"
+
+func newProvider(t *testing.T) (*oidc.Provider, *oidctest.Server) {
+	t.Helper()
+
+	srv, err := oidctest.NewServer(oidctest.Options{ClientID: "client", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	return oidc.NewProvider(oidc.Options{
+		Name:         "test",
+		Issuer:       srv.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+	}), srv
+}
+
+// authorize follows the login URL of p and returns the code the provider
+// redirected back with.
+func authorize(t *testing.T, p *oidc.Provider, state, nonce, verifier string) string {
+	t.Helper()
+
+	authURL, err := p.AuthCodeURL(context.Background(), callback, state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if !strings.HasPrefix(location.String(), callback+"?") {
+		t.Fatalf("redirected to %s", location)
+	}
+	if got := location.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return location.Query().Get("code")
+}
+
+func TestLogin(t *testing.T) {
+	p, srv := newProvider(t)
+	srv.SetUser(oidctest.User{Subject: "sub-1", Email: "a@example.com", EmailVerified: true, Name: "A"})
+	ctx := context.Background()
+
+	code := authorize(t, p, "state", "nonce", "verifier")
+	token, err := p.Exchange(ctx, callback, code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "sub-1" || claims.Email != "a@example.com" || !bool(claims.EmailVerified) || claims.Name != "A" {
+		t.Errorf("claims %+v", claims)
+	}
+
+	// Codes are single use.
+	if _, err := p.Exchange(ctx, callback, code, "verifier"); err == nil {
+		t.Error("code exchanged twice")
+	}
+}
+
+func TestExchangeChecksPKCEVerifier(t *testing.T) {
+	p, _ := newProvider(t)
+	ctx := context.Background()
+
+	code := authorize(t, p, "state", "nonce", "verifier")
+	if _, err := p.Exchange(ctx, callback, code, "another verifier"); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Exchange with the wrong verifier = %v, want invalid_grant", err)
+	}
+}
+
+func TestExchangeChecksRedirectURL(t *testing.T) {
+	p, _ := newProvider(t)
+
+	code := authorize(t, p, "state", "nonce", "verifier")
+	if _, err := p.Exchange(context.Background(), "https://evil.example.com/", code, "verifier"); err == nil {
+		t.Error("Exchange with another redirect URL succeeded")
+	}
+}
+
+func TestVerifyIDTokenNonce(t *testing.T) {
+	p, srv := newProvider(t)
+	ctx := context.Background()
+
+	srv.OverrideNonce("replayed")
+	code := authorize(t, p, "state", "nonce", "verifier")
+	token, err := p.Exchange(ctx, callback, code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.VerifyIDToken(ctx, token.IDToken, "nonce"); !errors.Is(err, oidc.ErrNonceMismatch) {
+		t.Errorf("VerifyIDToken = %v, want ErrNonceMismatch", err)
+	}
+}
+
+func TestVerifyIDTokenAudience(t *testing.T) {
+	p, srv := newProvider(t)
+	ctx := context.Background()
+
+	code := authorize(t, p, "state", "nonce", "verifier")
+	token, err := p.Exchange(ctx, callback, code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same provider, registered for another client.
+	other := oidc.NewProvider(oidc.Options{Name: "other", Issuer: srv.Issuer(), ClientID: "other"})
+	if _, err := other.VerifyIDToken(ctx, token.IDToken, "nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken for another client = %v, want ErrInvalidIDToken", err)
+	}
+	// Tampered tokens fail the signature check.
+	if _, err := p.VerifyIDToken(ctx, token.IDToken+"x", "nonce"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken of a tampered token = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestExchangeChecksClientSecret(t *testing.T) {
+	p, srv := newProvider(t)
+
+	wrong := oidc.NewProvider(oidc.Options{Name: "test", Issuer: srv.Issuer(), ClientID: "client", ClientSecret: "wrong"})
+	code := authorize(t, p, "state", "nonce", "verifier")
+	if _, err := wrong.Exchange(context.Background(), callback, code, "verifier"); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Exchange with the wrong secret = %v, want invalid_client", err)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B.
+	if got := oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %q", got)
+	}
+}
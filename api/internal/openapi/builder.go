@@ -0,0 +1,325 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Security scheme names.
+const (
+	BearerAuth = "bearerAuth"
+	CookieAuth = "cookieAuth"
+)
+
+// ErrorResponse is the component every operation refers to for its error
+// responses.
+const ErrorResponse = "Error"
+
+// Spec collects the operations of the API into a Document.
+type Spec struct {
+	doc     Document
+	schemas *schemas
+}
+
+func New(info Info, servers ...Server) *Spec {
+	s := &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Servers: servers,
+			Paths:   make(map[string]*PathItem),
+		},
+		schemas: newSchemas(),
+	}
+
+	s.doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+		BearerAuth: {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "An access token from /auth/login, or a personal API key.",
+		},
+		CookieAuth: {
+			Type:        "apiKey",
+			In:          "cookie",
+			Name:        "token",
+			Description: "The access token cookie set by /auth/login.",
+		},
+	}
+	s.doc.Components.Responses = map[string]*Response{
+		ErrorResponse: {
+			Description: "The request failed. The body is a message describing why.",
+			Content:     jsonContent(&Schema{Type: "string"}),
+		},
+	}
+	return s
+}
+
+// Override describes the type of v with schema instead of reflecting on
+// it, for types with their own JSON encoding.
+func (s *Spec) Override(v any, schema Schema) {
+	s.schemas.overrides[reflect.TypeOf(v)] = &schema
+}
+
+// Name sets the component name of the struct type of v, for types whose
+// name says little outside their package.
+func (s *Spec) Name(v any, name string) {
+	s.schemas.aliases[reflect.TypeOf(v)] = name
+}
+
+// Errors sets the error response every operation refers to: its
+// description and the body, a Content since errors often have a media type
+// of their own.
+func (s *Spec) Errors(description string, body Content) {
+	s.doc.Components.Responses[ErrorResponse] = &Response{
+		Description: description,
+		Content:     s.content(body),
+	}
+}
+
+// Document returns the description of every operation added so far.
+func (s *Spec) Document() *Document {
+	s.doc.Components.Schemas = s.schemas.components
+	return &s.doc
+}
+
+// Group returns a group of operations under prefix, tagged with tags.
+func (s *Spec) Group(prefix string, tags ...string) *Group {
+	return &Group{spec: s, prefix: prefix, tags: tags}
+}
+
+// Group mirrors a gin.RouterGroup: operations added to it share its path
+// prefix, tags and security.
+type Group struct {
+	spec       *Spec
+	prefix     string
+	tags       []string
+	security   []map[string][]string
+	deprecated bool
+}
+
+// Group returns a sub-group under prefix. It keeps the tags of g unless
+// others are given.
+func (g *Group) Group(prefix string, tags ...string) *Group {
+	sub := *g
+	sub.prefix = joinPath(g.prefix, prefix)
+	if len(tags) > 0 {
+		sub.tags = tags
+	}
+	return &sub
+}
+
+// Authenticated returns a copy of g whose operations require a user,
+// signed in or by API key.
+func (g *Group) Authenticated() *Group {
+	sub := *g
+	sub.security = []map[string][]string{
+		{BearerAuth: {}},
+		{CookieAuth: {}},
+	}
+	return &sub
+}
+
+// Deprecated returns a copy of g whose operations are marked deprecated.
+func (g *Group) Deprecated() *Group {
+	sub := *g
+	sub.deprecated = true
+	return &sub
+}
+
+// Op describes one operation. Params, Query and Body are values of the
+// types the handler binds; their schemas are derived from the types.
+type Op struct {
+	// ID names the operation, and the method of the generated client.
+	ID          string
+	Summary     string
+	Description string
+
+	// Params is bound from the path (uri tags). Path parameters without a
+	// field are described as strings.
+	Params any
+	// Query is bound from the query string (form tags).
+	Query any
+	// Body is the request body, described like Responses values.
+	Body any
+	// OptionalBody accepts requests without a body.
+	OptionalBody bool
+
+	// Responses maps status codes to a value of the type returned.
+	Responses Responses
+}
+
+// Responses values are described by their type. A nil value has no body,
+// and Redirect, Content and OneOf describe responses that are not a single
+// JSON type.
+type Responses map[int]any
+
+// Redirect is a response sending the client to its Location header.
+type Redirect struct{}
+
+// Content is a body in one or more media types other than JSON, by media
+// type. Values are described like Responses values; a string is plain
+// text.
+type Content map[string]any
+
+// File is an uploaded file in a multipart/form-data body.
+type File []byte
+
+type oneOf []any
+
+// OneOf is a JSON response of any of the types of values.
+func OneOf(values ...any) any {
+	return oneOf(values)
+}
+
+func (g *Group) GET(path string, op Op)    { g.Handle(http.MethodGet, path, op) }
+func (g *Group) POST(path string, op Op)   { g.Handle(http.MethodPost, path, op) }
+func (g *Group) PUT(path string, op Op)    { g.Handle(http.MethodPut, path, op) }
+func (g *Group) PATCH(path string, op Op)  { g.Handle(http.MethodPatch, path, op) }
+func (g *Group) DELETE(path string, op Op) { g.Handle(http.MethodDelete, path, op) }
+
+// Handle adds op for method at path, written as for gin with :name
+// parameters.
+func (g *Group) Handle(method, path string, op Op) {
+	fullPath, names := convertPath(joinPath(g.prefix, path))
+	s := g.spec
+
+	item := s.doc.Paths[fullPath]
+	if item == nil {
+		item = &PathItem{}
+		s.doc.Paths[fullPath] = item
+	}
+	slot := item.slot(method)
+	if slot == nil {
+		panic("openapi: unsupported method " + method)
+	}
+	if *slot != nil {
+		panic(fmt.Sprintf("openapi: %s %s described twice", method, fullPath))
+	}
+
+	operation := &Operation{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        g.tags,
+		Security:    g.security,
+		Deprecated:  g.deprecated,
+		Responses:   make(map[string]*Response),
+	}
+
+	operation.Parameters = s.pathParameters(names, op.Params)
+	if op.Query != nil {
+		operation.Parameters = append(operation.Parameters,
+			s.schemas.parameters(reflect.TypeOf(op.Query), "query", "form")...)
+	}
+
+	if op.Body != nil {
+		operation.RequestBody = &RequestBody{
+			Required: !op.OptionalBody,
+			Content:  s.content(op.Body),
+		}
+	}
+
+	for status, value := range op.Responses {
+		operation.Responses[strconv.Itoa(status)] = s.response(status, value)
+	}
+	operation.Responses["default"] = &Response{Ref: "#/components/responses/" + ErrorResponse}
+
+	*slot = operation
+}
+
+// pathParameters describes the parameters named in the path, typed by the
+// fields of params where it has them.
+func (s *Spec) pathParameters(names []string, params any) []*Parameter {
+	var bound []*Parameter
+	if params != nil {
+		bound = s.schemas.parameters(reflect.TypeOf(params), "path", "uri")
+	}
+
+	result := make([]*Parameter, 0, len(names))
+	for _, name := range names {
+		i := slices.IndexFunc(bound, func(p *Parameter) bool { return p.Name == name })
+		if i >= 0 {
+			result = append(result, bound[i])
+			continue
+		}
+		result = append(result, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	return result
+}
+
+func (s *Spec) response(status int, value any) *Response {
+	response := &Response{Description: http.StatusText(status)}
+
+	switch value := value.(type) {
+	case nil:
+	case Redirect:
+		response.Headers = map[string]*Header{
+			"Location": {Description: "The URL to go to.", Schema: &Schema{Type: "string"}},
+		}
+	default:
+		response.Content = s.content(value)
+	}
+	return response
+}
+
+// content describes a body: JSON of the type of value, unless it is
+// Content or OneOf.
+func (s *Spec) content(value any) map[string]*MediaType {
+	switch value := value.(type) {
+	case Content:
+		content := make(map[string]*MediaType, len(value))
+		for mediaType, v := range value {
+			content[mediaType] = &MediaType{Schema: s.schemas.of(reflect.TypeOf(v))}
+		}
+		return content
+	case oneOf:
+		schema := &Schema{}
+		for _, v := range value {
+			schema.OneOf = append(schema.OneOf, s.schemas.of(reflect.TypeOf(v)))
+		}
+		return jsonContent(schema)
+	default:
+		return jsonContent(s.schemas.of(reflect.TypeOf(value)))
+	}
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+func joinPath(prefix, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// Path turns a gin route like /links/:id into the OpenAPI path
+// /links/{id}.
+func Path(route string) string {
+	path, _ := convertPath(route)
+	return path
+}
+
+// convertPath converts like Path and also returns the parameter names.
+func convertPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var names []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			names = append(names, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), names
+}
@@ -0,0 +1,265 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	fileType = reflect.TypeOf(File(nil))
+)
+
+// schemas derives schemas from Go types. Named structs become components
+// and are referenced, everything else is inlined.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	aliases    map[reflect.Type]string
+	overrides  map[reflect.Type]*Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		aliases:    make(map[reflect.Type]string),
+		overrides:  make(map[reflect.Type]*Schema),
+	}
+}
+
+func (s *schemas) of(t reflect.Type) *Schema {
+	if override, ok := s.overrides[t]; ok {
+		clone := *override
+		return &clone
+	}
+
+	if t == fileType {
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(s.of(t.Elem()))
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Interface:
+		// Any JSON value.
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+	panic("openapi: unsupported type " + t.String())
+}
+
+// component registers the named struct t and returns its component name:
+// its alias, or the type name, qualified by the package when another
+// package already took it.
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name, ok := s.aliases[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := s.components[name]; taken {
+			name = exported(pkgName(t)) + name
+		}
+	}
+	if _, taken := s.components[name]; taken {
+		panic("openapi: two types named " + name)
+	}
+
+	s.names[t] = name
+	// Registered before the fields are walked so recursive types end.
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t)
+	return name
+}
+
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s *schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, omitempty, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			s.addFields(schema, embedded)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		property := s.of(f.Type)
+		required := applyBinding(property, f.Tag.Get("binding"))
+		schema.Properties[name] = property
+		if required && !omitempty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// parameters describes the fields of a struct bound from the query (tag
+// form) or the path (tag uri).
+func (s *schemas) parameters(t reflect.Type, in, tag string) []*Parameter {
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous {
+			params = append(params, s.parameters(f.Type, in, tag)...)
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := s.of(f.Type)
+		required := applyBinding(schema, f.Tag.Get("binding"))
+		params = append(params, &Parameter{
+			Name:     name,
+			In:       in,
+			Required: required || in == "path",
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+// jsonName reads the json tag of f. ok is false for fields that are not
+// serialized; name is empty when the tag does not rename the field.
+func jsonName(f reflect.StructField) (name string, omitempty, ok bool) {
+	if !f.IsExported() && !f.Anonymous {
+		return "", false, false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, true
+}
+
+// applyBinding adds the constraints of the validator tag of gin's binding
+// to schema and reports whether the field is required. Rules after dive
+// apply to the items of a list.
+func applyBinding(schema *Schema, binding string) bool {
+	required := false
+	target := schema
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "required":
+			required = true
+		case "dive":
+			if target.Items != nil {
+				target = target.Items
+			}
+		case "email":
+			target.Format = "email"
+		case "oneof":
+			target.Enum = strings.Fields(value)
+		case "min", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			setBound(target, key == "min", n)
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, min bool, n int) {
+	switch schema.Type {
+	case "string":
+		if min {
+			schema.MinLength = &n
+		} else {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if min {
+			schema.MinItems = &n
+		} else {
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if min {
+			schema.Minimum = &f
+		} else {
+			schema.Maximum = &f
+		}
+	}
+}
+
+// nullable marks schema as accepting null. A reference cannot carry
+// siblings in OpenAPI 3.0, so it is wrapped.
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+	}
+	schema.Nullable = true
+	return schema
+}
+
+func pkgName(t reflect.Type) string {
+	path := t.PkgPath()
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
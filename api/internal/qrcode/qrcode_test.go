@@ -0,0 +1,441 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGFMultiply(t *testing.T) {
+	// Powers of the generator 2, as tabulated in the standard.
+	powers := []byte{1, 2, 4, 8, 16, 32, 64, 128, 29, 58, 116, 232, 205, 135, 19, 38, 76, 152, 45, 90, 180, 117, 234, 201, 143, 3}
+	x := byte(1)
+	for i, want := range powers {
+		if x != want {
+			t.Fatalf("2^%d = %d, want %d", i, x, want)
+		}
+		x = gfMultiply(x, 2)
+	}
+
+	if got := gfMultiply(0, 0xff); got != 0 {
+		t.Errorf("0 * 255 = %d", got)
+	}
+}
+
+func TestRSDivisor(t *testing.T) {
+	// The generator polynomial for 7 codewords, leading term left out.
+	want := []byte{127, 122, 154, 164, 11, 68, 117}
+	if got := rsDivisor(7); !bytes.Equal(got, want) {
+		t.Errorf("rsDivisor(7) = %v, want %v", got, want)
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// HELLO WORLD at 1-M, the worked example most QR references use.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = %v, want %v", got, want)
+	}
+}
+
+func TestDataCodewords(t *testing.T) {
+	// Byte mode capacities from the standard's tables.
+	tests := []struct {
+		version int
+		level   Level
+		bytes   int
+	}{
+		{1, Low, 17},
+		{1, Medium, 14},
+		{1, Quartile, 11},
+		{1, High, 7},
+		{10, Medium, 213},
+		{5, Low, 106},
+		{5, High, 44},
+		{40, Low, 2953},
+		{40, High, 1273},
+	}
+	for _, tt := range tests {
+		got := (8*dataCodewords(tt.version, tt.level) - 4 - countBits(tt.version)) / 8
+		if got != tt.bytes {
+			t.Errorf("version %d-%s holds %d bytes, want %d", tt.version, tt.level, got, tt.bytes)
+		}
+	}
+}
+
+func TestEncodePicksSmallestVersion(t *testing.T) {
+	tests := []struct {
+		n       int
+		level   Level
+		version int
+	}{
+		{17, Low, 1},
+		{18, Low, 2},
+		{7, High, 1},
+		{8, High, 2},
+		{2953, Low, 40},
+	}
+	for _, tt := range tests {
+		c, err := Encode(bytes.Repeat([]byte("a"), tt.n), tt.level)
+		if err != nil {
+			t.Fatalf("%d bytes at %s: %v", tt.n, tt.level, err)
+		}
+		if c.Version != tt.version || c.Size != 4*tt.version+17 {
+			t.Errorf("%d bytes at %s: version %d size %d, want version %d", tt.n, tt.level, c.Version, c.Size, tt.version)
+		}
+	}
+
+	if _, err := Encode(bytes.Repeat([]byte("a"), 2954), Low); !errors.Is(err, ErrTooLong) {
+		t.Errorf("2954 bytes: %v, want ErrTooLong", err)
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	tests := map[int][]int{
+		1:  nil,
+		2:  {6, 18},
+		7:  {6, 22, 38},
+		14: {6, 26, 46, 66},
+		32: {6, 34, 60, 86, 112, 138},
+		40: {6, 30, 58, 86, 114, 142, 170},
+	}
+	for version, want := range tests {
+		if got := alignmentPositions(version); !slices.Equal(got, want) {
+			t.Errorf("version %d: %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	// Format information for mask 0, from the standard.
+	want := map[Level]int{Low: 0x77c4, Medium: 0x5412, Quartile: 0x355f, High: 0x1689}
+	for level, bits := range want {
+		c := newCode(1, level)
+		c.drawFormatBits(0)
+		if got := readFormat(c); got != bits {
+			t.Errorf("%s: format %015b, want %015b", level, got, bits)
+		}
+	}
+
+	// Every pair of format words differs in at least 7 bits, so up to 3
+	// flipped bits are corrected.
+	var words []int
+	for level := Low; level <= High; level++ {
+		for mask := range 8 {
+			c := newCode(1, level)
+			c.drawFormatBits(mask)
+			words = append(words, readFormat(c))
+		}
+	}
+	for i := range words {
+		for j := i + 1; j < len(words); j++ {
+			if d := popcount(words[i] ^ words[j]); d < 7 {
+				t.Errorf("format words %015b and %015b differ in %d bits", words[i], words[j], d)
+			}
+		}
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	// Version information from the standard.
+	want := map[int]int{7: 0x07c94, 8: 0x085bc, 21: 0x15683, 40: 0x28c69}
+	for version, bits := range want {
+		c := newCode(version, Medium)
+		c.drawVersion()
+
+		var top, left int
+		for i := range 18 {
+			a, b := c.Size-11+i%3, i/3
+			if c.Dark(a, b) {
+				top |= 1 << i
+			}
+			if c.Dark(b, a) {
+				left |= 1 << i
+			}
+		}
+		if top != bits || left != bits {
+			t.Errorf("version %d: %018b and %018b, want %018b", version, top, left, bits)
+		}
+	}
+}
+
+// maskedAt is the mask condition as the standard writes it, for row i and
+// column j.
+func maskedAt(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return i*j%2+i*j%3 == 0
+	case 6:
+		return (i*j%2+i*j%3)%2 == 0
+	default:
+		return ((i+j)%2+i*j%3)%2 == 0
+	}
+}
+
+func TestMasks(t *testing.T) {
+	for mask := range 8 {
+		c := newCode(1, Low)
+		c.applyMask(mask)
+		for i := range c.Size {
+			for j := range c.Size {
+				if c.Dark(j, i) != maskedAt(mask, i, j) {
+					t.Fatalf("mask %d differs at row %d column %d", mask, i, j)
+				}
+			}
+		}
+
+		// Function patterns are never masked.
+		c = newCode(2, Low)
+		c.drawFunctionPatterns()
+		before := slices.Clone(c.modules)
+		c.applyMask(mask)
+		for k := range c.modules {
+			if c.function[k] && c.modules[k] != before[k] {
+				t.Fatalf("mask %d flipped function module %d", mask, k)
+			}
+		}
+	}
+}
+
+func TestEncodeChoosesLowestPenalty(t *testing.T) {
+	c, err := Encode([]byte("https://example.com/short/abc1234"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chosen := (readFormat(c) ^ 0x5412) >> 10 & 7
+
+	best := c.penalty()
+	c.applyMask(chosen)
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); p < best {
+			t.Errorf("mask %d scores %d, below the chosen mask %d with %d", mask, p, chosen, best)
+		}
+		c.applyMask(mask)
+	}
+}
+
+func TestFunctionPatterns(t *testing.T) {
+	c, err := Encode([]byte("hello"), Low)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finder := []string{
+		"#######",
+		"#.....#",
+		"#.###.#",
+		"#.###.#",
+		"#.###.#",
+		"#.....#",
+		"#######",
+	}
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for y, row := range finder {
+			for x, m := range row {
+				if c.Dark(corner[0]+x, corner[1]+y) != (m == '#') {
+					t.Fatalf("finder at %v differs at %d,%d", corner, x, y)
+				}
+			}
+		}
+	}
+
+	for i := 8; i < c.Size-8; i++ {
+		if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+			t.Fatalf("timing pattern differs at %d", i)
+		}
+	}
+	if !c.Dark(8, c.Size-8) {
+		t.Error("the dark module is light")
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"hello",
+		"https://example.com/short/abc1234",
+		strings.Repeat("0123456789", 20), // version 7 and up carry version bits
+		strings.Repeat("ünïcödé ", 40),   // 16 bit character counts
+		strings.Repeat("https://sho.rt/", 150)[:2000], // many blocks of both lengths
+	}
+	for _, input := range inputs {
+		for level := Low; level <= High; level++ {
+			c, err := Encode([]byte(input), level)
+			if errors.Is(err, ErrTooLong) {
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := decode(c)
+			if err != nil {
+				t.Fatalf("%d bytes at %s, version %d: %v", len(input), level, c.Version, err)
+			}
+			if string(got) != input {
+				t.Fatalf("%d bytes at %s: decoded %q", len(input), level, got)
+			}
+		}
+	}
+}
+
+func TestEncodeDataCodewords(t *testing.T) {
+	// "hello" in byte mode: 0100, a count of 5, the bytes and a terminator,
+	// then pad bytes up to the 19 data codewords of 1-L.
+	c, err := Encode([]byte("hello"), Low)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x40, 0x56, 0x86, 0x56, 0xc6, 0xc6, 0xf0, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11}
+	if got := readCodewords(c)[:19]; !bytes.Equal(got, want) {
+		t.Errorf("data codewords\n got %x\nwant %x", got, want)
+	}
+}
+
+func readFormat(c *Code) int {
+	bits := 0
+	read := func(i, x, y int) {
+		if c.Dark(x, y) {
+			bits |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		read(i, 8, i)
+	}
+	read(6, 8, 7)
+	read(7, 8, 8)
+	read(8, 7, 8)
+	for i := 9; i < 15; i++ {
+		read(i, 14-i, 8)
+	}
+	return bits
+}
+
+// readCodewords reads the codewords of c in placement order, unmasked.
+func readCodewords(c *Code) []byte {
+	mask := (readFormat(c) ^ 0x5412) >> 10 & 7
+
+	function := newCode(c.Version, c.Level)
+	function.drawFunctionPatterns()
+
+	var bits bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for _, x := range []int{right, right - 1} {
+				if function.function[y*c.Size+x] {
+					continue
+				}
+				dark := c.Dark(x, y) != maskedAt(mask, y, x)
+				if dark {
+					bits.append(1, 1)
+				} else {
+					bits.append(0, 1)
+				}
+			}
+		}
+	}
+	return bits.bytes()[:rawModules(c.Version)/8]
+}
+
+// decode reads c back: it splits the codewords into blocks, checks their
+// error correction and parses the byte mode segment.
+func decode(c *Code) ([]byte, error) {
+	codewords := readCodewords(c)
+
+	blocks := eccBlocks[c.Level][c.Version]
+	eccLen := eccPerBlock[c.Level][c.Version]
+	short := blocks - len(codewords)%blocks
+	shortData := len(codewords)/blocks - eccLen
+
+	split := make([][]byte, blocks)
+	k := 0
+	for i := range shortData + 1 {
+		for j := range split {
+			if i < shortData || j >= short {
+				split[j] = append(split[j], codewords[k])
+				k++
+			}
+		}
+	}
+	for range eccLen {
+		for j := range split {
+			split[j] = append(split[j], codewords[k])
+			k++
+		}
+	}
+
+	var data []byte
+	for j, block := range split {
+		// A valid block is a multiple of the generator, so it vanishes at
+		// each of its roots.
+		root := byte(1)
+		for range eccLen {
+			var sum byte
+			for _, b := range block {
+				sum = gfMultiply(sum, root) ^ b
+			}
+			if sum != 0 {
+				return nil, fmt.Errorf("block %d fails its error correction", j)
+			}
+			root = gfMultiply(root, 2)
+		}
+		data = append(data, block[:len(block)-eccLen]...)
+	}
+
+	r := bitReader{data: data}
+	if mode := r.read(4); mode != 0b0100 {
+		return nil, errors.New("not byte mode")
+	}
+	n := r.read(countBits(c.Version))
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = byte(r.read(8))
+	}
+	return out, nil
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	v := 0
+	for range n {
+		v = v<<1 | int(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+func popcount(v int) int {
+	n := 0
+	for ; v != 0; v &= v - 1 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// DefaultMargin is the quiet zone the standard asks for, in modules.
+const DefaultMargin = 4
+
+// Style is how a code is drawn.
+type Style struct {
+	// Size is the width and height of the image in pixels. PNG modules are
+	// whole pixels, so the code is centred in any space left over; images
+	// too small for one pixel per module grow to fit.
+	Size int
+	// Margin is the quiet zone around the code, in modules.
+	Margin     int
+	Foreground color.NRGBA
+	Background color.NRGBA
+}
+
+// width is the number of modules across the image, quiet zone included.
+func (c *Code) width(s Style) int {
+	return c.Size + 2*s.Margin
+}
+
+// PNG draws c as a two colour PNG image.
+func (c *Code) PNG(s Style) ([]byte, error) {
+	modules := c.width(s)
+	scale := max(1, s.Size/modules)
+	size := max(s.Size, modules)
+	offset := (size-modules*scale)/2 + s.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{s.Background, s.Foreground})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for py := range scale {
+				row := img.Pix[(offset+y*scale+py)*img.Stride:]
+				for px := range scale {
+					row[offset+x*scale+px] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG draws c as an SVG image, one path for the dark modules on a
+// rectangle of the background colour.
+func (c *Code) SVG(s Style) []byte {
+	modules := c.width(s)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		s.Size, s.Size, modules, modules)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" %s/>`, svgFill(s.Background))
+	fmt.Fprintf(&b, `<path %s d="`, svgFill(s.Foreground))
+	for y := range c.Size {
+		for x := 0; x < c.Size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			// Draw runs of dark modules as one rectangle.
+			run := 1
+			for x+run < c.Size && c.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", x+s.Margin, y+s.Margin, run, run)
+			x += run - 1
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return []byte(b.String())
+}
+
+func svgFill(c color.NRGBA) string {
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, c.R, c.G, c.B)
+	if c.A != 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(c.A)/0xff, 'g', 3, 64))
+	}
+	return fill
+}
+
+// ParseColor parses a hex colour written as rgb, rgba, rrggbb or rrggbbaa,
+// with or without a leading #.
+func ParseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 || len(hex) == 4 {
+		short := hex
+		hex = ""
+		for i := range short {
+			hex += short[i:i+1] + short[i:i+1]
+		}
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
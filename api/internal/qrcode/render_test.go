@@ -0,0 +1,172 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+var (
+	black = color.NRGBA{A: 0xff}
+	white = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+func TestPNG(t *testing.T) {
+	c, err := Encode([]byte("https://example.com/short/abc1234"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules := c.Size + 2*DefaultMargin
+
+	for _, size := range []int{10, modules, 256, 1000} {
+		data, err := c.PNG(Style{Size: size, Margin: DefaultMargin, Foreground: black, Background: white})
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+
+		// Images too small for a pixel per module grow to fit.
+		want := max(size, modules)
+		if b := img.Bounds(); b.Dx() != want || b.Dy() != want {
+			t.Fatalf("size %d: image is %v", size, b)
+		}
+
+		// Sample the centre of every module.
+		scale := want / modules
+		offset := (want-modules*scale)/2 + DefaultMargin*scale
+		for y := range c.Size {
+			for x := range c.Size {
+				got := color.NRGBAModel.Convert(img.At(offset+x*scale+scale/2, offset+y*scale+scale/2))
+				want := white
+				if c.Dark(x, y) {
+					want = black
+				}
+				if got != want {
+					t.Fatalf("size %d: module %d,%d is %v", size, x, y, got)
+				}
+			}
+		}
+
+		// The quiet zone is left blank.
+		if got := color.NRGBAModel.Convert(img.At(offset-1, offset-1)); got != white {
+			t.Errorf("size %d: quiet zone is %v", size, got)
+		}
+	}
+}
+
+func TestPNGColours(t *testing.T) {
+	c, err := Encode([]byte("x"), Low)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fg := color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}
+	bg := color.NRGBA{R: 0xff, G: 0xee, B: 0xdd, A: 0x80}
+
+	data, err := c.PNG(Style{Size: 100, Margin: 0, Foreground: fg, Background: bg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The top left finder is dark in its corner and light inside.
+	scale := 100 / c.Size
+	offset := (100 - c.Size*scale) / 2
+	if got := color.NRGBAModel.Convert(img.At(offset, offset)); got != fg {
+		t.Errorf("foreground = %v, want %v", got, fg)
+	}
+	if got := color.NRGBAModel.Convert(img.At(offset+scale, offset+scale)); got != bg {
+		t.Errorf("background = %v, want %v", got, bg)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, err := Encode([]byte("https://example.com/short/abc1234"), Quartile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fg := color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x80}
+	svg := c.SVG(Style{Size: 300, Margin: 2, Foreground: fg, Background: white})
+
+	var doc struct {
+		Width   string `xml:"width,attr"`
+		ViewBox string `xml:"viewBox,attr"`
+		Rect    struct {
+			Fill string `xml:"fill,attr"`
+		} `xml:"rect"`
+		Path struct {
+			Fill    string `xml:"fill,attr"`
+			Opacity string `xml:"fill-opacity,attr"`
+			D       string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+	if err := xml.Unmarshal(svg, &doc); err != nil {
+		t.Fatalf("invalid SVG: %v\n%s", err, svg)
+	}
+
+	modules := c.Size + 4
+	if doc.Width != "300" || doc.ViewBox != fmt.Sprintf("0 0 %d %d", modules, modules) {
+		t.Errorf("width %q viewBox %q", doc.Width, doc.ViewBox)
+	}
+	if doc.Rect.Fill != "#ffffff" || doc.Path.Fill != "#112233" || doc.Path.Opacity != "0.502" {
+		t.Errorf("fills %q and %q, opacity %q", doc.Rect.Fill, doc.Path.Fill, doc.Path.Opacity)
+	}
+
+	// Paint the path back onto a grid; it must cover the dark modules
+	// exactly.
+	grid := make([]bool, modules*modules)
+	for _, cmd := range strings.Split(strings.TrimSuffix(doc.Path.D, "z"), "z") {
+		var x, y, run, back int
+		if _, err := fmt.Sscanf(cmd, "M%d %dh%dv1h-%d", &x, &y, &run, &back); err != nil || run != back {
+			t.Fatalf("unexpected path command %q", cmd)
+		}
+		for i := range run {
+			if grid[y*modules+x+i] {
+				t.Fatalf("module %d,%d drawn twice", x+i, y)
+			}
+			grid[y*modules+x+i] = true
+		}
+	}
+	for y := range modules {
+		for x := range modules {
+			inside := x >= 2 && y >= 2 && x < c.Size+2 && y < c.Size+2
+			if want := inside && c.Dark(x-2, y-2); grid[y*modules+x] != want {
+				t.Fatalf("module %d,%d painted %v, want %v", x, y, grid[y*modules+x], want)
+			}
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want color.NRGBA
+	}{
+		{"000", black},
+		{"#fff", white},
+		{"#1234", color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x44}},
+		{"a1b2c3", color.NRGBA{R: 0xa1, G: 0xb2, B: 0xc3, A: 0xff}},
+		{"#A1B2C380", color.NRGBA{R: 0xa1, G: 0xb2, B: 0xc3, A: 0x80}},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "#", "12", "12345", "gggggg", "#1234567", "-12345"} {
+		if _, err := ParseColor(in); err == nil {
+			t.Errorf("ParseColor(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// bucket is the persisted state of one token bucket.
+type bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result describes the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long to wait for the next token. It is zero when the
+	// request was allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// take refills b up to now and tries to take one token from it. A nil b is a
+// full bucket.
+func take(b *bucket, p Policy, now time.Time) (bucket, Result) {
+	state := bucket{Tokens: float64(p.Burst), Updated: now}
+	if b != nil {
+		elapsed := now.Sub(b.Updated).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		state.Tokens = math.Min(float64(p.Burst), b.Tokens+elapsed*p.rate())
+	}
+
+	allowed := state.Tokens >= 1
+	if allowed {
+		state.Tokens--
+	}
+	return state, outcome(p, state.Tokens, allowed)
+}
+
+// outcome describes a bucket left with tokens after a request was allowed or
+// refused.
+func outcome(p Policy, tokens float64, allowed bool) Result {
+	result := Result{Limit: p.Burst, Allowed: allowed}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / p.rate())
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = secondsToDuration((float64(p.Burst) - tokens) / p.rate())
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
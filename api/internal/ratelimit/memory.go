@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops buckets that refilled.
+const sweepInterval = time.Minute
+
+// Memory keeps buckets in process. Every instance of the API counts
+// separately, so use Redis when running several.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	fullAt time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]memoryBucket)}
+}
+
+func (m *Memory) Take(_ context.Context, key string, p Policy, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	var prev *bucket
+	if b, ok := m.buckets[key]; ok {
+		prev = &b.bucket
+	}
+
+	state, result := take(prev, p, now)
+	m.buckets[key] = memoryBucket{bucket: state, fullAt: now.Add(result.Reset)}
+	return result, nil
+}
+
+// sweep drops buckets that are full again; a missing bucket behaves the same.
+// The caller must hold m.mu.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if !now.Before(b.fullAt) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	p := Policy{Name: "test", Requests: 1, Period: time.Minute, Burst: 2}
+	start := time.Unix(1_700_000_000, 0)
+
+	// The first take sweeps, so the next sweep is a minute later.
+	m.Take(context.Background(), "a", p, start)
+	m.Take(context.Background(), "b", p, start)
+	m.Take(context.Background(), "b", p, start)
+
+	// a is full again after a minute, b not before two.
+	m.Take(context.Background(), "c", p, start.Add(time.Minute))
+	if _, ok := m.buckets["a"]; ok {
+		t.Error("full bucket a was kept")
+	}
+	if _, ok := m.buckets["b"]; !ok {
+		t.Error("bucket b was dropped before it refilled")
+	}
+
+	// b is full now, but no sweep is due yet.
+	m.Take(context.Background(), "c", p, start.Add(2*time.Minute-time.Second))
+	if _, ok := m.buckets["b"]; !ok {
+		t.Error("swept again within a minute")
+	}
+
+	m.Take(context.Background(), "c", p, start.Add(2*time.Minute))
+	if _, ok := m.buckets["b"]; ok {
+		t.Error("full bucket b was kept")
+	}
+	if len(m.buckets) != 1 {
+		t.Errorf("%d buckets left, want only c", len(m.buckets))
+	}
+
+	// A dropped bucket is full, as it would have been.
+	result, _ := m.Take(context.Background(), "b", p, start.Add(2*time.Minute))
+	if !result.Allowed || result.Remaining != p.Burst-1 {
+		t.Errorf("take after sweep = %+v, want a full bucket", result)
+	}
+}
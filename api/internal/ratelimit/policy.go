@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key sources a policy can count requests by.
+const (
+	// KeyIP counts requests per client IP.
+	KeyIP = "ip"
+	// KeyUser counts requests per authenticated user, falling back to the IP
+	// for anonymous requests.
+	KeyUser = "user"
+	// KeyCredential counts requests per API key, or per user for session
+	// logins, falling back to the IP for anonymous requests.
+	KeyCredential = "key"
+)
+
+// Policy is a token bucket: it holds up to Burst tokens and refills Requests
+// tokens every Period. Every request takes one token.
+type Policy struct {
+	Name     string
+	Requests int
+	Period   time.Duration
+	Burst    int
+	By       string
+}
+
+// rate is the refill rate in tokens per second.
+func (p Policy) rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+func (p Policy) String() string {
+	return fmt.Sprintf("%s=%d/%s burst=%d by=%s", p.Name, p.Requests, p.Period, p.Burst, p.By)
+}
+
+// ParsePolicies parses a semicolon separated list of policies such as
+//
+//	login=10/1m by=ip; shorten=60/1m burst=20 by=key
+//
+// Burst defaults to the number of requests and by defaults to ip.
+func ParsePolicies(spec string) (map[string]Policy, error) {
+	policies := make(map[string]Policy)
+
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		p, err := parsePolicy(item)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit %q: %w", item, err)
+		}
+		if _, ok := policies[p.Name]; ok {
+			return nil, fmt.Errorf("rate limit %q: duplicate policy %s", item, p.Name)
+		}
+		policies[p.Name] = p
+	}
+
+	return policies, nil
+}
+
+func parsePolicy(item string) (Policy, error) {
+	name, rest, ok := strings.Cut(item, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return Policy{}, fmt.Errorf("expected name=requests/period")
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return Policy{}, fmt.Errorf("missing requests/period")
+	}
+
+	p := Policy{Name: name, By: KeyIP}
+
+	requests, period, ok := strings.Cut(fields[0], "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("expected requests/period, got %q", fields[0])
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Policy{}, fmt.Errorf("invalid request count %q", requests)
+	}
+	p.Requests = n
+
+	// Accept a bare unit such as "m" as shorthand for "1m".
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	if p.Period, err = time.ParseDuration(period); err != nil || p.Period <= 0 {
+		return Policy{}, fmt.Errorf("invalid period %q", period)
+	}
+
+	for _, field := range fields[1:] {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "burst":
+			if p.Burst, err = strconv.Atoi(value); err != nil || p.Burst <= 0 {
+				return Policy{}, fmt.Errorf("invalid burst %q", value)
+			}
+		case "by":
+			switch value {
+			case KeyIP, KeyUser, KeyCredential:
+				p.By = value
+			default:
+				return Policy{}, fmt.Errorf("invalid key source %q, expected ip, user or key", value)
+			}
+		default:
+			return Policy{}, fmt.Errorf("unknown option %q", field)
+		}
+	}
+
+	if p.Burst == 0 {
+		p.Burst = p.Requests
+	}
+	return p, nil
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(" login=10/m by=ip ; shorten=60/1m burst=20 by=key;; api=5/30s by=user ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Policy{
+		"login":   {Name: "login", Requests: 10, Period: time.Minute, Burst: 10, By: KeyIP},
+		"shorten": {Name: "shorten", Requests: 60, Period: time.Minute, Burst: 20, By: KeyCredential},
+		"api":     {Name: "api", Requests: 5, Period: 30 * time.Second, Burst: 5, By: KeyUser},
+	}
+	if len(policies) != len(want) {
+		t.Fatalf("parsed %d policies, want %d: %v", len(policies), len(want), policies)
+	}
+	for name, p := range want {
+		if policies[name] != p {
+			t.Errorf("%s = %v, want %v", name, policies[name], p)
+		}
+	}
+}
+
+func TestParsePoliciesDefaults(t *testing.T) {
+	policies, err := ParsePolicies("login=3/h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Policy{Name: "login", Requests: 3, Period: time.Hour, Burst: 3, By: KeyIP}
+	if policies["login"] != want {
+		t.Errorf("login = %v, want %v", policies["login"], want)
+	}
+
+	if policies, err := ParsePolicies(" ; "); err != nil || len(policies) != 0 {
+		t.Errorf("empty spec = %v, %v", policies, err)
+	}
+}
+
+func TestParsePoliciesInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"login",
+		"=10/m",
+		"login=",
+		"login=10",
+		"login=0/m",
+		"login=x/m",
+		"login=10/",
+		"login=10/0s",
+		"login=10/-1m",
+		"login=10/fortnight",
+		"login=10/m burst=0",
+		"login=10/m burst=x",
+		"login=10/m by=session",
+		"login=10/m per=ip",
+		"login=10/m; login=20/h",
+	} {
+		if policies, err := ParsePolicies(spec); err == nil {
+			t.Errorf("ParsePolicies(%q) = %v, want an error", spec, policies)
+		}
+	}
+}
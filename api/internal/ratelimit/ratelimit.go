@@ -0,0 +1,39 @@
+// Package ratelimit throttles requests with token buckets kept in memory or
+// in a Redis compatible server.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps token buckets.
+type Store interface {
+	// Take takes a token from the bucket stored under key.
+	Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error)
+}
+
+// Limiter applies named policies.
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{store: store, policies: policies}
+}
+
+// Policy returns the named policy. Routes without a configured policy are
+// not limited.
+func (l *Limiter) Policy(name string) (Policy, bool) {
+	if l == nil {
+		return Policy{}, false
+	}
+	p, ok := l.policies[name]
+	return p, ok
+}
+
+// Take takes a token from the bucket of the subject under policy p.
+func (l *Limiter) Take(ctx context.Context, p Policy, subject string) (Result, error) {
+	return l.store.Take(ctx, "ratelimit:"+p.Name+":"+subject, p, time.Now())
+}
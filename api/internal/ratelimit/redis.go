@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-api/internal/cache"
+	"go-api/internal/resp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// takeScript is take run by the server, so concurrent requests for one
+// bucket cannot both spend its last token. Buckets are stored as
+// "tokens:updated", updated in Unix microseconds, and kept a second longer
+// than they take to refill; after that a missing bucket is equivalent.
+//
+// KEYS[1] is the bucket, ARGV the burst, the rate in tokens per second and
+// now in Unix microseconds. The reply is whether the request is allowed and
+// the tokens left, as a string since integer replies would truncate them.
+const takeScript = `
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local tokens = burst
+local value = redis.call('GET', KEYS[1])
+if value then
+  local sep = string.find(value, ':', 1, true)
+  if sep then
+    local prev = tonumber(string.sub(value, 1, sep - 1))
+    local updated = tonumber(string.sub(value, sep + 1))
+    if prev and updated then
+      local elapsed = math.max(0, now - updated) / 1e6
+      tokens = math.min(burst, prev + elapsed * rate)
+    end
+  end
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+local left = string.format('%.17g', tokens)
+local ttl = math.ceil((burst - tokens) / rate * 1000) + 1000
+redis.call('SET', KEYS[1], left .. ':' .. ARGV[3], 'PX', ttl)
+return {allowed, left}
+`
+
+var takeScriptSHA = func() string {
+	sum := sha1.Sum([]byte(takeScript))
+	return hex.EncodeToString(sum[:])
+}()
+
+// Redis keeps buckets in a Redis compatible server so that every instance of
+// the API shares them. Buckets are updated by a Lua script, which the server
+// runs atomically.
+type Redis struct {
+	client *cache.Redis
+}
+
+func NewRedis(client *cache.Redis) *Redis {
+	return &Redis{client: client}
+}
+
+func (s *Redis) Take(ctx context.Context, key string, p Policy, now time.Time) (Result, error) {
+	args := []string{
+		"1", s.client.Key(key),
+		strconv.Itoa(p.Burst),
+		strconv.FormatFloat(p.rate(), 'g', -1, 64),
+		strconv.FormatInt(now.UnixMicro(), 10),
+	}
+
+	// The script is sent in full only when the server has not cached it.
+	reply, err := s.client.Do(ctx, append([]string{"EVALSHA", takeScriptSHA}, args...)...)
+	var replyErr resp.Error
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		reply, err = s.client.Do(ctx, append([]string{"EVAL", takeScript}, args...)...)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, tokens, err := parseTakeReply(reply)
+	if err != nil {
+		return Result{}, err
+	}
+	return outcome(p, tokens, allowed), nil
+}
+
+func parseTakeReply(reply any) (bool, float64, error) {
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("ratelimit: unexpected script reply %v", reply)
+	}
+
+	allowed, ok := values[0].(int64)
+	left, isBulk := values[1].([]byte)
+	if !ok || !isBulk {
+		return false, 0, fmt.Errorf("ratelimit: unexpected script reply %v", reply)
+	}
+
+	tokens, err := strconv.ParseFloat(string(left), 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("ratelimit: malformed token count %q", left)
+	}
+	return allowed == 1, tokens, nil
+}
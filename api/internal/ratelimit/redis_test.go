@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"go-api/internal/cache"
+	"go-api/internal/cache/redistest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// takeStandIn does what takeScript does, for the test server that cannot
+// run Lua. Tests using it cover the client side of the store: loading the
+// script, running it by hash and parsing the reply. The script itself only
+// runs in TestRedisScript.
+func takeStandIn(kv *redistest.KV, keys, args []string) any {
+	burst, _ := strconv.Atoi(args[0])
+	rate, _ := strconv.ParseFloat(args[1], 64)
+	micros, _ := strconv.ParseInt(args[2], 10, 64)
+	p := Policy{Requests: 1, Period: time.Duration(float64(time.Second) / rate), Burst: burst}
+
+	var prev *bucket
+	if value, ok := kv.Get(keys[0]); ok {
+		tokens, updated, _ := strings.Cut(string(value), ":")
+		t, _ := strconv.ParseFloat(tokens, 64)
+		u, _ := strconv.ParseInt(updated, 10, 64)
+		prev = &bucket{Tokens: t, Updated: time.UnixMicro(u)}
+	}
+
+	state, result := take(prev, p, time.UnixMicro(micros))
+	left := strconv.FormatFloat(state.Tokens, 'g', 17, 64)
+	kv.Set(keys[0], []byte(left+":"+args[2]), result.Reset+time.Second)
+
+	var allowed int64
+	if result.Allowed {
+		allowed = 1
+	}
+	return []any{allowed, left}
+}
+
+func newRedisStore(t *testing.T) (*Redis, *redistest.Server) {
+	t.Helper()
+
+	srv, err := redistest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	srv.Script(takeScript, takeStandIn)
+
+	client := cache.NewRedis(cache.RedisOptions{Addr: srv.Addr(), PoolSize: 16})
+	t.Cleanup(func() { client.Close() })
+	return NewRedis(client), srv
+}
+
+// matchesMemory checks that store allows the same requests as the memory
+// store and reports the same tokens left, and returns how many takes it made.
+func matchesMemory(t *testing.T, store Store) int {
+	t.Helper()
+
+	memory := NewMemory()
+	p := Policy{Name: "test", Requests: 2, Period: time.Second, Burst: 3}
+
+	start := time.Unix(1_700_000_000, 0)
+	steps := []time.Duration{0, 0, 0, 0, 100 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond, 3 * time.Second}
+	now := start
+	for i, step := range steps {
+		now = now.Add(step)
+		want, err := memory.Take(context.Background(), "k", p, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.Take(context.Background(), "k", p, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("take %d: redis %+v, memory %+v", i, got, want)
+		}
+	}
+	return len(steps)
+}
+
+func TestRedisMatchesMemory(t *testing.T) {
+	store, srv := newRedisStore(t)
+	takes := matchesMemory(t, store)
+
+	// The script is sent once, then run by its hash.
+	if n := srv.CommandCount("EVAL"); n != 1 {
+		t.Errorf("EVAL sent %d times, want 1", n)
+	}
+	if n := srv.CommandCount("EVALSHA"); n != takes {
+		t.Errorf("EVALSHA sent %d times, want %d", n, takes)
+	}
+}
+
+// TestRedisScript runs takeScript on a real server, set by TEST_REDIS_ADDR.
+// Its keys are prefixed and removed afterwards.
+func TestRedisScript(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+
+	client := cache.NewRedis(cache.RedisOptions{
+		Addr:        addr,
+		Password:    os.Getenv("TEST_REDIS_PASSWORD"),
+		Prefix:      fmt.Sprintf("ratelimit-test-%d:", time.Now().UnixNano()),
+		DialTimeout: time.Second,
+	})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()); err != nil {
+		t.Skipf("redis at %s is not reachable: %v", addr, err)
+	}
+	t.Cleanup(func() { client.Delete(context.Background(), "k") })
+
+	// Drop a cached copy so that the EVAL fallback runs too.
+	if _, err := client.Do(context.Background(), "SCRIPT", "FLUSH"); err != nil {
+		t.Fatal(err)
+	}
+	matchesMemory(t, NewRedis(client))
+}
+
+func TestRedisConcurrentTakes(t *testing.T) {
+	store, _ := newRedisStore(t)
+	p := Policy{Name: "test", Requests: 1, Period: time.Hour, Burst: 10}
+	now := time.Now()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+		errs    []error
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := store.Take(context.Background(), "k", p, now)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			} else if result.Allowed {
+				allowed++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatalf("takes failed: %v", errs)
+	}
+	if allowed != p.Burst {
+		t.Errorf("%d of 50 concurrent requests allowed, want %d", allowed, p.Burst)
+	}
+}
+
+func TestParseTakeReply(t *testing.T) {
+	for _, reply := range []any{nil, []any{int64(1)}, []any{"1", []byte("2")}, []any{int64(1), []byte("x")}} {
+		if _, _, err := parseTakeReply(reply); err == nil {
+			t.Errorf("parseTakeReply(%s) succeeded", fmt.Sprint(reply))
+		}
+	}
+
+	allowed, tokens, err := parseTakeReply([]any{int64(1), []byte("2.5")})
+	if err != nil || !allowed || tokens != 2.5 {
+		t.Errorf("parseTakeReply = %v, %v, %v", allowed, tokens, err)
+	}
+}
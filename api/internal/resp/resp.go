@@ -0,0 +1,159 @@
+// Package resp implements the subset of the Redis serialization protocol
+// (RESP2) needed to talk to Redis compatible servers.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// SimpleString is a status reply such as OK or PONG.
+type SimpleString string
+
+var ErrProtocol = errors.New("resp: protocol error")
+
+// maxBulkLength guards against allocating absurd buffers on corrupt input.
+const maxBulkLength = 512 << 20
+
+// ReadValue reads a single reply. Bulk strings are returned as []byte,
+// integers as int64, arrays as []any and nil bulk strings or arrays as nil.
+// Error replies are returned as a value of type Error, not as err.
+func ReadValue(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, ErrProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return SimpleString(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil || n > maxBulkLength {
+			return nil, ErrProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[n] != '\r' || buf[n+1] != '\n' {
+			return nil, ErrProtocol
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = ReadValue(r); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, ErrProtocol
+	}
+}
+
+// ReadCommand reads a client command, which is always an array of bulk
+// strings.
+func ReadCommand(r *bufio.Reader) ([]string, error) {
+	value, err := ReadValue(r)
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		return nil, ErrProtocol
+	}
+
+	args := make([]string, len(items))
+	for i, item := range items {
+		b, ok := item.([]byte)
+		if !ok {
+			return nil, ErrProtocol
+		}
+		args[i] = string(b)
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, ErrProtocol
+	}
+	return line[:len(line)-2], nil
+}
+
+// WriteCommand encodes args as an array of bulk strings.
+func WriteCommand(w *bufio.Writer, args ...string) error {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		WriteBulk(w, []byte(arg))
+	}
+	return w.Flush()
+}
+
+func WriteSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s)
+}
+
+func WriteError(w *bufio.Writer, msg string) {
+	fmt.Fprintf(w, "-%s\r\n", msg)
+}
+
+func WriteInt(w *bufio.Writer, n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func WriteBulk(w *bufio.Writer, b []byte) {
+	fmt.Fprintf(w, "$%d\r\n", len(b))
+	w.Write(b)
+	w.WriteString("\r\n")
+}
+
+func WriteNil(w *bufio.Writer) {
+	w.WriteString("$-1\r\n")
+}
+
+func WriteNilArray(w *bufio.Writer) {
+	w.WriteString("*-1\r\n")
+}
+
+func WriteArrayHeader(w *bufio.Writer, n int) {
+	fmt.Fprintf(w, "*%d\r\n", n)
+}
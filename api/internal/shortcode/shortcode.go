@@ -0,0 +1,120 @@
+package shortcode
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+const (
+	alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	// DefaultLength is the length of generated codes. 62^7 leaves plenty of
+	// room before collisions become a concern.
+	DefaultLength = 7
+
+	MinSlugLength = 3
+	MaxSlugLength = 64
+)
+
+var (
+	ErrSlugLength   = errors.New("slug must be between 3 and 64 characters")
+	ErrSlugChars    = errors.New("slug may only contain letters, digits, '-' and '_' and must start with a letter or digit")
+	ErrSlugNumeric  = errors.New("slug cannot be purely numeric")
+	ErrSlugReserved = errors.New("slug is reserved")
+
+	slugPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+)
+
+// reserved holds words that cannot be claimed as vanity slugs because they
+// collide with routes or are likely to confuse visitors.
+var reserved = map[string]struct{}{
+	"admin":    {},
+	"api":      {},
+	"auth":     {},
+	"bulk":     {},
+	"export":   {},
+	"health":   {},
+	"healthz":  {},
+	"help":     {},
+	"login":    {},
+	"logout":   {},
+	"metrics":  {},
+	"ping":     {},
+	"qr":       {},
+	"readyz":   {},
+	"register": {},
+	"short":    {},
+	"static":   {},
+	"stats":    {},
+	"status":   {},
+}
+
+// Generate returns a random base62 code of the given length. Codes always
+// contain at least one letter so they can never be mistaken for a legacy
+// numeric ID.
+func Generate(length int) (string, error) {
+	if length < 2 {
+		length = DefaultLength
+	}
+
+	max := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, length)
+	for {
+		for i := range code {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			code[i] = alphabet[n.Int64()]
+		}
+
+		if !IsNumeric(string(code)) {
+			return string(code), nil
+		}
+	}
+}
+
+// ValidateSlug checks that a user chosen slug is usable as a short code.
+func ValidateSlug(slug string) error {
+	if len(slug) < MinSlugLength || len(slug) > MaxSlugLength {
+		return ErrSlugLength
+	}
+
+	if !slugPattern.MatchString(slug) {
+		return ErrSlugChars
+	}
+
+	if IsNumeric(slug) {
+		return ErrSlugNumeric
+	}
+
+	if IsReserved(slug) {
+		return ErrSlugReserved
+	}
+
+	return nil
+}
+
+// IsReserved reports whether slug is on the reserved word list.
+func IsReserved(slug string) bool {
+	_, ok := reserved[strings.ToLower(slug)]
+	return ok
+}
+
+// IsNumeric reports whether s only contains ASCII digits. Such codes are
+// treated as legacy numeric IDs.
+func IsNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package shortcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, length := range []int{2, 3, DefaultLength, 20} {
+		for range 200 {
+			code, err := Generate(length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(code) != length {
+				t.Fatalf("Generate(%d) = %q", length, code)
+			}
+			if IsNumeric(code) {
+				t.Fatalf("Generate(%d) = %q, which is numeric", length, code)
+			}
+			if strings.Trim(code, alphabet) != "" {
+				t.Fatalf("Generate(%d) = %q, which is not base62", length, code)
+			}
+		}
+	}
+
+	// Lengths too short to hold a letter fall back to the default.
+	for _, length := range []int{-1, 0, 1} {
+		if code, err := Generate(length); err != nil || len(code) != DefaultLength {
+			t.Errorf("Generate(%d) = %q, %v", length, code, err)
+		}
+	}
+}
+
+func TestValidateSlug(t *testing.T) {
+	for _, tt := range []struct {
+		slug string
+		want error
+	}{
+		{"abc", nil},
+		{"My-Page_2", nil},
+		{"2024-report", nil},
+		{"a1", ErrSlugLength},
+		{strings.Repeat("a", MaxSlugLength), nil},
+		{strings.Repeat("a", MaxSlugLength+1), ErrSlugLength},
+		{"-abc", ErrSlugChars},
+		{"_abc", ErrSlugChars},
+		{"ab c", ErrSlugChars},
+		{"ab/c", ErrSlugChars},
+		{"abc.html", ErrSlugChars},
+		{"café", ErrSlugChars},
+		{"12345", ErrSlugNumeric},
+		{"api", ErrSlugReserved},
+		{"Admin", ErrSlugReserved},
+		{"HEALTHZ", ErrSlugReserved},
+		{"apis", nil},
+	} {
+		if err := ValidateSlug(tt.slug); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateSlug(%q) = %v, want %v", tt.slug, err, tt.want)
+		}
+	}
+}
+
+func TestReservedSlugs(t *testing.T) {
+	// Every reserved word must be a slug that would otherwise be accepted,
+	// or listing it has no effect.
+	for word := range reserved {
+		if word != strings.ToLower(word) {
+			t.Errorf("reserved word %q is not lower case", word)
+		}
+		if err := ValidateSlug(word); len(word) >= MinSlugLength && !errors.Is(err, ErrSlugReserved) {
+			t.Errorf("ValidateSlug(%q) = %v, want %v", word, err, ErrSlugReserved)
+		}
+	}
+}
+
+func TestIsNumeric(t *testing.T) {
+	for s, want := range map[string]bool{"": false, "0": true, "123": true, "12a": false, "-1": false, "１２": false} {
+		if got := IsNumeric(s); got != want {
+			t.Errorf("IsNumeric(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
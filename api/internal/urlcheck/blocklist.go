@@ -0,0 +1,73 @@
+package urlcheck
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// Blocklist holds domains whose links may not be shortened. A listed domain
+// also blocks all of its subdomains.
+type Blocklist struct {
+	domains map[string]struct{}
+}
+
+// LoadBlocklist reads a blocklist file with one domain per line. Blank lines
+// and lines starting with '#' are ignored.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseBlocklist(file)
+}
+
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	b := &Blocklist{domains: make(map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		domain, err := normalizeHost(strings.TrimPrefix(line, "*."))
+		if err != nil {
+			continue
+		}
+		b.domains[domain] = struct{}{}
+	}
+
+	return b, scanner.Err()
+}
+
+// Blocks reports whether host or one of its parent domains is listed.
+func (b *Blocklist) Blocks(host string) bool {
+	if b == nil || len(b.domains) == 0 {
+		return false
+	}
+
+	for {
+		if _, ok := b.domains[host]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+// Len returns the number of listed domains.
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.domains)
+}
@@ -0,0 +1,38 @@
+package urlcheck
+
+import "errors"
+
+// Error codes returned in Error.Code. They are part of the API and must not
+// change once published.
+const (
+	CodeEmpty            = "empty"
+	CodeMalformed        = "malformed"
+	CodeNotAbsolute      = "not_absolute"
+	CodeSchemeNotAllowed = "scheme_not_allowed"
+	CodeInvalidHost      = "invalid_host"
+	CodeUserinfo         = "userinfo_not_allowed"
+	CodeTooLong          = "too_long"
+	CodeBlockedDomain    = "blocked_domain"
+	CodeSelfReference    = "self_reference"
+	CodeRedirectLoop     = "redirect_loop"
+	CodeTooManyRedirects = "too_many_redirects"
+)
+
+// ErrHostLookup wraps failures to find out whether a URL points at one of
+// our custom domains. The URL is neither accepted nor at fault.
+var ErrHostLookup = errors.New("looking up the url host failed")
+
+// Error describes why a destination URL was rejected.
+type Error struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, message string) *Error {
+	return &Error{Field: "url", Code: code, Message: message}
+}
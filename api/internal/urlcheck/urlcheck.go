@@ -0,0 +1,339 @@
+// Package urlcheck normalizes destination URLs and screens them before they
+// are stored as short link targets.
+package urlcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+const maxURLLength = 2048
+
+type Options struct {
+	// AllowedSchemes defaults to http and https.
+	AllowedSchemes []string
+	// SelfHosts are the hosts serving short links. URLs pointing back at
+	// /short/ on one of them would redirect to ourselves.
+	SelfHosts []string
+	// Hosts finds the other hosts serving short links, the verified custom
+	// domains.
+	Hosts     HostResolver
+	Blocklist *Blocklist
+	// FollowRedirects makes Validate request the destination and walk its
+	// redirect chain to detect loops and hops through blocked or own hosts.
+	FollowRedirects bool
+	MaxRedirects    int
+	Timeout         time.Duration
+	// Client is used to follow redirects. It defaults to a client that
+	// refuses to connect to private and loopback addresses.
+	Client *http.Client
+}
+
+// HostResolver returns the ID of the custom domain serving host, or 0 when
+// it is not one. *model.ShortLinkCache satisfies it.
+type HostResolver interface {
+	ResolveHost(ctx context.Context, host string) (uint, error)
+}
+
+type Validator struct {
+	schemes   map[string]bool
+	selfHosts map[string]bool
+	hosts     HostResolver
+	blocklist *Blocklist
+
+	followRedirects bool
+	maxRedirects    int
+	timeout         time.Duration
+	client          *http.Client
+}
+
+func New(opts Options) *Validator {
+	if len(opts.AllowedSchemes) == 0 {
+		opts.AllowedSchemes = []string{"http", "https"}
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	v := &Validator{
+		schemes:         make(map[string]bool, len(opts.AllowedSchemes)),
+		selfHosts:       make(map[string]bool, len(opts.SelfHosts)),
+		hosts:           opts.Hosts,
+		blocklist:       opts.Blocklist,
+		followRedirects: opts.FollowRedirects,
+		maxRedirects:    opts.MaxRedirects,
+		timeout:         opts.Timeout,
+		client:          opts.Client,
+	}
+
+	for _, scheme := range opts.AllowedSchemes {
+		v.schemes[strings.ToLower(strings.TrimSpace(scheme))] = true
+	}
+	for _, host := range opts.SelfHosts {
+		if host, err := normalizeHost(stripPort(host)); err == nil {
+			v.selfHosts[host] = true
+		}
+	}
+
+	if v.client == nil {
+		v.client = &http.Client{
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{Timeout: v.timeout, Control: denyPrivateAddresses}).DialContext,
+				Proxy:       nil,
+			},
+		}
+	}
+
+	return v
+}
+
+// Normalize checks the syntax of raw and returns its canonical form: lower
+// case scheme and host, IDNA host names converted to ASCII, default ports
+// dropped and an empty path replaced by "/".
+func (v *Validator) Normalize(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, newError(CodeEmpty, "url is required")
+	}
+	if len(raw) > maxURLLength {
+		return nil, newError(CodeTooLong, "url must be at most 2048 characters")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, newError(CodeMalformed, "url could not be parsed")
+	}
+
+	if u.Scheme == "" || u.Opaque != "" && u.Host == "" {
+		if u.Scheme != "" && !v.schemes[strings.ToLower(u.Scheme)] {
+			return nil, newError(CodeSchemeNotAllowed, "url scheme "+strings.ToLower(u.Scheme)+" is not allowed")
+		}
+		return nil, newError(CodeNotAbsolute, "url must be absolute, including scheme and host")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !v.schemes[u.Scheme] {
+		return nil, newError(CodeSchemeNotAllowed, "url scheme "+u.Scheme+" is not allowed")
+	}
+
+	if u.User != nil {
+		return nil, newError(CodeUserinfo, "url must not contain credentials")
+	}
+
+	if u.Host == "" {
+		return nil, newError(CodeNotAbsolute, "url must be absolute, including scheme and host")
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return nil, newError(CodeInvalidHost, "url host is invalid")
+	}
+
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	if port != "" || strings.Contains(host, ":") {
+		u.Host = net.JoinHostPort(host, port)
+		if port == "" {
+			u.Host = "[" + host + "]"
+		}
+	} else {
+		u.Host = host
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u, nil
+}
+
+// ValidateStatic normalizes raw and applies every check that does not need
+// the network. requestHost is the host the API was reached on and always
+// counts as one of our own hosts.
+func (v *Validator) ValidateStatic(ctx context.Context, raw, requestHost string) (string, error) {
+	u, err := v.Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if err := v.checkTarget(ctx, u, requestHost); err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
+
+// Validate runs ValidateStatic and, when enabled, follows the redirect chain
+// of the destination.
+func (v *Validator) Validate(ctx context.Context, raw, requestHost string) (string, error) {
+	normalized, err := v.ValidateStatic(ctx, raw, requestHost)
+	if err != nil {
+		return "", err
+	}
+
+	if v.followRedirects {
+		if err := v.checkRedirects(ctx, normalized, requestHost); err != nil {
+			return "", err
+		}
+	}
+
+	return normalized, nil
+}
+
+func (v *Validator) checkTarget(ctx context.Context, u *url.URL, requestHost string) error {
+	host := u.Hostname()
+
+	if v.blocklist.Blocks(host) {
+		return newError(CodeBlockedDomain, "links to "+host+" are not allowed")
+	}
+
+	if u.Path == "/short" || strings.HasPrefix(u.Path, "/short/") {
+		self, err := v.isSelf(ctx, host, requestHost)
+		if err != nil {
+			return err
+		}
+		if self {
+			return newError(CodeSelfReference, "url must not point to another short link")
+		}
+	}
+
+	return nil
+}
+
+// isSelf reports whether host serves our short links: it is one of the
+// configured hosts, the one the request came in on, or a verified custom
+// domain.
+func (v *Validator) isSelf(ctx context.Context, host, requestHost string) (bool, error) {
+	if v.selfHosts[host] {
+		return true, nil
+	}
+
+	if requestHost != "" {
+		if self, err := normalizeHost(stripPort(requestHost)); err == nil && self == host {
+			return true, nil
+		}
+	}
+
+	if v.hosts == nil {
+		return false, nil
+	}
+	domainID, err := v.hosts.ResolveHost(ctx, host)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrHostLookup, err)
+	}
+	return domainID != 0, nil
+}
+
+// checkRedirects walks the redirect chain with HEAD requests. Unreachable
+// destinations are accepted: only chains we can observe are judged.
+func (v *Validator) checkRedirects(ctx context.Context, target, requestHost string) error {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	client := *v.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	visited := map[string]bool{target: true}
+	current := target
+
+	for hop := 0; hop < v.maxRedirects; hop++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, current, nil)
+		if err != nil {
+			return nil
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil
+		}
+		res.Body.Close()
+
+		if res.StatusCode < 300 || res.StatusCode >= 400 {
+			return nil
+		}
+
+		location, err := res.Location()
+		if err != nil {
+			return nil
+		}
+
+		next, err := v.Normalize(location.String())
+		if err != nil {
+			// Redirects to schemes we do not allow are as bad as linking
+			// to them directly.
+			var checkErr *Error
+			if errors.As(err, &checkErr) && checkErr.Code == CodeSchemeNotAllowed {
+				return checkErr
+			}
+			return nil
+		}
+
+		if err := v.checkTarget(ctx, next, requestHost); err != nil {
+			return err
+		}
+
+		if visited[next.String()] {
+			return newError(CodeRedirectLoop, "url redirects in a loop")
+		}
+		visited[next.String()] = true
+		current = next.String()
+	}
+
+	return newError(CodeTooManyRedirects, "url redirects too many times")
+}
+
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" {
+		return "", errors.New("empty host")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	return ascii, nil
+}
+
+func stripPort(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+// denyPrivateAddresses keeps redirect checks from probing the internal
+// network.
+func denyPrivateAddresses(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return errors.New("refusing to connect to non-public address " + host)
+	}
+	return nil
+}
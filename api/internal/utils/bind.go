@@ -1,37 +1,158 @@
 package utils
 
 import (
-	"net/http"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api/internal/apperr"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
 // GetBody extracts and validates the request body into a struct T
-func GetBody[T any](c *gin.Context) (T, bool) {
+func GetBody[T any](c *gin.Context) (T, error) {
+	registerFieldNames()
 	var body T
 	if err := c.ShouldBindJSON(&body); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
-		return body, false
+		return body, bindError(err, apperr.CodeInvalidBody, "Invalid request body")
 	}
-	return body, true
+	return body, nil
 }
 
 // GetParams extracts and validates URL parameters into a struct T
-func GetParams[T any](c *gin.Context) (T, bool) {
+func GetParams[T any](c *gin.Context) (T, error) {
+	registerFieldNames()
 	var params T
 	if err := c.ShouldBindUri(&params); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid path parameters: " + err.Error()})
-		return params, false
+		return params, bindError(err, apperr.CodeInvalidPath, "Invalid path parameters")
 	}
-	return params, true
+	return params, nil
 }
 
 // GetSearchParams extracts and validates query parameters into a struct T
-func GetSearchParams[T any](c *gin.Context) (T, bool) {
+func GetSearchParams[T any](c *gin.Context) (T, error) {
+	registerFieldNames()
 	var queryParams T
 	if err := c.ShouldBindQuery(&queryParams); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters: " + err.Error()})
-		return queryParams, false
+		return queryParams, bindError(err, apperr.CodeInvalidQuery, "Invalid query parameters")
+	}
+	return queryParams, nil
+}
+
+// bindError describes why binding failed. Failed validations list every
+// rejected field by the name the client sent it as.
+func bindError(err error, code, message string) *apperr.Error {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields := make([]apperr.FieldError, len(invalid))
+		for i, fe := range invalid {
+			fields[i] = apperr.FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: validationMessage(fe),
+			}
+		}
+		return apperr.Validation(fields...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return apperr.Validation(apperr.FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be " + jsonKind(typeErr.Type),
+		})
+	}
+
+	if errors.Is(err, io.EOF) {
+		return apperr.BadRequest(code, message+": the body is empty")
+	}
+	return apperr.BadRequest(code, message+": "+err.Error())
+}
+
+var fieldNamesOnce sync.Once
+
+// registerFieldNames makes the validator report fields by their json,
+// form or uri name instead of the Go field name. It must run before the
+// first validation, which caches the names.
+func registerFieldNames() {
+	fieldNamesOnce.Do(func() {
+		v, ok := binding.Validator.Engine().(*validator.Validate)
+		if !ok {
+			return
+		}
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	})
+}
+
+// fieldPath is the namespace of the field without the name of the bound
+// struct, like scopes[0].
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func validationMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, param)
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("must have %s %s items", bound, param)
+		default:
+			return fmt.Sprintf("must be %s %s", bound, param)
+		}
+	default:
+		return "is invalid"
+	}
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
 	}
-	return queryParams, true
 }
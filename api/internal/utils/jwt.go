@@ -1,33 +1,58 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
-	"go-api/internal/env"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var (
-	secretKey       = []byte(env.GetString("JWT_SECRET_KEY", ""))
-	tokenExpiration = time.Duration(env.GetInt("JWT_TOKEN_EXPIRATION", int(time.Hour*10))) // Default to 10 hours
-)
+const defaultAccessTokenTTL = 15 * time.Minute
 
 type JWTClaims struct {
-	UserID int   `json:"user_id"`
-	Exp    int64 `json:"exp"`
-	Iat    int64 `json:"iat"`
-	Nbf    int64 `json:"nbf"`
+	UserID    int    `json:"user_id"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a JWT for a given user ID
-func GenerateJWT(userID uint) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(tokenExpiration).Unix(),
-		"iat":     time.Now().Unix(),
-		"nbf":     time.Now().Unix(),
+var (
+	secretKey      []byte
+	accessTokenTTL = defaultAccessTokenTTL
+)
+
+var errNoSecretKey = errors.New("JWT secret key is not configured")
+
+// ConfigureJWT sets the key tokens are signed with and the lifetime of
+// access tokens. It must be called at startup, before tokens are issued or
+// validated; without a key both fail.
+func ConfigureJWT(secret string, ttl time.Duration) {
+	secretKey = []byte(secret)
+	if ttl > 0 {
+		accessTokenTTL = ttl
+	}
+}
+
+// AccessTokenTTL is the lifetime of access tokens.
+func AccessTokenTTL() time.Duration {
+	return accessTokenTTL
+}
+
+// GenerateJWT creates a short-lived access token for a user's session
+func GenerateJWT(userID uint, sessionID string) (string, error) {
+	now := time.Now()
+	claims := JWTClaims{
+		UserID:    int(userID),
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	if len(secretKey) == 0 {
+		return "", errNoSecretKey
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -37,6 +62,10 @@ func GenerateJWT(userID uint) (string, error) {
 // ValidateJWT checks the validity of a JWT token
 
 func ValidateJWT(tokenString string) (*JWTClaims, error) {
+	if len(secretKey) == 0 {
+		return nil, errNoSecretKey
+	}
+
 	claims := &JWTClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -44,7 +73,7 @@ func ValidateJWT(tokenString string) (*JWTClaims, error) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return secretKey, nil
-	})
+	}, jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Job is work done outside of a request. It should give up when ctx is done.
+type Job func(ctx context.Context)
+
+// Queue runs jobs on a fixed number of background workers. Jobs wait in a
+// bounded buffer and are dropped when it is full, so a burst of requests
+// cannot pile up goroutines.
+type Queue struct {
+	jobs chan Job
+
+	// ctx is cancelled when Close gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewQueue(workers, size int) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		jobs:   make(chan Job, size),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	q.wg.Add(workers)
+	for range workers {
+		go q.run()
+	}
+	return q
+}
+
+// Submit queues job without blocking. It reports false when the job was
+// dropped because the queue is full or closed.
+func (q *Queue) Submit(job Job) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		q.dropped.Add(1)
+		return false
+	}
+
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		q.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns how many jobs were discarded.
+func (q *Queue) Dropped() uint64 {
+	return q.dropped.Load()
+}
+
+// Close stops accepting jobs and waits for the queued ones. When ctx is done
+// first, running jobs are cancelled and the ones still queued are dropped.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run() {
+	defer q.wg.Done()
+
+	for job := range q.jobs {
+		if q.ctx.Err() != nil {
+			q.dropped.Add(1)
+			continue
+		}
+		job(q.ctx)
+	}
+}
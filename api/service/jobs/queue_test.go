@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueRunsJobs(t *testing.T) {
+	q := NewQueue(2, 10)
+
+	var ran atomic.Int32
+	for range 10 {
+		if !q.Submit(func(context.Context) { ran.Add(1) }) {
+			t.Fatal("job dropped")
+		}
+	}
+
+	// Close waits for every queued job.
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := ran.Load(); n != 10 {
+		t.Errorf("ran %d jobs, want 10", n)
+	}
+
+	if q.Submit(func(context.Context) {}) {
+		t.Error("job accepted after Close")
+	}
+	if n := q.Dropped(); n != 1 {
+		t.Errorf("dropped %d, want 1", n)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := NewQueue(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	q.Submit(func(context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	// One job waits in the buffer, the next does not fit.
+	if !q.Submit(func(context.Context) {}) {
+		t.Error("job dropped with room in the buffer")
+	}
+	if q.Submit(func(context.Context) {}) {
+		t.Error("job accepted by a full queue")
+	}
+	if n := q.Dropped(); n != 1 {
+		t.Errorf("dropped %d, want 1", n)
+	}
+
+	close(release)
+	q.Close(context.Background())
+}
+
+func TestQueueCloseDeadline(t *testing.T) {
+	q := NewQueue(1, 10)
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+
+	q.Submit(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+	})
+	var ranQueued atomic.Bool
+	q.Submit(func(context.Context) { ranQueued.Store(true) })
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close = %v, want DeadlineExceeded", err)
+	}
+
+	// The running job is cancelled and the queued one dropped.
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("running job was not cancelled")
+	}
+	for deadline := time.Now().Add(time.Second); q.Dropped() != 1 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if ranQueued.Load() || q.Dropped() != 1 {
+		t.Errorf("queued job ran %v, dropped %d", ranQueued.Load(), q.Dropped())
+	}
+}
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"context"
+	"go-api/database/model"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LinkSweeper periodically soft-deletes short links whose expiry date has
+// passed.
+type LinkSweeper struct {
+	db       *gorm.DB
+	links    *model.ShortLinkCache
+	interval time.Duration
+
+	stop     chan struct{}
+	done     chan struct{}
+	started  atomic.Bool
+	stopOnce sync.Once
+}
+
+func NewLinkSweeper(db *gorm.DB, links *model.ShortLinkCache, interval time.Duration) *LinkSweeper {
+	return &LinkSweeper{
+		db:       db,
+		links:    links,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the sweeper in the background until Stop is called.
+func (s *LinkSweeper) Start() {
+	if s.started.CompareAndSwap(false, true) {
+		go s.run()
+	}
+}
+
+// Stop signals the sweeper to exit and waits for the current pass to finish.
+func (s *LinkSweeper) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	if s.started.Load() {
+		<-s.done
+	}
+}
+
+func (s *LinkSweeper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.Sweep()
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep performs a single pass.
+func (s *LinkSweeper) Sweep() {
+	deleted, err := model.SoftDeleteExpiredShortLinks(s.db, time.Now())
+	if err != nil {
+		log.Printf("Link sweeper failed: %v", err)
+		return
+	}
+
+	if len(deleted) > 0 {
+		s.links.Invalidate(context.Background(), deleted...)
+		log.Printf("Link sweeper removed %d expired links", len(deleted))
+	}
+}
@@ -0,0 +1,499 @@
+package routers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/middleware"
+	"go-api/internal/openapi"
+	"go-api/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Audit log target types.
+const (
+	auditTargetUser = "user"
+	auditTargetLink = "link"
+)
+
+// AdminRouter serves moderation and user management endpoints. Every change
+// made through it is recorded in the audit log.
+type AdminRouter struct {
+	db    *gorm.DB
+	links *model.ShortLinkCache
+}
+
+func NewAdminRouter(db *gorm.DB, links *model.ShortLinkCache) *AdminRouter {
+	return &AdminRouter{db: db, links: links}
+}
+
+func (r *AdminRouter) RegisterRouter(router *gin.RouterGroup, _ apiversion.Version) {
+	adminRouter := router.Group("/admin",
+		middleware.AuthMiddleware(r.db),
+		middleware.RequireSession(),
+		middleware.RequireRole(model.RoleAdmin),
+	)
+	{
+		adminRouter.GET("/stats", apperr.Handle(r.GetStats))
+		adminRouter.GET("/cache/stats", apperr.Handle(r.GetCacheStats))
+		adminRouter.GET("/audit", apperr.Handle(r.ListAuditLogs))
+		adminRouter.GET("/users", apperr.Handle(r.ListUsers))
+		adminRouter.GET("/users/:id", apperr.Handle(r.GetUser))
+		adminRouter.POST("/users/:id/disable", apperr.Handle(r.DisableUser))
+		adminRouter.POST("/users/:id/enable", apperr.Handle(r.EnableUser))
+		adminRouter.PUT("/users/:id/role", apperr.Handle(r.SetUserRole))
+		adminRouter.POST("/links/:id/takedown", apperr.Handle(r.TakeDownLink))
+		adminRouter.POST("/links/:id/restore", apperr.Handle(r.RestoreLink))
+	}
+}
+
+func (r *AdminRouter) DescribeRoutes(spec *openapi.Group, _ apiversion.Version) {
+	adminRouter := spec.Group("/admin", "admin").Authenticated()
+	{
+		adminRouter.GET("/stats", openapi.Op{
+			ID:        "getSystemStats",
+			Summary:   "Count users, links and clicks",
+			Responses: openapi.Responses{http.StatusOK: model.SystemStats{}},
+		})
+		adminRouter.GET("/cache/stats", openapi.Op{
+			ID:        "getCacheStats",
+			Summary:   "Report the hit ratio of the link cache",
+			Responses: openapi.Responses{http.StatusOK: model.ShortLinkCacheStats{}},
+		})
+		adminRouter.GET("/audit", openapi.Op{
+			ID:        "listAuditLogs",
+			Summary:   "List the audit log, newest first",
+			Query:     entities.AdminAuditQuery{},
+			Responses: openapi.Responses{http.StatusOK: entities.AdminAuditListResponse{}},
+		})
+		adminRouter.GET("/users", openapi.Op{
+			ID:        "listUsers",
+			Summary:   "List and search users",
+			Query:     entities.AdminUserListQuery{},
+			Responses: openapi.Responses{http.StatusOK: entities.AdminUserListResponse{}},
+		})
+		adminRouter.GET("/users/:id", openapi.Op{
+			ID:        "getUser",
+			Params:    entities.AdminIDParams{},
+			Responses: openapi.Responses{http.StatusOK: entities.AdminUserResponse{}},
+		})
+		adminRouter.POST("/users/:id/disable", openapi.Op{
+			ID:          "disableUser",
+			Summary:     "Block an account and end its sessions",
+			Description: "API keys of the account stop working while it is disabled.",
+			Params:      entities.AdminIDParams{},
+			Body:        entities.AdminReasonBody{},
+			Responses:   openapi.Responses{http.StatusOK: entities.AdminUserResponse{}},
+		})
+		adminRouter.POST("/users/:id/enable", openapi.Op{
+			ID:        "enableUser",
+			Summary:   "Unblock a disabled account",
+			Params:    entities.AdminIDParams{},
+			Body:      entities.AdminReasonBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.AdminUserResponse{}},
+		})
+		adminRouter.PUT("/users/:id/role", openapi.Op{
+			ID:        "setUserRole",
+			Params:    entities.AdminIDParams{},
+			Body:      entities.AdminRoleBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.AdminUserResponse{}},
+		})
+		adminRouter.POST("/links/:id/takedown", openapi.Op{
+			ID:        "takeDownLink",
+			Summary:   "Stop a link from redirecting",
+			Params:    entities.AdminIDParams{},
+			Body:      entities.AdminReasonBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.ShortLinkResponse{}},
+		})
+		adminRouter.POST("/links/:id/restore", openapi.Op{
+			ID:        "restoreLink",
+			Summary:   "Undo the takedown of a link",
+			Params:    entities.AdminIDParams{},
+			Body:      entities.AdminReasonBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.ShortLinkResponse{}},
+		})
+	}
+}
+
+func (r *AdminRouter) GetStats(c *gin.Context) error {
+	stats, err := model.GetSystemStats(r.db, time.Now())
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, stats)
+	return nil
+}
+
+func (r *AdminRouter) GetCacheStats(c *gin.Context) error {
+	c.JSON(http.StatusOK, r.links.Stats())
+	return nil
+}
+
+func (r *AdminRouter) ListUsers(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.AdminUserListQuery](c)
+	if err != nil {
+		return err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+
+	cursor, err := decodeCursorParam(query.Cursor)
+	if err != nil {
+		return err
+	}
+
+	users, next, err := model.ListUsers(r.db, model.ListUsersQuery{
+		Search: query.Search,
+		Role:   query.Role,
+		Cursor: cursor,
+		Limit:  query.Limit,
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := entities.AdminUserListResponse{
+		Items: make([]entities.AdminUserResponse, len(users)),
+	}
+	for i := range users {
+		response.Items[i] = toAdminUserResponse(&users[i])
+	}
+	if next != nil {
+		response.NextCursor = model.EncodeCursor(*next)
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+func (r *AdminRouter) GetUser(c *gin.Context) error {
+	user, err := r.targetUser(c)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, toAdminUserResponse(user))
+	return nil
+}
+
+// DisableUser blocks the account and ends all its sessions. API keys stop
+// working while the account is disabled.
+func (r *AdminRouter) DisableUser(c *gin.Context) error {
+	user, err := r.targetUser(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.AdminReasonBody](c)
+	if err != nil {
+		return err
+	}
+
+	if user.ID == auth.GetCurrentUserID(c) {
+		return errDisableSelf
+	}
+	if user.IsDisabled() {
+		return errAlreadyDisabled
+	}
+
+	now := time.Now()
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := model.SetUserDisabled(tx, user.ID, &now); err != nil {
+			return err
+		}
+		if _, err := model.RevokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+		return r.audit(tx, c, model.AuditUserDisabled, auditTargetUser, user.ID, body.Reason, nil)
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	user.DisabledAt = &now
+	c.JSON(http.StatusOK, toAdminUserResponse(user))
+	return nil
+}
+
+func (r *AdminRouter) EnableUser(c *gin.Context) error {
+	user, err := r.targetUser(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.AdminReasonBody](c)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsDisabled() {
+		return errNotDisabled
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := model.SetUserDisabled(tx, user.ID, nil); err != nil {
+			return err
+		}
+		return r.audit(tx, c, model.AuditUserEnabled, auditTargetUser, user.ID, body.Reason, nil)
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	user.DisabledAt = nil
+	c.JSON(http.StatusOK, toAdminUserResponse(user))
+	return nil
+}
+
+func (r *AdminRouter) SetUserRole(c *gin.Context) error {
+	user, err := r.targetUser(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.AdminRoleBody](c)
+	if err != nil {
+		return err
+	}
+
+	// Demoting yourself could leave nobody able to manage roles.
+	if user.ID == auth.GetCurrentUserID(c) {
+		return errChangeOwnRole
+	}
+	if user.Role == body.Role {
+		c.JSON(http.StatusOK, toAdminUserResponse(user))
+		return nil
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := model.SetUserRole(tx, user.ID, body.Role); err != nil {
+			return err
+		}
+		return r.audit(tx, c, model.AuditUserRoleChanged, auditTargetUser, user.ID, body.Reason, map[string]any{
+			"from": user.Role,
+			"to":   body.Role,
+		})
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	user.Role = body.Role
+	c.JSON(http.StatusOK, toAdminUserResponse(user))
+	return nil
+}
+
+func (r *AdminRouter) TakeDownLink(c *gin.Context) error {
+	link, err := r.targetLink(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.AdminReasonBody](c)
+	if err != nil {
+		return err
+	}
+
+	if link.IsTakenDown() {
+		return errAlreadyTakenDown
+	}
+
+	now := time.Now()
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := model.SetShortLinkTakedown(tx, link, &now, body.Reason); err != nil {
+			return err
+		}
+		return r.audit(tx, c, model.AuditLinkTakenDown, auditTargetLink, link.ID, body.Reason, map[string]any{
+			"code": link.Code,
+			"url":  link.URL,
+		})
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	r.links.Invalidate(c.Request.Context(), *link)
+
+	return respondShortLink(c, r.db, link)
+}
+
+func (r *AdminRouter) RestoreLink(c *gin.Context) error {
+	link, err := r.targetLink(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.AdminReasonBody](c)
+	if err != nil {
+		return err
+	}
+
+	if !link.IsTakenDown() {
+		return errNotTakenDown
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		if err := model.SetShortLinkTakedown(tx, link, nil, ""); err != nil {
+			return err
+		}
+		return r.audit(tx, c, model.AuditLinkRestored, auditTargetLink, link.ID, body.Reason, nil)
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	r.links.Invalidate(c.Request.Context(), *link)
+
+	return respondShortLink(c, r.db, link)
+}
+
+func (r *AdminRouter) ListAuditLogs(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.AdminAuditQuery](c)
+	if err != nil {
+		return err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+
+	cursor, err := decodeCursorParam(query.Cursor)
+	if err != nil {
+		return err
+	}
+
+	entries, next, err := model.ListAuditLogs(r.db, model.ListAuditLogsQuery{
+		ActorID:    query.ActorID,
+		Action:     query.Action,
+		TargetType: query.TargetType,
+		TargetID:   query.TargetID,
+		Cursor:     cursor,
+		Limit:      query.Limit,
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := entities.AdminAuditListResponse{
+		Items: make([]entities.AdminAuditLogResponse, len(entries)),
+	}
+	for i, entry := range entries {
+		item := entities.AdminAuditLogResponse{
+			ID:         entry.ID,
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			TargetType: entry.TargetType,
+			TargetID:   entry.TargetID,
+			Reason:     entry.Reason,
+			IP:         entry.IP,
+			CreatedAt:  entry.CreatedAt,
+		}
+		if entry.Details != "" {
+			_ = json.Unmarshal([]byte(entry.Details), &item.Details)
+		}
+		response.Items[i] = item
+	}
+	if next != nil {
+		response.NextCursor = model.EncodeCursor(*next)
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+// audit records an admin action as part of the transaction tx.
+func (r *AdminRouter) audit(tx *gorm.DB, c *gin.Context, action, targetType string, targetID uint, reason string, details map[string]any) error {
+	entry := model.AuditLog{
+		ActorID:    auth.GetCurrentUserID(c),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		IP:         c.ClientIP(),
+	}
+
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		entry.Details = string(data)
+	}
+
+	if err := model.CreateAuditLog(tx, &entry); err != nil {
+		return err
+	}
+
+	log.Printf("Admin %d: %s %s %d", entry.ActorID, action, targetType, targetID)
+	return nil
+}
+
+func (r *AdminRouter) targetUser(c *gin.Context) (*model.User, error) {
+	params, err := utils.GetParams[entities.AdminIDParams](c)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := model.GetUserByID(r.db, params.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUserNotFound
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return user, nil
+}
+
+func (r *AdminRouter) targetLink(c *gin.Context) (*model.ShortLink, error) {
+	params, err := utils.GetParams[entities.AdminIDParams](c)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := model.GetShortLinkByID(r.db, params.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errLinkNotFound
+	}
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+
+	return link, nil
+}
+
+func toAdminUserResponse(user *model.User) entities.AdminUserResponse {
+	return entities.AdminUserResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		DisabledAt:      user.DisabledAt,
+		LockedUntil:     user.LockedUntil,
+		CreatedAt:       user.CreatedAt,
+	}
+}
+
+// decodeCursorParam decodes an optional cursor query parameter.
+func decodeCursorParam(value string) (*model.Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	cursor, err := model.DecodeCursor(value)
+	if err != nil {
+		return nil, errInvalidCursorParam
+	}
+	return cursor, nil
+}
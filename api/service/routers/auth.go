@@ -3,47 +3,235 @@ package routers
 import (
 	"go-api/database/model"
 	"go-api/entities"
-	"go-api/internal/env"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/config"
+	"go-api/internal/mailer"
+	"go-api/internal/middleware"
+	"go-api/internal/oidc"
+	"go-api/internal/openapi"
+	"go-api/internal/ratelimit"
 	"go-api/internal/utils"
+	"go-api/service/jobs"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+const (
+	refreshCookieName = "refresh_token"
+	refreshCookiePath = "/api"
+)
+
 type AuthRouter struct {
-	db *gorm.DB
+	db     *gorm.DB
+	mailer mailer.Mailer
+	// background runs the work of requests that answer before it is done.
+	background *jobs.Queue
+	limits     *ratelimit.Limiter
+	// oidc holds the identity providers users can log in with, by name.
+	oidc map[string]*oidc.Provider
+	cfg  *config.Config
 }
 
-func NewAuthRouter(db *gorm.DB) *AuthRouter {
-	return &AuthRouter{db: db}
+func NewAuthRouter(db *gorm.DB, mailer mailer.Mailer, background *jobs.Queue, limits *ratelimit.Limiter, providers map[string]*oidc.Provider, cfg *config.Config) *AuthRouter {
+	return &AuthRouter{db: db, mailer: mailer, background: background, limits: limits, oidc: providers, cfg: cfg}
 }
 
-func (r *AuthRouter) RegisterRouter(router *gin.RouterGroup) {
+func (r *AuthRouter) RegisterRouter(router *gin.RouterGroup, _ apiversion.Version) {
+	authed := middleware.AuthMiddleware(r.db)
+	loginLimit := middleware.RateLimit(r.limits, "login")
+	authLimit := middleware.RateLimit(r.limits, "auth")
+
 	authRouter := router.Group("/auth")
 	{
-		authRouter.POST("/register", r.RegisterAccount)
-		authRouter.POST("/login", r.LoginAccount)
+		authRouter.POST("/register", authLimit, apperr.Handle(r.RegisterAccount))
+		authRouter.POST("/login", loginLimit, apperr.Handle(r.LoginAccount))
+		authRouter.POST("/refresh", authLimit, apperr.Handle(r.RefreshSession))
+		authRouter.POST("/logout", authed, middleware.RequireSession(), apperr.Handle(r.Logout))
+		authRouter.POST("/logout-all", authed, middleware.RequireSession(), apperr.Handle(r.LogoutAll))
+		authRouter.POST("/verify-email", authLimit, apperr.Handle(r.VerifyEmail))
+		authRouter.POST("/verify-email/request", authed, middleware.RequireSession(), authLimit, apperr.Handle(r.RequestEmailVerification))
+		authRouter.POST("/password/forgot", authLimit, apperr.Handle(r.ForgotPassword))
+		authRouter.POST("/password/reset", authLimit, apperr.Handle(r.ResetPassword))
+		authRouter.POST("/2fa/verify", loginLimit, apperr.Handle(r.VerifyTwoFactor))
+		authRouter.GET("/oidc", apperr.Handle(r.ListOIDCProviders))
+		authRouter.GET("/oidc/:provider/login", authLimit, apperr.Handle(r.StartOIDCLogin))
+		authRouter.GET("/oidc/:provider/callback", authLimit, apperr.Handle(r.OIDCCallback))
+
+		twoFactorRouter := authRouter.Group("/2fa", authed, middleware.RequireSession())
+		{
+			twoFactorRouter.GET("", apperr.Handle(r.GetTwoFactorStatus))
+			twoFactorRouter.POST("/setup", apperr.Handle(r.SetupTwoFactor))
+			twoFactorRouter.POST("/enable", authLimit, apperr.Handle(r.EnableTwoFactor))
+			twoFactorRouter.POST("/disable", authLimit, apperr.Handle(r.DisableTwoFactor))
+			twoFactorRouter.POST("/recovery-codes", authLimit, apperr.Handle(r.RegenerateRecoveryCodes))
+		}
+
+		keysRouter := authRouter.Group("/keys", authed, middleware.RequireSession())
+		{
+			keysRouter.GET("", apperr.Handle(r.ListAPIKeys))
+			keysRouter.POST("", apperr.Handle(r.CreateAPIKey))
+			keysRouter.DELETE("/:id", apperr.Handle(r.RevokeAPIKey))
+		}
+	}
+}
+
+func (r *AuthRouter) DescribeRoutes(spec *openapi.Group, _ apiversion.Version) {
+	loginResponse := openapi.OneOf(entities.AuthTokenResponse{}, entities.AuthTwoFactorChallengeResponse{})
+
+	authRouter := spec.Group("/auth", "auth")
+	authed := authRouter.Authenticated()
+	{
+		authRouter.POST("/register", openapi.Op{
+			ID:        "register",
+			Summary:   "Create an account",
+			Body:      entities.AuthRegisterRequestBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.AuthRegisterResponse{}},
+		})
+		authRouter.POST("/login", openapi.Op{
+			ID:          "login",
+			Summary:     "Log in with email and password",
+			Description: "Accounts with two-factor login get a challenge to complete at /auth/2fa/verify instead of tokens.",
+			Body:        entities.AuthLoginRequestBody{},
+			Responses:   openapi.Responses{http.StatusOK: loginResponse},
+		})
+		authRouter.POST("/refresh", openapi.Op{
+			ID:           "refreshSession",
+			Summary:      "Exchange a refresh token for new tokens",
+			Description:  "The refresh token is read from the body, or the refresh_token cookie.",
+			Body:         entities.AuthRefreshRequestBody{},
+			OptionalBody: true,
+			Responses:    openapi.Responses{http.StatusOK: entities.AuthTokenResponse{}},
+		})
+		authed.POST("/logout", openapi.Op{
+			ID:        "logout",
+			Summary:   "End the current session",
+			Responses: openapi.Responses{http.StatusNoContent: nil},
+		})
+		authed.POST("/logout-all", openapi.Op{
+			ID:        "logoutAll",
+			Summary:   "End every session of the user",
+			Responses: openapi.Responses{http.StatusOK: entities.AuthLogoutAllResponse{}},
+		})
+		authRouter.POST("/verify-email", openapi.Op{
+			ID:        "verifyEmail",
+			Body:      entities.AuthVerifyEmailRequestBody{},
+			Responses: openapi.Responses{http.StatusOK: ""},
+		})
+		authed.POST("/verify-email/request", openapi.Op{
+			ID:        "requestEmailVerification",
+			Summary:   "Send a new verification email",
+			Responses: openapi.Responses{http.StatusAccepted: ""},
+		})
+		authRouter.POST("/password/forgot", openapi.Op{
+			ID:        "forgotPassword",
+			Summary:   "Send a password reset email",
+			Body:      entities.AuthEmailRequestBody{},
+			Responses: openapi.Responses{http.StatusAccepted: ""},
+		})
+		authRouter.POST("/password/reset", openapi.Op{
+			ID:        "resetPassword",
+			Body:      entities.AuthPasswordResetRequestBody{},
+			Responses: openapi.Responses{http.StatusOK: ""},
+		})
+		authRouter.POST("/2fa/verify", openapi.Op{
+			ID:        "verifyTwoFactor",
+			Summary:   "Complete a login with a second factor",
+			Body:      entities.AuthTwoFactorVerifyBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.AuthTokenResponse{}},
+		})
+		authRouter.GET("/oidc", openapi.Op{
+			ID:        "listOIDCProviders",
+			Summary:   "Name the identity providers users can log in with",
+			Responses: openapi.Responses{http.StatusOK: entities.AuthOIDCProvidersResponse{}},
+		})
+		authRouter.GET("/oidc/:provider/login", openapi.Op{
+			ID:        "startOIDCLogin",
+			Summary:   "Redirect to the identity provider",
+			Params:    entities.AuthOIDCParams{},
+			Responses: openapi.Responses{http.StatusFound: openapi.Redirect{}},
+		})
+		authRouter.GET("/oidc/:provider/callback", openapi.Op{
+			ID:        "completeOIDCLogin",
+			Summary:   "Complete a login at an identity provider",
+			Params:    entities.AuthOIDCParams{},
+			Query:     entities.AuthOIDCCallbackQuery{},
+			Responses: openapi.Responses{http.StatusOK: loginResponse},
+		})
+
+		twoFactorRouter := authed.Group("/2fa", "two-factor")
+		{
+			twoFactorRouter.GET("", openapi.Op{
+				ID:        "getTwoFactorStatus",
+				Responses: openapi.Responses{http.StatusOK: entities.AuthTwoFactorStatusResponse{}},
+			})
+			twoFactorRouter.POST("/setup", openapi.Op{
+				ID:          "setupTwoFactor",
+				Summary:     "Start setting up two-factor login",
+				Description: "Returns a new secret, which is only used once /auth/2fa/enable confirms a code from it.",
+				Responses:   openapi.Responses{http.StatusOK: entities.AuthTwoFactorSetupResponse{}},
+			})
+			twoFactorRouter.POST("/enable", openapi.Op{
+				ID:        "enableTwoFactor",
+				Body:      entities.AuthTwoFactorCodeBody{},
+				Responses: openapi.Responses{http.StatusOK: entities.AuthRecoveryCodesResponse{}},
+			})
+			twoFactorRouter.POST("/disable", openapi.Op{
+				ID:        "disableTwoFactor",
+				Body:      entities.AuthTwoFactorCodeBody{},
+				Responses: openapi.Responses{http.StatusNoContent: nil},
+			})
+			twoFactorRouter.POST("/recovery-codes", openapi.Op{
+				ID:        "regenerateRecoveryCodes",
+				Summary:   "Replace the recovery codes",
+				Body:      entities.AuthTwoFactorCodeBody{},
+				Responses: openapi.Responses{http.StatusOK: entities.AuthRecoveryCodesResponse{}},
+			})
+		}
+
+		keysRouter := authed.Group("/keys", "api-keys")
+		{
+			keysRouter.GET("", openapi.Op{
+				ID:        "listAPIKeys",
+				Responses: openapi.Responses{http.StatusOK: entities.AuthAPIKeyListResponse{}},
+			})
+			keysRouter.POST("", openapi.Op{
+				ID:          "createAPIKey",
+				Summary:     "Create a personal API key",
+				Description: "The key itself is only returned in this response.",
+				Body:        entities.AuthAPIKeyCreateRequestBody{},
+				Responses:   openapi.Responses{http.StatusCreated: entities.AuthAPIKeyResponse{}},
+			})
+			keysRouter.DELETE("/:id", openapi.Op{
+				ID:        "revokeAPIKey",
+				Params:    entities.AuthAPIKeyParams{},
+				Responses: openapi.Responses{http.StatusOK: entities.AuthAPIKeyResponse{}},
+			})
+		}
 	}
 }
 
-func (r *AuthRouter) RegisterAccount(c *gin.Context) {
-	body, ok := utils.GetBody[entities.AuthRegisterRequestBody](c)
-	if !ok {
-		return
+func (r *AuthRouter) RegisterAccount(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthRegisterRequestBody](c)
+	if err != nil {
+		return err
 	}
 
 	existingAccount, err := model.GetUserByEmail(r.db, body.Email)
 	if err == nil || existingAccount != nil {
-		c.JSON(http.StatusBadRequest, "Account already exists")
-		return
+		return errAccountExists
 	}
 
 	encryptedPassword, err := utils.HashPassword(body.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, "Something went wrong.")
-		return
+		return apperr.Internal(err)
 	}
 
 	user := model.User{
@@ -55,44 +243,225 @@ func (r *AuthRouter) RegisterAccount(c *gin.Context) {
 
 	usrId, err := model.CreateUserAndReturnID(r.db, &user)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, "Account Already Exists")
-		return
+		return errAccountExists
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"userId": usrId,
-	})
+	if err := r.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to issue email verification for user %d: %v", usrId, err)
+	}
 
-	return
+	c.JSON(http.StatusOK, entities.AuthRegisterResponse{
+		UserID: usrId,
+	})
+	return nil
 }
 
-func (r *AuthRouter) LoginAccount(c *gin.Context) {
-	body, ok := utils.GetBody[entities.AuthLoginRequestBody](c)
-	if !ok {
-		return
+func (r *AuthRouter) LoginAccount(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthLoginRequestBody](c)
+	if err != nil {
+		return err
 	}
 
 	user, err := model.GetUserByEmail(r.db, body.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, "Invalid Credentials")
-		return
+		return errInvalidCredentials
+	}
+
+	// A locked account rejects even the right password, otherwise the lock
+	// would not slow down guessing.
+	now := time.Now()
+	if user.IsLocked(now) {
+		return accountLocked(c, *user.LockedUntil, now)
 	}
 
 	if !utils.CheckPassword(user.Password, body.Password) {
-		c.JSON(http.StatusUnauthorized, "Invalid Credentials")
-		return
+		_, lockedUntil, err := model.RecordFailedLogin(r.db, user.ID, now, r.loginLockout)
+		if err != nil {
+			log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+		}
+		if lockedUntil != nil {
+			return accountLocked(c, *lockedUntil, now)
+		}
+
+		return errInvalidCredentials
+	}
+
+	if user.IsDisabled() {
+		return errAccountDisabled
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
+	return r.completeLogin(c, user)
+}
+
+// RefreshSession exchanges a refresh token, from the body or the
+// refresh_token cookie, for a new access and refresh token pair. Presenting
+// a refresh token that was already exchanged revokes its whole session.
+func (r *AuthRouter) RefreshSession(c *gin.Context) error {
+	var body entities.AuthRefreshRequestBody
+	if c.Request.ContentLength > 0 {
+		var err error
+		if body, err = utils.GetBody[entities.AuthRefreshRequestBody](c); err != nil {
+			return err
+		}
+	}
+
+	refreshToken := body.RefreshToken
+	if refreshToken == "" {
+		refreshToken, _ = c.Cookie(refreshCookieName)
+	}
+	if refreshToken == "" {
+		return errInvalidRefresh
+	}
+
+	stored, err := model.GetRefreshTokenByHash(r.db, utils.HashToken(refreshToken))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, "Something went wrong.")
-		return
+		return errInvalidRefresh
 	}
 
-	tokenExpiration := time.Duration(env.GetInt("JWT_TOKEN_EXPIRATION", int(time.Hour*10))) // Default to 10 hours
+	now := time.Now()
+	session, err := model.GetSessionByID(r.db, stored.SessionID)
+	if err != nil || !session.IsActive(now) || !now.Before(stored.ExpiresAt) {
+		return errInvalidRefresh
+	}
 
-	c.SetCookie("token", token, int(tokenExpiration.Seconds()), "/", "", false, false)
-	c.JSON(http.StatusOK, gin.H{
-		"userId": user.ID,
+	if stored.UsedAt != nil {
+		return r.revokeReusedSession(c, session)
+	}
+
+	newToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	expiresAt := now.Add(r.cfg.Auth.RefreshTokenTTL)
+	rotated, err := model.RotateRefreshToken(r.db, stored, utils.HashToken(newToken), expiresAt)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !rotated {
+		return r.revokeReusedSession(c, session)
+	}
+
+	return r.issueTokens(c, session.UserID, session.ID.String(), newToken)
+}
+
+func (r *AuthRouter) Logout(c *gin.Context) error {
+	sessionID, err := model.ParseUUID(auth.GetCurrentSessionID(c))
+	if err != nil {
+		return middleware.ErrUnauthorized
+	}
+
+	if err := model.RevokeSession(r.db, sessionID); err != nil {
+		return apperr.Internal(err)
+	}
+
+	clearAuthCookies(c)
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+func (r *AuthRouter) LogoutAll(c *gin.Context) error {
+	revoked, err := model.RevokeUserSessions(r.db, auth.GetCurrentUserID(c))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, entities.AuthLogoutAllResponse{
+		RevokedSessions: revoked,
+	})
+	return nil
+}
+
+// startSession opens a new session for the user and responds with its tokens.
+func (r *AuthRouter) startSession(c *gin.Context, userID uint) error {
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	now := time.Now()
+	session := model.Session{
+		UserID:     userID,
+		ExpiresAt:  now.Add(r.cfg.Auth.RefreshTokenTTL),
+		LastUsedAt: now,
+		UserAgent:  truncateString(c.Request.UserAgent(), 512),
+		IP:         c.ClientIP(),
+	}
+
+	if err := model.CreateSession(r.db, &session, utils.HashToken(refreshToken)); err != nil {
+		return apperr.Internal(err)
+	}
+
+	return r.issueTokens(c, userID, session.ID.String(), refreshToken)
+}
+
+func (r *AuthRouter) issueTokens(c *gin.Context, userID uint, sessionID, refreshToken string) error {
+	token, err := utils.GenerateJWT(userID, sessionID)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	accessTTL := utils.AccessTokenTTL()
+	secure := utils.GetProtocol(c) == "https"
+
+	c.SetCookie("token", token, int(accessTTL.Seconds()), "/", "", secure, true)
+	c.SetCookie(refreshCookieName, refreshToken, int(r.cfg.Auth.RefreshTokenTTL.Seconds()), refreshCookiePath, "", secure, true)
+	c.JSON(http.StatusOK, entities.AuthTokenResponse{
+		UserID:       userID,
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTTL.Seconds()),
 	})
+	return nil
+}
+
+func (r *AuthRouter) revokeReusedSession(c *gin.Context, session *model.Session) error {
+	log.Printf("Refresh token reuse detected for session %s of user %d, revoking it", session.ID, session.UserID)
+
+	if err := model.RevokeSession(r.db, session.ID); err != nil {
+		return apperr.Internal(err)
+	}
+
+	clearAuthCookies(c)
+	return errInvalidRefresh
+}
+
+func clearAuthCookies(c *gin.Context) {
+	c.SetCookie("token", "", -1, "/", "", false, true)
+	c.SetCookie(refreshCookieName, "", -1, refreshCookiePath, "", false, true)
+}
+
+// accountLocked tells the client when to retry a login to a locked account.
+func accountLocked(c *gin.Context, until, now time.Time) error {
+	retryAfter := int(math.Ceil(until.Sub(now).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	return errAccountLocked
+}
+
+// loginLockout is how long an account is locked after count failed logins in
+// a row. Reaching the lockout threshold locks it for the base duration, and
+// every further failure doubles that up to the maximum.
+func (r *AuthRouter) loginLockout(count int) time.Duration {
+	threshold := r.cfg.Auth.LockoutThreshold
+	if threshold <= 0 || count < threshold {
+		return 0
+	}
+
+	base := r.cfg.Auth.LockoutBase
+	max := r.cfg.Auth.LockoutMax
+
+	doublings := min(count-threshold, 30)
+	lock := base << doublings
+	if lock <= 0 || lock > max {
+		lock = max
+	}
+	return lock
+}
+
+func truncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
 }
@@ -0,0 +1,212 @@
+package routers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/mailer"
+	"go-api/internal/middleware"
+	"go-api/internal/utils"
+	"go-api/service/jobs"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const mailSendTimeout = 30 * time.Second
+
+// RequestEmailVerification mails a new verification link to the current user.
+func (r *AuthRouter) RequestEmailVerification(c *gin.Context) error {
+	user, err := model.GetUserByID(r.db, auth.GetCurrentUserID(c))
+	if err != nil {
+		return middleware.ErrUnauthorized
+	}
+
+	if user.IsEmailVerified() {
+		return errEmailVerified
+	}
+
+	if err := r.sendVerificationEmail(user); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusAccepted, "Verification email sent")
+	return nil
+}
+
+func (r *AuthRouter) VerifyEmail(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthVerifyEmailRequestBody](c)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		token, err := model.ConsumeUserToken(tx, model.TokenPurposeVerifyEmail, utils.HashToken(body.Token))
+		if err != nil {
+			return err
+		}
+		return model.MarkEmailVerified(tx, token.UserID, time.Now())
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errInvalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, "Email verified")
+	return nil
+}
+
+// ForgotPassword mails a password reset link. The account is looked up and
+// the token issued in the background for every address, so neither the
+// answer nor how long it takes reveals whether the account exists.
+func (r *AuthRouter) ForgotPassword(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthEmailRequestBody](c)
+	if err != nil {
+		return err
+	}
+
+	email := body.Email
+	if !r.background.Submit(func(ctx context.Context) { r.sendPasswordResetEmail(ctx, email) }) {
+		log.Printf("Dropped a password reset request: background queue full")
+	}
+
+	c.JSON(http.StatusAccepted, "If the account exists, a reset email was sent")
+	return nil
+}
+
+// ResetPassword sets a new password, lifts any login lockout and signs the
+// user out everywhere.
+// Receiving the email proves ownership of the address, so it also counts as
+// verification.
+func (r *AuthRouter) ResetPassword(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthPasswordResetRequestBody](c)
+	if err != nil {
+		return err
+	}
+
+	encryptedPassword, err := utils.HashPassword(body.Password)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		token, err := model.ConsumeUserToken(tx, model.TokenPurposePasswordReset, utils.HashToken(body.Token))
+		if err != nil {
+			return err
+		}
+		if err := model.UpdateUserPassword(tx, token.UserID, encryptedPassword); err != nil {
+			return err
+		}
+		if err := model.MarkEmailVerified(tx, token.UserID, time.Now()); err != nil {
+			return err
+		}
+		if err := model.ResetFailedLogins(tx, token.UserID); err != nil {
+			return err
+		}
+		_, err = model.RevokeUserSessions(tx, token.UserID)
+		return err
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errInvalidToken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	clearAuthCookies(c)
+	c.JSON(http.StatusOK, "Password updated")
+	return nil
+}
+
+func (r *AuthRouter) sendVerificationEmail(user *model.User) error {
+	ttl := r.cfg.Auth.EmailVerificationTTL
+	token, err := r.issueUserToken(user.ID, model.TokenPurposeVerifyEmail, ttl)
+	if err != nil {
+		return err
+	}
+
+	sendMail(r.background, r.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.",
+			appLink(r.cfg.Mail.AppURL, "/verify-email", token), ttl),
+	})
+	return nil
+}
+
+// sendPasswordResetEmail mails a reset link to the account of email, if
+// there is one. It runs outside the request, so it only logs failures.
+func (r *AuthRouter) sendPasswordResetEmail(ctx context.Context, email string) {
+	user, err := model.GetUserByEmail(r.db.WithContext(ctx), email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up account for password reset: %v", err)
+		return
+	}
+
+	ttl := r.cfg.Auth.PasswordResetTTL
+	token, err := r.issueUserToken(user.ID, model.TokenPurposePasswordReset, ttl)
+	if err != nil {
+		log.Printf("Failed to issue password reset for user %d: %v", user.ID, err)
+		return
+	}
+
+	deliver(ctx, r.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Choose a new password by opening the link below:\n\n%s\n\nThe link expires in %s. If you did not ask for a reset, ignore this email.",
+			appLink(r.cfg.Mail.AppURL, "/reset-password", token), ttl),
+	})
+}
+
+func (r *AuthRouter) issueUserToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = model.CreateUserToken(r.db, &model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	return token, err
+}
+
+// sendMail delivers in the background so slow mail servers do not hold up
+// the request.
+func sendMail(background *jobs.Queue, m mailer.Mailer, msg mailer.Message) {
+	if !background.Submit(func(ctx context.Context) { deliver(ctx, m, msg) }) {
+		log.Printf("Dropped %q email: background queue full", msg.Subject)
+	}
+}
+
+func deliver(ctx context.Context, m mailer.Mailer, msg mailer.Message) {
+	ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	defer cancel()
+
+	if err := m.Send(ctx, msg); err != nil {
+		log.Printf("Failed to send %q email: %v", msg.Subject, err)
+	}
+}
+
+// appLink builds a link to the frontend page at base that consumes a token.
+// base is the configured APP_URL, never the request's Host, which the
+// client controls.
+func appLink(base, path, token string) string {
+	return strings.TrimSuffix(base, "/") + path + "?token=" + url.QueryEscape(token)
+}
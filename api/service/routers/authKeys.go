@@ -0,0 +1,96 @@
+package routers
+
+import (
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/utils"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (r *AuthRouter) ListAPIKeys(c *gin.Context) error {
+	keys, err := model.ListUserAPIKeys(r.db, auth.GetCurrentUserID(c))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := make([]entities.AuthAPIKeyResponse, len(keys))
+	for i := range keys {
+		response[i] = toAPIKeyResponse(&keys[i])
+	}
+
+	c.JSON(http.StatusOK, entities.AuthAPIKeyListResponse{
+		Keys:   response,
+		Scopes: auth.Scopes,
+	})
+	return nil
+}
+
+func (r *AuthRouter) CreateAPIKey(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthAPIKeyCreateRequestBody](c)
+	if err != nil {
+		return err
+	}
+
+	scopes := slices.Clone(body.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	secret, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	key := model.APIKey{
+		UserID:  auth.GetCurrentUserID(c),
+		Name:    strings.TrimSpace(body.Name),
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(secret),
+		Scopes:  strings.Join(scopes, " "),
+	}
+
+	if err := model.CreateAPIKey(r.db, &key); err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := toAPIKeyResponse(&key)
+	response.Key = secret
+	c.JSON(http.StatusCreated, response)
+	return nil
+}
+
+func (r *AuthRouter) RevokeAPIKey(c *gin.Context) error {
+	params, err := utils.GetParams[entities.AuthAPIKeyParams](c)
+	if err != nil {
+		return err
+	}
+
+	key, err := model.GetUserAPIKey(r.db, auth.GetCurrentUserID(c), params.ID)
+	if err != nil {
+		return errAPIKeyNotFound
+	}
+
+	if err := model.RevokeAPIKey(r.db, key); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, toAPIKeyResponse(key))
+	return nil
+}
+
+func toAPIKeyResponse(key *model.APIKey) entities.AuthAPIKeyResponse {
+	return entities.AuthAPIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
@@ -0,0 +1,163 @@
+package routers
+
+import (
+	"errors"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/oidc"
+	"go-api/internal/utils"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookieName    = "oidc_state"
+	oidcCallbackPathSuffix = "/callback"
+)
+
+// ListOIDCProviders names the identity providers users can log in with.
+func (r *AuthRouter) ListOIDCProviders(c *gin.Context) error {
+	names := make([]string, 0, len(r.oidc))
+	for name := range r.oidc {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	c.JSON(http.StatusOK, entities.AuthOIDCProvidersResponse{
+		Providers: names,
+	})
+	return nil
+}
+
+// StartOIDCLogin sends the user to the provider. The state, nonce and PKCE
+// verifier of the login are kept server side; the state also goes into a
+// cookie so the callback only completes in the browser that started it.
+func (r *AuthRouter) StartOIDCLogin(c *gin.Context) error {
+	provider, err := r.oidcProvider(c)
+	if err != nil {
+		return err
+	}
+
+	state, err1 := utils.GenerateOpaqueToken()
+	nonce, err2 := utils.GenerateOpaqueToken()
+	verifier, err3 := utils.GenerateOpaqueToken()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return apperr.Internal(err)
+	}
+
+	redirectURL := provider.RedirectURL(utils.GetProtocol(c) + "://" + c.Request.Host +
+		strings.TrimSuffix(c.Request.URL.Path, "/login") + oidcCallbackPathSuffix)
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), redirectURL, state, nonce, verifier)
+	if err != nil {
+		log.Printf("Starting %s login failed: %v", provider.Name(), err)
+		return errProviderUnavailable
+	}
+
+	ttl := r.cfg.OIDC.LoginTTL
+	err = model.CreateOIDCLogin(r.db, &model.OIDCLogin{
+		Provider:     provider.Name(),
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectURL:  redirectURL,
+		ExpiresAt:    time.Now().Add(ttl),
+	})
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	// Lax lets the cookie come along on the top-level redirect back from
+	// the provider.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookieName, state, int(ttl.Seconds()), refreshCookiePath, "", utils.GetProtocol(c) == "https", true)
+	c.Redirect(http.StatusFound, authURL)
+	return nil
+}
+
+// OIDCCallback completes a login: it checks the state, exchanges the code
+// with the PKCE verifier, verifies the ID token and its nonce, and starts a
+// session for the linked user just like a password login, including the
+// second factor when the user turned it on.
+func (r *AuthRouter) OIDCCallback(c *gin.Context) error {
+	provider, err := r.oidcProvider(c)
+	if err != nil {
+		return err
+	}
+
+	query, err := utils.GetSearchParams[entities.AuthOIDCCallbackQuery](c)
+	if err != nil {
+		return err
+	}
+
+	cookieState, _ := c.Cookie(oidcStateCookieName)
+	c.SetCookie(oidcStateCookieName, "", -1, refreshCookiePath, "", false, true)
+	if cookieState == "" || cookieState != query.State {
+		return errInvalidLoginState
+	}
+
+	now := time.Now()
+	login, err := model.ConsumeOIDCLogin(r.db, provider.Name(), utils.HashToken(query.State), now)
+	if err != nil {
+		return errInvalidLoginState
+	}
+
+	if query.Error != "" {
+		log.Printf("%s login failed: %s %s", provider.Name(), query.Error, query.ErrorDescription)
+		return errProviderDenied
+	}
+	if query.Code == "" {
+		return errMissingAuthCode
+	}
+
+	token, err := provider.Exchange(c.Request.Context(), login.RedirectURL, query.Code, login.CodeVerifier)
+	if err != nil {
+		log.Printf("%s code exchange failed: %v", provider.Name(), err)
+		return errProviderExchange
+	}
+
+	claims, err := provider.VerifyIDToken(c.Request.Context(), token.IDToken, login.Nonce)
+	if err != nil {
+		log.Printf("%s ID token rejected: %v", provider.Name(), err)
+		return errInvalidIDToken
+	}
+
+	user, err := model.LoginWithIdentity(r.db, model.ExternalLogin{
+		Provider:      provider.Name(),
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, now)
+	if errors.Is(err, model.ErrIdentityEmailUnverified) {
+		return errEmailNotVerifiedIdP
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	if user.IsDisabled() {
+		return errAccountDisabled
+	}
+
+	return r.completeLogin(c, user)
+}
+
+func (r *AuthRouter) oidcProvider(c *gin.Context) (*oidc.Provider, error) {
+	params, err := utils.GetParams[entities.AuthOIDCParams](c)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, ok := r.oidc[params.Provider]
+	if !ok {
+		return nil, errUnknownProvider
+	}
+	return provider, nil
+}
@@ -0,0 +1,316 @@
+package routers
+
+import (
+	"crypto/rand"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/middleware"
+	"go-api/internal/totp"
+	"go-api/internal/utils"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	recoveryCodeCount = 10
+	// totpSkew accepts the codes of the neighbouring steps, for clock drift
+	// and for codes typed just as they changed.
+	totpSkew = 1
+)
+
+// recoveryCodeAlphabet leaves out characters that are easily confused.
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+func (r *AuthRouter) GetTwoFactorStatus(c *gin.Context) error {
+	user, err := r.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	remaining, err := model.CountUnusedRecoveryCodes(r.db, user.ID)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, entities.AuthTwoFactorStatusResponse{
+		Enabled:                user.HasTwoFactor(),
+		RecoveryCodesRemaining: remaining,
+	})
+	return nil
+}
+
+// SetupTwoFactor starts enrollment with a new secret. Two-factor login is
+// only turned on once EnableTwoFactor saw a valid code for it.
+func (r *AuthRouter) SetupTwoFactor(c *gin.Context) error {
+	user, err := r.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	if user.HasTwoFactor() {
+		return errTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	if err := model.StartTOTPEnrollment(r.db, user.ID, secret); err != nil {
+		return apperr.Internal(err)
+	}
+
+	uri := totp.ProvisioningURI(r.cfg.Auth.TOTPIssuer, user.Email, secret)
+	c.JSON(http.StatusOK, entities.AuthTwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRPayload:       uri,
+	})
+	return nil
+}
+
+// EnableTwoFactor confirms enrollment with a code from the authenticator and
+// answers with the recovery codes.
+func (r *AuthRouter) EnableTwoFactor(c *gin.Context) error {
+	user, err := r.currentUser(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.AuthTwoFactorCodeBody](c)
+	if err != nil {
+		return err
+	}
+
+	if user.HasTwoFactor() {
+		return errTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return errTwoFactorNoSetup
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, body.Code, time.Now(), totpSkew)
+	if !valid {
+		return errInvalidSetupCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	if err := model.EnableTOTP(r.db, user.ID, step, hashes, time.Now()); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, entities.AuthRecoveryCodesResponse{RecoveryCodes: codes})
+	return nil
+}
+
+// DisableTwoFactor turns two-factor login off. It takes a current code so a
+// stolen session alone cannot remove the second factor.
+func (r *AuthRouter) DisableTwoFactor(c *gin.Context) error {
+	user, err := r.twoFactorUser(c)
+	if err != nil {
+		return err
+	}
+
+	if err := model.DisableTOTP(r.db, user.ID); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes with new ones.
+func (r *AuthRouter) RegenerateRecoveryCodes(c *gin.Context) error {
+	user, err := r.twoFactorUser(c)
+	if err != nil {
+		return err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	if err := model.ReplaceRecoveryCodes(r.db, user.ID, hashes); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, entities.AuthRecoveryCodesResponse{RecoveryCodes: codes})
+	return nil
+}
+
+// VerifyTwoFactor exchanges a login challenge and a second factor for a
+// session. Wrong codes count as failed logins, so guessing runs into the
+// same lockout as guessing passwords.
+func (r *AuthRouter) VerifyTwoFactor(c *gin.Context) error {
+	body, err := utils.GetBody[entities.AuthTwoFactorVerifyBody](c)
+	if err != nil {
+		return err
+	}
+
+	challengeHash := utils.HashToken(body.ChallengeToken)
+	challenge, err := model.GetActiveUserToken(r.db, model.TokenPurposeLoginChallenge, challengeHash)
+	if err != nil {
+		return errInvalidChallenge
+	}
+
+	user, err := model.GetUserByID(r.db, challenge.UserID)
+	if err != nil || !user.HasTwoFactor() {
+		return errInvalidChallenge
+	}
+
+	now := time.Now()
+	if user.IsLocked(now) {
+		return accountLocked(c, *user.LockedUntil, now)
+	}
+	if user.IsDisabled() {
+		return errAccountDisabled
+	}
+
+	valid, err := r.checkSecondFactor(user, body.Code, now)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !valid {
+		_, lockedUntil, err := model.RecordFailedLogin(r.db, user.ID, now, r.loginLockout)
+		if err != nil {
+			log.Printf("Failed to record failed login for user %d: %v", user.ID, err)
+		}
+		if lockedUntil != nil {
+			return accountLocked(c, *lockedUntil, now)
+		}
+
+		return errInvalidCode
+	}
+
+	// Consuming the challenge only now lets users retry a mistyped code.
+	if _, err := model.ConsumeUserToken(r.db, model.TokenPurposeLoginChallenge, challengeHash); err != nil {
+		return errInvalidChallenge
+	}
+
+	if err := model.ResetFailedLogins(r.db, user.ID); err != nil {
+		log.Printf("Failed to reset failed logins for user %d: %v", user.ID, err)
+	}
+
+	return r.startSession(c, user.ID)
+}
+
+// completeLogin finishes a login whose first factor checked out: users
+// without two-factor login get a session, the others a challenge.
+func (r *AuthRouter) completeLogin(c *gin.Context, user *model.User) error {
+	if !user.HasTwoFactor() {
+		if err := model.ResetFailedLogins(r.db, user.ID); err != nil {
+			log.Printf("Failed to reset failed logins for user %d: %v", user.ID, err)
+		}
+		return r.startSession(c, user.ID)
+	}
+
+	ttl := r.cfg.Auth.LoginChallengeTTL
+	token, err := r.issueUserToken(user.ID, model.TokenPurposeLoginChallenge, ttl)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, entities.AuthTwoFactorChallengeResponse{
+		UserID:            user.ID,
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int(ttl.Seconds()),
+	})
+	return nil
+}
+
+// checkSecondFactor accepts a TOTP code that was not used before, or an
+// unused recovery code.
+func (r *AuthRouter) checkSecondFactor(user *model.User, code string, now time.Time) (bool, error) {
+	if step, ok := totp.Validate(user.TOTPSecret, code, now, totpSkew); ok {
+		return model.UseTOTPStep(r.db, user.ID, step)
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) == 0 {
+		return false, nil
+	}
+	return model.UseRecoveryCode(r.db, user.ID, utils.HashToken(normalized), now)
+}
+
+// twoFactorUser loads the current user, who must have two-factor login on,
+// and checks the code in the request body.
+func (r *AuthRouter) twoFactorUser(c *gin.Context) (*model.User, error) {
+	user, err := r.currentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := utils.GetBody[entities.AuthTwoFactorCodeBody](c)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.HasTwoFactor() {
+		return nil, errTwoFactorNotEnabled
+	}
+
+	valid, err := r.checkSecondFactor(user, body.Code, time.Now())
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	if !valid {
+		return nil, errInvalidSetupCode
+	}
+
+	return user, nil
+}
+
+func (r *AuthRouter) currentUser(c *gin.Context) (*model.User, error) {
+	user, err := model.GetUserByID(r.db, auth.GetCurrentUserID(c))
+	if err != nil {
+		return nil, middleware.ErrUnauthorized
+	}
+	return user, nil
+}
+
+// generateRecoveryCodes returns new codes formatted for display and the
+// hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	for i := range codes {
+		b := make([]byte, 10)
+		for j := range b {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, nil, err
+			}
+			b[j] = recoveryCodeAlphabet[n.Int64()]
+		}
+
+		raw := string(b)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = utils.HashToken(raw)
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+}
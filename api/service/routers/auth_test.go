@@ -0,0 +1,40 @@
+package routers
+
+import (
+	"go-api/internal/config"
+	"testing"
+	"time"
+)
+
+func TestLoginLockout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.LockoutThreshold = 5
+	cfg.Auth.LockoutBase = 30 * time.Second
+	cfg.Auth.LockoutMax = time.Hour
+	r := &AuthRouter{cfg: cfg}
+
+	for _, tt := range []struct {
+		count int
+		want  time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, 30 * time.Second},
+		{6, time.Minute},
+		{7, 2 * time.Minute},
+		{11, 32 * time.Minute},
+		{12, time.Hour},
+		{25, time.Hour},
+		// Shifting this far overflows; the lock stays at the maximum.
+		{100, time.Hour},
+	} {
+		if got := r.loginLockout(tt.count); got != tt.want {
+			t.Errorf("loginLockout(%d) = %s, want %s", tt.count, got, tt.want)
+		}
+	}
+
+	cfg.Auth.LockoutThreshold = 0
+	if got := r.loginLockout(100); got != 0 {
+		t.Errorf("disabled lockout = %s, want 0", got)
+	}
+}
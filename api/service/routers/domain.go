@@ -0,0 +1,276 @@
+package routers
+
+import (
+	"errors"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/domains"
+	"go-api/internal/middleware"
+	"go-api/internal/openapi"
+	"go-api/internal/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DomainRouter manages the custom domains links can be served on. Personal
+// domains belong to their user; workspace domains are managed by owners and
+// can be used by editors.
+type DomainRouter struct {
+	db       *gorm.DB
+	links    *model.ShortLinkCache
+	verifier *domains.Verifier
+}
+
+func NewDomainRouter(db *gorm.DB, links *model.ShortLinkCache, verifier *domains.Verifier) *DomainRouter {
+	return &DomainRouter{db: db, links: links, verifier: verifier}
+}
+
+func (r *DomainRouter) RegisterRouter(router *gin.RouterGroup, _ apiversion.Version) {
+	domainRouter := router.Group("/domains", middleware.AuthMiddleware(r.db), middleware.RequireSession())
+	{
+		domainRouter.GET("", apperr.Handle(r.ListDomains))
+		domainRouter.POST("", apperr.Handle(r.CreateDomain))
+		domainRouter.GET("/:id", apperr.Handle(r.GetDomain))
+		domainRouter.POST("/:id/verify", apperr.Handle(r.VerifyDomain))
+		domainRouter.DELETE("/:id", apperr.Handle(r.DeleteDomain))
+	}
+}
+
+func (r *DomainRouter) DescribeRoutes(spec *openapi.Group, _ apiversion.Version) {
+	domainRouter := spec.Group("/domains", "domains").Authenticated()
+	{
+		domainRouter.GET("", openapi.Op{
+			ID:        "listDomains",
+			Summary:   "List custom domains, personal or of a workspace",
+			Query:     entities.ShortenerScopeQuery{},
+			Responses: openapi.Responses{http.StatusOK: []entities.DomainResponse{}},
+		})
+		domainRouter.POST("", openapi.Op{
+			ID:          "createDomain",
+			Summary:     "Claim a custom domain",
+			Description: "The domain serves links once the TXT record in the response is published and verified.",
+			Query:       entities.ShortenerScopeQuery{},
+			Body:        entities.DomainBody{},
+			Responses:   openapi.Responses{http.StatusCreated: entities.DomainResponse{}},
+		})
+		domainRouter.GET("/:id", openapi.Op{
+			ID:        "getDomain",
+			Params:    entities.DomainParams{},
+			Responses: openapi.Responses{http.StatusOK: entities.DomainResponse{}},
+		})
+		domainRouter.POST("/:id/verify", openapi.Op{
+			ID:        "verifyDomain",
+			Summary:   "Look up the verification record of a domain",
+			Params:    entities.DomainParams{},
+			Responses: openapi.Responses{http.StatusOK: entities.DomainResponse{}},
+		})
+		domainRouter.DELETE("/:id", openapi.Op{
+			ID:        "deleteDomain",
+			Summary:   "Remove a domain and every link served on it",
+			Params:    entities.DomainParams{},
+			Responses: openapi.Responses{http.StatusNoContent: nil},
+		})
+	}
+}
+
+func (r *DomainRouter) ListDomains(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.ShortenerScopeQuery](c)
+	if err != nil {
+		return err
+	}
+
+	scope, err := linkScope(c, r.db, query.Workspace, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	list, err := model.ListDomains(r.db, scope)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := make([]entities.DomainResponse, len(list))
+	for i := range list {
+		response[i] = r.toDomainResponse(&list[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+// CreateDomain registers a hostname and answers with the TXT record that has
+// to be published before the domain can be verified.
+func (r *DomainRouter) CreateDomain(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.ShortenerScopeQuery](c)
+	if err != nil {
+		return err
+	}
+
+	scope, err := linkScope(c, r.db, query.Workspace, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.DomainBody](c)
+	if err != nil {
+		return err
+	}
+
+	hostname, err := r.verifier.Validate(body.Hostname)
+	if err != nil {
+		return errInvalidHostname.WithFields(apperr.FieldError{Field: "hostname", Code: "hostname", Message: err.Error()})
+	}
+
+	exists, err := model.ScopeHasDomain(r.db, scope, hostname)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if exists {
+		return errDomainExists
+	}
+
+	if _, err := model.GetVerifiedDomainByHostname(r.db, hostname); err == nil {
+		return errDomainTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperr.Internal(err)
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	domain := model.Domain{
+		Hostname:          hostname,
+		UserID:            scope.UserID,
+		WorkspaceID:       scope.WorkspaceID,
+		VerificationToken: token,
+	}
+	if err := model.CreateDomain(r.db, &domain); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusCreated, r.toDomainResponse(&domain))
+	return nil
+}
+
+func (r *DomainRouter) GetDomain(c *gin.Context) error {
+	domain, err := r.domain(c, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, r.toDomainResponse(domain))
+	return nil
+}
+
+// VerifyDomain looks up the domain's TXT record. Verified domains answer
+// right away; the check is only repeated while the domain is unverified.
+func (r *DomainRouter) VerifyDomain(c *gin.Context) error {
+	domain, err := r.domain(c, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	if domain.IsVerified() {
+		c.JSON(http.StatusOK, r.toDomainResponse(domain))
+		return nil
+	}
+
+	verified, err := r.verifier.Verify(c.Request.Context(), domain.Hostname, domain.VerificationToken)
+	if err != nil {
+		log.Printf("Domain verification lookup for %s failed: %v", domain.Hostname, err)
+		return errDomainLookup
+	}
+
+	err = model.MarkDomainChecked(r.db, domain, verified, time.Now())
+	if errors.Is(err, model.ErrDomainTaken) {
+		return errDomainTaken
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	if verified {
+		// The hostname may have been cached as not being a custom domain.
+		r.links.InvalidateHost(c.Request.Context(), domain.Hostname)
+	}
+
+	c.JSON(http.StatusOK, r.toDomainResponse(domain))
+	return nil
+}
+
+// DeleteDomain removes the domain together with every link served on it.
+func (r *DomainRouter) DeleteDomain(c *gin.Context) error {
+	domain, err := r.domain(c, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	links, err := model.DeleteDomain(r.db, domain)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	r.links.Invalidate(c.Request.Context(), links...)
+	r.links.InvalidateHost(c.Request.Context(), domain.Hostname)
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+// domain loads the domain named by the :id parameter. Personal domains are
+// only visible to their user, workspace domains to members with at least
+// role. Domains the user cannot see are reported as missing.
+func (r *DomainRouter) domain(c *gin.Context, role string) (*model.Domain, error) {
+	params, err := utils.GetParams[entities.DomainParams](c)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := auth.GetCurrentUserID(c)
+	domain, err := model.GetDomainByID(r.db, params.ID)
+	if err != nil {
+		return nil, errDomainNotFound
+	}
+
+	if domain.WorkspaceID == nil {
+		if domain.UserID != userID {
+			return nil, errDomainNotFound
+		}
+		return domain, nil
+	}
+
+	member, err := model.GetWorkspaceMember(r.db, *domain.WorkspaceID, userID)
+	if err != nil {
+		return nil, errDomainNotFound
+	}
+	if !member.Can(role) {
+		return nil, errWorkspaceRole
+	}
+
+	return domain, nil
+}
+
+func (r *DomainRouter) toDomainResponse(domain *model.Domain) entities.DomainResponse {
+	return entities.DomainResponse{
+		ID:            domain.ID,
+		Hostname:      domain.Hostname,
+		WorkspaceID:   domain.WorkspaceID,
+		Verified:      domain.IsVerified(),
+		VerifiedAt:    domain.VerifiedAt,
+		LastCheckedAt: domain.LastCheckedAt,
+		Verification: entities.DomainVerificationRecord{
+			Type:  "TXT",
+			Name:  r.verifier.RecordName(domain.Hostname),
+			Value: r.verifier.RecordValue(domain.VerificationToken),
+		},
+		CreatedAt: domain.CreatedAt,
+	}
+}
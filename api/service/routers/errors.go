@@ -0,0 +1,80 @@
+package routers
+
+import (
+	"go-api/internal/apperr"
+	"net/http"
+)
+
+// Errors the routers answer with. The codes are part of the API: clients
+// match on them, so they must not change once released.
+var (
+	errAccountExists      = apperr.BadRequest("account_exists", "Account already exists")
+	errInvalidCredentials = apperr.Unauthorized("invalid_credentials", "Invalid Credentials")
+	errAccountDisabled    = apperr.Forbidden("account_disabled", "Account is disabled")
+	errAccountLocked      = apperr.TooManyRequests("account_locked", "Too many failed logins, try again later")
+	errInvalidRefresh     = apperr.Unauthorized("invalid_refresh_token", "Invalid refresh token")
+	errInvalidToken       = apperr.BadRequest("invalid_token", "Invalid or expired token")
+	errEmailVerified      = apperr.Conflict("email_already_verified", "Email already verified")
+
+	errInvalidChallenge    = apperr.Unauthorized("invalid_challenge", "Invalid or expired challenge")
+	errInvalidCode         = apperr.Unauthorized("invalid_code", "Invalid code")
+	errInvalidSetupCode    = apperr.BadRequest("invalid_code", "Invalid code")
+	errTwoFactorEnabled    = apperr.Conflict("two_factor_enabled", "Two-factor authentication is already enabled")
+	errTwoFactorNotEnabled = apperr.Conflict("two_factor_not_enabled", "Two-factor authentication is not enabled")
+	errTwoFactorNoSetup    = apperr.BadRequest("two_factor_not_set_up", "Start the setup first")
+	errAPIKeyNotFound      = apperr.NotFound("api_key_not_found", "API key not found")
+
+	errUnknownProvider     = apperr.NotFound("unknown_identity_provider", "Unknown identity provider")
+	errProviderUnavailable = apperr.BadGateway("identity_provider_unavailable", "Identity provider is unavailable")
+	errProviderExchange    = apperr.BadGateway("identity_provider_failed", "Could not complete the login with the identity provider")
+	errProviderDenied      = apperr.Unauthorized("identity_provider_denied", "Login was denied by the identity provider")
+	errInvalidLoginState   = apperr.BadRequest("invalid_login_state", "Invalid login state")
+	errMissingAuthCode     = apperr.BadRequest("missing_authorization_code", "Missing authorization code")
+	errInvalidIDToken      = apperr.Unauthorized("invalid_id_token", "Invalid ID token")
+	errEmailNotVerifiedIdP = apperr.Forbidden("identity_email_not_verified", "The identity provider has not verified your email address")
+
+	errUserNotFound       = apperr.NotFound("user_not_found", "User not found")
+	errDisableSelf        = apperr.BadRequest("cannot_disable_self", "You cannot disable your own account")
+	errChangeOwnRole      = apperr.BadRequest("cannot_change_own_role", "You cannot change your own role")
+	errAlreadyDisabled    = apperr.Conflict("account_already_disabled", "Account is already disabled")
+	errNotDisabled        = apperr.Conflict("account_not_disabled", "Account is not disabled")
+	errAlreadyTakenDown   = apperr.Conflict("link_already_taken_down", "Link is already taken down")
+	errNotTakenDown       = apperr.Conflict("link_not_taken_down", "Link is not taken down")
+	errInvalidCursorParam = apperr.Validation(apperr.FieldError{Field: "cursor", Code: "cursor", Message: "is not a cursor returned by the API"})
+
+	errLinkNotFound    = apperr.NotFound("link_not_found", "Link not found")
+	errLinkPending     = apperr.NotFound("link_not_active", "Link is not active yet")
+	errLinkTakenDown   = apperr.Gone("link_taken_down", "Link was taken down")
+	errLinkExpired     = apperr.Gone("link_expired", "Link has expired")
+	errInvalidURL      = apperr.BadRequest("invalid_url", "Invalid URL")
+	errInvalidSlug     = apperr.BadRequest("invalid_slug", "Invalid slug")
+	errSlugTaken       = apperr.Conflict("slug_taken", "Slug already in use")
+	errLinkNotCreated  = apperr.BadRequest("link_not_created", "Invalid URL or url already exists")
+	errLinkConflict    = apperr.Conflict("link_conflict", "Could not create links, please retry")
+	errNoBulkRows      = apperr.BadRequest("no_rows", "No rows to import")
+	errInvalidBulkBody = apperr.BadRequest(apperr.CodeInvalidBody, "Invalid request body")
+	errBulkTooLarge    = apperr.New(http.StatusRequestEntityTooLarge, apperr.CodePayloadTooLarge, "The upload is too large")
+	errInvalidSchedule = apperr.BadRequest("invalid_schedule", "Invalid schedule")
+	errInvalidRange    = apperr.Validation(apperr.FieldError{Field: "from", Code: "range", Message: "must be before to"})
+	errTooManyBuckets  = apperr.BadRequest("too_many_buckets", "Requested range contains too many buckets for the interval")
+	errInvalidQRFg     = apperr.Validation(apperr.FieldError{Field: "fg", Code: "color", Message: "must be a hex colour like 1a2b3c"})
+	errInvalidQRBg     = apperr.Validation(apperr.FieldError{Field: "bg", Code: "color", Message: "must be a hex colour like 1a2b3c"})
+	errQRNoContrast    = apperr.Validation(apperr.FieldError{Field: "bg", Code: "contrast", Message: "must differ from fg"})
+
+	errDomainNotFound    = apperr.NotFound("domain_not_found", "Domain not found")
+	errDomainExists      = apperr.Conflict("domain_exists", "Domain already added")
+	errDomainTaken       = apperr.Conflict("domain_taken", "Domain is already verified by another account")
+	errInvalidHostname   = apperr.BadRequest("invalid_hostname", "Invalid hostname")
+	errUnknownDomain     = apperr.BadRequest("unknown_domain", "Unknown domain")
+	errDomainNotVerified = apperr.BadRequest("domain_not_verified", "Domain is not verified")
+	errDomainLookup      = apperr.BadGateway("domain_lookup_failed", "Could not look up the verification record, please retry")
+
+	errWorkspaceNotFound  = apperr.NotFound("workspace_not_found", "Workspace not found")
+	errWorkspaceRole      = apperr.Forbidden("workspace_role", "Your workspace role does not allow this")
+	errMemberNotFound     = apperr.NotFound("member_not_found", "Member not found")
+	errLastOwner          = apperr.Conflict("last_workspace_owner", "A workspace needs at least one owner")
+	errBlankName          = apperr.Validation(apperr.FieldError{Field: "name", Code: "blank", Message: "must not be blank"})
+	errInvitationNotFound = apperr.NotFound("invitation_not_found", "Invitation not found")
+	errInvalidInvitation  = apperr.BadRequest("invalid_invitation", "Invalid or expired invitation")
+	errInvitationEmail    = apperr.Forbidden("invitation_email_mismatch", "This invitation was sent to a different email address")
+)
@@ -2,51 +2,125 @@ package routers
 
 import (
 	"go-api/entities"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/health"
 	"go-api/internal/middleware"
+	"go-api/internal/openapi"
 	"go-api/internal/utils"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-type HealthRouter struct{}
+type HealthRouter struct {
+	db      *gorm.DB
+	checker *health.Checker
+}
+
+func NewHealthRouter(db *gorm.DB, checker *health.Checker) *HealthRouter {
+	return &HealthRouter{db: db, checker: checker}
+}
 
-func NewHealthRouter() *HealthRouter {
-	return &HealthRouter{}
+// RegisterBaseRoutes mounts the probes outside the versioned API, where
+// orchestrators expect them.
+func (r *HealthRouter) RegisterBaseRoutes(router *gin.Engine) {
+	router.GET("/healthz", r.GetLiveness)
+	router.GET("/readyz", r.GetReadiness)
 }
 
-func (r *HealthRouter) RegisterRouter(router *gin.RouterGroup) {
+func (r *HealthRouter) RegisterRouter(router *gin.RouterGroup, _ apiversion.Version) {
 	router.GET("/ping", r.GetHealth)
-	router.POST("/ping", r.PostHealth)
-	router.GET("/ping/:quantity", middleware.AuthMiddleware(), r.GetHealthWithParams)
+	router.POST("/ping", apperr.Handle(r.PostHealth))
+	router.GET("/ping/:quantity", middleware.AuthMiddleware(r.db), apperr.Handle(r.GetHealthWithParams))
+}
+
+func (r *HealthRouter) DescribeBaseRoutes(spec *openapi.Group) {
+	probes := spec.Group("", "health")
+	probes.GET("/healthz", openapi.Op{
+		ID:        "getLiveness",
+		Summary:   "Report that the process serves requests",
+		Responses: openapi.Responses{http.StatusOK: entities.HealthStatusResponse{}},
+	})
+	probes.GET("/readyz", openapi.Op{
+		ID:      "getReadiness",
+		Summary: "Report whether the instance and its dependencies are ready",
+		Responses: openapi.Responses{
+			http.StatusOK:                 health.Report{},
+			http.StatusServiceUnavailable: health.Report{},
+		},
+	})
+}
+
+func (r *HealthRouter) DescribeRoutes(spec *openapi.Group, _ apiversion.Version) {
+	ping := spec.Group("", "health")
+	ping.GET("/ping", openapi.Op{
+		ID:        "ping",
+		Summary:   "Check that the API answers",
+		Responses: openapi.Responses{http.StatusOK: entities.HealthMessageResponse{}},
+	})
+	ping.POST("/ping", openapi.Op{
+		ID:        "echo",
+		Summary:   "Echo a message",
+		Body:      entities.HealthPost{},
+		Responses: openapi.Responses{http.StatusOK: entities.HealthMessageResponse{}},
+	})
+	ping.Authenticated().GET("/ping/:quantity", openapi.Op{
+		ID:        "pingQuantity",
+		Summary:   "Echo a number from the path",
+		Params:    entities.HealthParams{},
+		Responses: openapi.Responses{http.StatusOK: entities.HealthQuantityResponse{}},
+	})
+}
+
+// GetLiveness only reports that the process serves requests. It does not
+// look at dependencies, so an outage of the database does not get every
+// instance restarted.
+func (r *HealthRouter) GetLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, entities.HealthStatusResponse{Status: health.StatusOK})
+}
+
+// GetReadiness reports whether the instance should receive traffic, with the
+// status of each dependency.
+func (r *HealthRouter) GetReadiness(c *gin.Context) {
+	report := r.checker.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
 }
 
 // Handler function that retrieves and uses validated data
 func (r *HealthRouter) GetHealth(c *gin.Context) {
 	// Use the validated data
-	c.JSON(http.StatusOK, gin.H{
-		"message": "pong",
+	c.JSON(http.StatusOK, entities.HealthMessageResponse{
+		Message: "pong",
 	})
 }
 
-func (r *HealthRouter) PostHealth(c *gin.Context) {
-	body, ok := utils.GetBody[entities.HealthPost](c)
-	if !ok {
-		return
+func (r *HealthRouter) PostHealth(c *gin.Context) error {
+	body, err := utils.GetBody[entities.HealthPost](c)
+	if err != nil {
+		return err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": body.Message,
+	c.JSON(http.StatusOK, entities.HealthMessageResponse{
+		Message: body.Message,
 	})
+	return nil
 }
 
-func (r *HealthRouter) GetHealthWithParams(c *gin.Context) {
-	params, ok := utils.GetParams[entities.HealthParams](c)
-	if !ok {
-		return
+func (r *HealthRouter) GetHealthWithParams(c *gin.Context) error {
+	params, err := utils.GetParams[entities.HealthParams](c)
+	if err != nil {
+		return err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": params.Quantity,
+	c.JSON(http.StatusOK, entities.HealthQuantityResponse{
+		Message: params.Quantity,
 	})
+	return nil
 }
@@ -1,76 +1,534 @@
 package routers
 
 import (
+	"errors"
 	"fmt"
 	"go-api/database/model"
 	"go-api/entities"
+	"go-api/internal/analytics"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
 	"go-api/internal/auth"
+	"go-api/internal/config"
+	"go-api/internal/domains"
+	"go-api/internal/metrics"
 	"go-api/internal/middleware"
+	"go-api/internal/openapi"
+	"go-api/internal/ratelimit"
+	"go-api/internal/shortcode"
+	"go-api/internal/urlcheck"
 	"go-api/internal/utils"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// maxCodeAttempts bounds how often a generated code is retried on collision.
+const maxCodeAttempts = 5
+
 type ShortenerRouter struct {
-	db *gorm.DB
+	db     *gorm.DB
+	links  *model.ShortLinkCache
+	clicks *analytics.Recorder
+	urls   *urlcheck.Validator
+	limits *ratelimit.Limiter
+	// redirects counts answers of the redirect endpoint by outcome.
+	redirects *metrics.CounterVec
+	cfg       *config.Config
 }
 
-func NewShortenerRouter(db *gorm.DB) *ShortenerRouter {
-	return &ShortenerRouter{db: db}
+func NewShortenerRouter(db *gorm.DB, links *model.ShortLinkCache, clicks *analytics.Recorder, urls *urlcheck.Validator, limits *ratelimit.Limiter, redirects *metrics.CounterVec, cfg *config.Config) *ShortenerRouter {
+	return &ShortenerRouter{
+		db:        db,
+		links:     links,
+		clicks:    clicks,
+		urls:      urls,
+		limits:    limits,
+		redirects: redirects,
+		cfg:       cfg,
+	}
 }
 
 func (r *ShortenerRouter) RegisterBaseRoutes(router *gin.Engine) {
-	router.GET("/short/:uid", r.GetShortener)
+	router.GET("/short/:code", middleware.RateLimit(r.limits, "redirect"), apperr.Handle(r.GetShortener))
+	router.GET("/short/:code/qr", middleware.RateLimit(r.limits, "qr"), apperr.Handle(r.GetShortLinkQR))
 }
 
-func (r *ShortenerRouter) RegisterRouter(router *gin.RouterGroup) {
-	router.POST("/short", middleware.AuthMiddleware(), r.PostShortener)
+func (r *ShortenerRouter) RegisterRouter(router *gin.RouterGroup, version apiversion.Version) {
+	authed := middleware.AuthMiddleware(r.db)
+	canRead := middleware.RequireScope(auth.ScopeRead)
+	canCreate := middleware.RequireScope(auth.ScopeLinksCreate)
+	canWrite := middleware.RequireScope(auth.ScopeLinksWrite)
+	verified := func(c *gin.Context) { c.Next() }
+	if r.cfg.Auth.RequireVerifiedEmail {
+		verified = middleware.RequireVerifiedEmail(r.db)
+	}
+
+	// v2 answers a created link with 201 and the link as GET returns it.
+	create := r.PostShortener
+	if version.Number >= 2 {
+		create = r.CreateShortLink
+	}
+
+	router.GET("/short", authed, canRead, apperr.Handle(r.ListShortLinks))
+	router.POST("/short", authed, canCreate, verified, middleware.RateLimit(r.limits, "shorten"), apperr.Handle(create))
+	router.POST("/short/bulk", authed, canCreate, verified, middleware.RateLimit(r.limits, "bulk"), apperr.Handle(r.PostShortenerBulk))
+	router.GET("/short/export", authed, canRead, apperr.Handle(r.GetShortenerExport))
+	router.GET("/short/:id", authed, canRead, apperr.Handle(r.GetShortLink))
+	router.PATCH("/short/:id", authed, canWrite, apperr.Handle(r.PatchShortener))
+	router.DELETE("/short/:id", authed, canWrite, apperr.Handle(r.DeleteShortener))
+	router.GET("/short/:id/stats", authed, canRead, apperr.Handle(r.GetShortenerStats))
+}
+
+func (r *ShortenerRouter) DescribeBaseRoutes(spec *openapi.Group) {
+	spec.Group("", "redirect").GET("/short/:code", openapi.Op{
+		ID:          "followShortLink",
+		Summary:     "Redirect to the destination of a short link",
+		Description: "Links that expired or reached their click limit answer 410, or redirect to the fallback URL when one is configured.",
+		Params:      entities.ShortenerParams{},
+		Responses:   openapi.Responses{http.StatusFound: openapi.Redirect{}},
+	})
+	spec.Group("", "links").GET("/short/:code/qr", openapi.Op{
+		ID:          "getShortLinkQR",
+		Summary:     "Draw a QR code of a short link",
+		Description: "Encodes the public short URL of the link, on the host the request was made to. Send the ETag back in If-None-Match to get 304 while the image is unchanged.",
+		Params:      entities.ShortenerParams{},
+		Query:       entities.ShortenerQRQuery{},
+		Responses: openapi.Responses{
+			http.StatusOK: openapi.Content{
+				"image/png":     openapi.File{},
+				"image/svg+xml": "",
+			},
+			http.StatusNotModified: nil,
+		},
+	})
 }
 
-func (r *ShortenerRouter) GetShortener(c *gin.Context) {
-	params, ok := utils.GetParams[entities.ShortenerParams](c)
-	if !ok {
-		return
+func (r *ShortenerRouter) DescribeRoutes(spec *openapi.Group, version apiversion.Version) {
+	links := spec.Group("", "links")
+	authed := links.Authenticated()
+
+	authed.GET("/short", openapi.Op{
+		ID:        "listShortLinks",
+		Summary:   "List short links, personal or of a workspace",
+		Query:     entities.ShortenerListQuery{},
+		Responses: openapi.Responses{http.StatusOK: entities.ShortLinkListResponse{}},
+	})
+	created := openapi.Responses{http.StatusOK: entities.ShortenerCreateResponse{}}
+	if version.Number >= 2 {
+		created = openapi.Responses{http.StatusCreated: entities.ShortLinkResponse{}}
+	}
+	authed.POST("/short", openapi.Op{
+		ID:        "createShortLink",
+		Summary:   "Shorten a URL",
+		Query:     entities.ShortenerScopeQuery{},
+		Body:      entities.ShortenerPost{},
+		Responses: created,
+	})
+	authed.POST("/short/bulk", openapi.Op{
+		ID:          "createShortLinks",
+		Summary:     "Shorten many URLs at once",
+		Description: "Takes a JSON array, a CSV file with a url header, or either uploaded as the file field of a form.",
+		Query:       entities.ShortenerBulkQuery{},
+		Body: openapi.Content{
+			"application/json": []entities.ShortenerPost{},
+			"text/csv":         "",
+			"multipart/form-data": struct {
+				File openapi.File `json:"file" binding:"required"`
+			}{},
+		},
+		Responses: openapi.Responses{http.StatusOK: entities.ShortenerBulkResponse{}},
+	})
+	authed.GET("/short/export", openapi.Op{
+		ID:      "exportShortLinks",
+		Summary: "Download every link as CSV or newline delimited JSON",
+		Query:   entities.ShortenerExportQuery{},
+		Responses: openapi.Responses{http.StatusOK: openapi.Content{
+			"text/csv":             "",
+			"application/x-ndjson": entities.ShortenerExportRow{},
+		}},
+	})
+	authed.GET("/short/:id", openapi.Op{
+		ID:        "getShortLink",
+		Params:    entities.ShortenerIDParams{},
+		Responses: openapi.Responses{http.StatusOK: entities.ShortLinkResponse{}},
+	})
+	authed.PATCH("/short/:id", openapi.Op{
+		ID:          "updateShortLink",
+		Summary:     "Change a short link",
+		Description: "Only the fields present are changed. The schedule fields take null to remove the limit.",
+		Params:      entities.ShortenerIDParams{},
+		Body:        entities.ShortenerPatch{},
+		Responses:   openapi.Responses{http.StatusOK: entities.ShortLinkResponse{}},
+	})
+	authed.DELETE("/short/:id", openapi.Op{
+		ID:        "deleteShortLink",
+		Params:    entities.ShortenerIDParams{},
+		Responses: openapi.Responses{http.StatusNoContent: nil},
+	})
+	authed.GET("/short/:id/stats", openapi.Op{
+		ID:        "getShortLinkStats",
+		Summary:   "Count the clicks of a link over time",
+		Params:    entities.ShortenerIDParams{},
+		Query:     entities.ShortenerStatsQuery{},
+		Responses: openapi.Responses{http.StatusOK: entities.ShortenerStatsResponse{}},
+	})
+}
+
+func (r *ShortenerRouter) GetShortener(c *gin.Context) error {
+	params, err := utils.GetParams[entities.ShortenerParams](c)
+	if err != nil {
+		return err
 	}
 
-	shortUrl, err := model.GetShortLinkByID(r.db, uint(params.UID))
+	// Links on custom domains are looked up by host, every other host serves
+	// the default domain.
+	domainID, err := r.links.ResolveHost(c.Request.Context(), domains.HostOnly(c.Request.Host))
+	if err != nil {
+		r.redirects.Inc("error")
+		return apperr.Internal(err)
+	}
+
+	shortUrl, err := r.links.Resolve(c.Request.Context(), domainID, params.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && shortUrl == nil) {
+		r.redirects.Inc("not_found")
+		return errLinkNotFound
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, "Invalid URL")
-		return
+		r.redirects.Inc("error")
+		return apperr.Internal(err)
 	}
 
-	if shortUrl == nil {
-		c.JSON(http.StatusBadRequest, "Invalid URL")
-		return
+	now := time.Now()
+	if shortUrl.IsPending(now) {
+		r.redirects.Inc("pending")
+		return errLinkPending
 	}
 
-	c.Redirect(http.StatusMovedPermanently, shortUrl.URL)
-	return
+	if shortUrl.IsTakenDown() {
+		r.redirects.Inc("taken_down")
+		return errLinkTakenDown
+	}
+
+	if shortUrl.Disabled || shortUrl.IsExpired(now) || shortUrl.IsExhausted() {
+		return r.gone(c)
+	}
+
+	if shortUrl.MaxClicks > 0 {
+		counted, err := model.RecordShortLinkClick(r.db, shortUrl.ID)
+		if err != nil {
+			r.redirects.Inc("error")
+			return apperr.Internal(err)
+		}
+		if !counted {
+			return r.gone(c)
+		}
+	}
+
+	r.clicks.Record(analytics.Click{
+		ShortLinkID: shortUrl.ID,
+		At:          now,
+		Referrer:    c.Request.Referer(),
+		UserAgent:   c.Request.UserAgent(),
+		IP:          c.ClientIP(),
+	})
+
+	// A temporary redirect keeps browsers from caching the target, so every
+	// visit passes the expiry and click checks and shows up in the stats.
+	r.redirects.Inc("redirected")
+	c.Redirect(http.StatusFound, shortUrl.URL)
+	return nil
 }
 
-func (r *ShortenerRouter) PostShortener(c *gin.Context) {
-	body, ok := utils.GetBody[entities.ShortenerPost](c)
-	if !ok {
-		return
+// gone answers for links that were disabled, expired or ran out of clicks, either by
+// redirecting to the configured fallback URL or with 410 Gone.
+func (r *ShortenerRouter) gone(c *gin.Context) error {
+	r.redirects.Inc("gone")
+	if r.cfg.Shortener.FallbackURL != "" {
+		c.Redirect(http.StatusFound, r.cfg.Shortener.FallbackURL)
+		return nil
 	}
 
-	userId := auth.GetCurrentUserID(c)
+	return errLinkExpired
+}
+
+// PostShortener answers a created link with a summary of it, as v1 does.
+func (r *ShortenerRouter) PostShortener(c *gin.Context) error {
+	data, host, err := r.createShortLink(c)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, entities.ShortenerCreateResponse{
+		LongUrl:  data.URL,
+		Code:     data.Code,
+		ShortUrl: shortURL(c, host, data.Code),
+
+		ActivatesAt: data.ActivatesAt,
+		ExpiresAt:   data.ExpiresAt,
+		MaxClicks:   data.MaxClicks,
+		WorkspaceID: data.WorkspaceID,
+		DomainID:    data.DomainID,
+	})
+	return nil
+}
+
+// CreateShortLink answers a created link with 201, its location and the
+// link as GetShortLink returns it, from v2 on.
+func (r *ShortenerRouter) CreateShortLink(c *gin.Context) error {
+	data, host, err := r.createShortLink(c)
+	if err != nil {
+		return err
+	}
+
+	c.Header("Location", fmt.Sprintf("%s/short/%d", middleware.GetAPIVersion(c).Prefix(), data.ID))
+	c.JSON(http.StatusCreated, toShortLinkResponse(c, data, host))
+	return nil
+}
+
+// createShortLink creates the link described by the request and returns
+// it with the hostname of its domain.
+func (r *ShortenerRouter) createShortLink(c *gin.Context) (*model.ShortLink, string, error) {
+	query, err := utils.GetSearchParams[entities.ShortenerScopeQuery](c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scope, err := linkScope(c, r.db, query.Workspace, model.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := utils.GetBody[entities.ShortenerPost](c)
+	if err != nil {
+		return nil, "", err
+	}
+
+	destination, err := r.urls.Validate(c.Request.Context(), body.Url, c.Request.Host)
+	if err != nil {
+		return nil, "", invalidURL(err)
+	}
+
+	if err := validateSchedule(body.ActivatesAt, body.ExpiresAt, time.Now()); err != nil {
+		return nil, "", errInvalidSchedule.WithMessage(err.Error())
+	}
+
+	domain, err := r.linkDomain(c, body.DomainID, scope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	code, err := r.pickCode(body.DomainID, body.Slug)
+	if err != nil {
+		return nil, "", err
+	}
 
 	shortUrl := model.ShortLink{
-		UserID: int(userId),
-		URL:    body.Url,
+		UserID:      int(scope.UserID),
+		WorkspaceID: scope.WorkspaceID,
+		DomainID:    body.DomainID,
+		Code:        code,
+		URL:         destination,
+
+		ActivatesAt: body.ActivatesAt,
+		ExpiresAt:   body.ExpiresAt,
+		MaxClicks:   body.MaxClicks,
 	}
 
 	data, err := model.CreateShortLink(r.db, &shortUrl)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Claimed by another request since pickCode looked.
+		return nil, "", errSlugTaken
+	}
+	if err != nil {
+		return nil, "", errLinkNotCreated
+	}
+
+	// The code may have been cached as unknown before it was claimed.
+	r.links.Invalidate(c.Request.Context(), *data)
+
+	return data, domain.Hostname, nil
+}
+
+func (r *ShortenerRouter) GetShortenerStats(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.ShortenerStatsQuery](c)
+	if err != nil {
+		return err
+	}
+
+	link, err := r.accessibleLink(c, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	if query.Interval == "" {
+		query.Interval = "day"
+	}
+	if query.Referrers == 0 {
+		query.Referrers = 10
+	}
+
+	from, to := analytics.DefaultRange(query.Interval, time.Now())
+	if !query.From.IsZero() {
+		from = query.From
+	}
+	if !query.To.IsZero() {
+		to = query.To
+	}
+	if !from.Before(to) {
+		return errInvalidRange
+	}
+
+	width := analytics.IntervalDuration(query.Interval)
+	buckets, err := analytics.Series(from, to, width)
+	if errors.Is(err, analytics.ErrTooManyBuckets) {
+		return errTooManyBuckets
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, "Invalid URL or url already exists")
-		return
+		return apperr.Internal(err)
+	}
+
+	counts, err := model.CountClicksByBucket(r.db, link.ID, from, to, buckets[0].Start, width)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	var totalClicks int64
+	for i, count := range counts {
+		if i >= 0 && i < len(buckets) {
+			buckets[i].Count = count
+		}
+		totalClicks += count
+	}
+
+	uniqueVisitors, err := model.CountUniqueVisitors(r.db, link.ID, from, to)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	referrers, err := model.GetTopReferrers(r.db, link.ID, from, to, query.Referrers)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := entities.ShortenerStatsResponse{
+		ID:             link.ID,
+		Code:           link.Code,
+		From:           from.UTC(),
+		To:             to.UTC(),
+		Interval:       query.Interval,
+		TotalClicks:    int(totalClicks),
+		UniqueVisitors: uniqueVisitors,
+		Buckets:        make([]entities.ShortenerStatsBucket, len(buckets)),
+		TopReferrers:   make([]entities.ShortenerReferrerCount, len(referrers)),
+	}
+	for i, b := range buckets {
+		response.Buckets[i] = entities.ShortenerStatsBucket{Start: b.Start, Count: b.Count}
+	}
+	for i, ref := range referrers {
+		response.TopReferrers[i] = entities.ShortenerReferrerCount{Referrer: ref.Referrer, Count: ref.Count}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"longUrl":  data.URL,
-		"shortUrl": fmt.Sprintf("%s://%s/short/%d", utils.GetProtocol(c), c.Request.Host, data.ID),
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+// invalidURL reports the structured reason a destination was rejected.
+func invalidURL(err error) error {
+	if errors.Is(err, urlcheck.ErrHostLookup) {
+		return apperr.Internal(err)
+	}
+
+	var checkErr *urlcheck.Error
+	if !errors.As(err, &checkErr) {
+		return errInvalidURL
+	}
+
+	return errInvalidURL.WithMessage(checkErr.Message).WithFields(apperr.FieldError{
+		Field:   checkErr.Field,
+		Code:    checkErr.Code,
+		Message: checkErr.Message,
 	})
 }
+
+func validateSchedule(activatesAt, expiresAt *time.Time, now time.Time) error {
+	return validateScheduleChange(activatesAt, expiresAt, true, now)
+}
+
+// validateScheduleChange checks the schedule of a link after a change. The
+// expiry only has to lie in the future when the change sets it, so links
+// that already expired can still be edited; either way it has to come after
+// the activation.
+func validateScheduleChange(activatesAt, expiresAt *time.Time, expiresChanged bool, now time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+
+	if expiresChanged && !expiresAt.After(now) {
+		return errors.New("expiresAt must be in the future")
+	}
+
+	if activatesAt != nil && !expiresAt.After(*activatesAt) {
+		return errors.New("expiresAt must be after activatesAt")
+	}
+
+	return nil
+}
+
+// linkDomain loads the domain a new link should be served on. The domain
+// must be verified and belong to scope. A zero domainID selects the default
+// host and yields an empty domain.
+func (r *ShortenerRouter) linkDomain(c *gin.Context, domainID uint, scope model.ShortLinkScope) (*model.Domain, error) {
+	if domainID == 0 {
+		return &model.Domain{}, nil
+	}
+
+	domain, err := model.GetDomainByID(r.db, domainID)
+	if err != nil || !domain.InScope(scope) {
+		return nil, errUnknownDomain
+	}
+	if !domain.IsVerified() {
+		return nil, errDomainNotVerified
+	}
+
+	return domain, nil
+}
+
+// pickCode validates a requested vanity slug or generates a fresh code that is
+// not in use on the domain yet.
+func (r *ShortenerRouter) pickCode(domainID uint, slug string) (string, error) {
+	if slug != "" {
+		if err := shortcode.ValidateSlug(slug); err != nil {
+			return "", errInvalidSlug.WithFields(apperr.FieldError{Field: "slug", Code: "slug", Message: err.Error()})
+		}
+
+		exists, err := model.ShortLinkCodeExists(r.db, domainID, slug)
+		if err != nil {
+			return "", apperr.Internal(err)
+		}
+		if exists {
+			return "", errSlugTaken
+		}
+
+		return slug, nil
+	}
+
+	for range maxCodeAttempts {
+		code, err := shortcode.Generate(shortcode.DefaultLength)
+		if err != nil {
+			return "", apperr.Internal(err)
+		}
+
+		exists, err := model.ShortLinkCodeExists(r.db, domainID, code)
+		if err != nil {
+			return "", apperr.Internal(err)
+		}
+		if !exists {
+			return code, nil
+		}
+	}
+
+	return "", apperr.Internal(errors.New("could not generate a unique code"))
+}
@@ -0,0 +1,614 @@
+package routers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/shortcode"
+	"go-api/internal/urlcheck"
+	"go-api/internal/utils"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+const (
+	maxBulkRows  = 5000
+	maxBulkBytes = 10 << 20
+)
+
+// bulkCSVColumns are the accepted CSV header names, matched case-insensitively.
+var bulkCSVColumns = []string{"url", "slug", "expiresAt", "activatesAt", "maxClicks", "domainId"}
+
+var errTooManyRows = fmt.Errorf("a bulk request may contain at most %d rows", maxBulkRows)
+
+// domainCode identifies a code on a domain, which is what has to be unique.
+type domainCode struct {
+	domainID uint
+	code     string
+}
+
+func (r *ShortenerRouter) PostShortenerBulk(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.ShortenerBulkQuery](c)
+	if err != nil {
+		return err
+	}
+	if query.Mode == "" {
+		query.Mode = "atomic"
+	}
+
+	scope, err := linkScope(c, r.db, query.Workspace, model.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkBytes)
+
+	rows, rowErrors, err := readBulkRows(c)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return errBulkTooLarge
+	}
+	if err != nil {
+		return errInvalidBulkBody.WithMessage(err.Error())
+	}
+	if len(rows)+len(rowErrors) == 0 {
+		return errNoBulkRows
+	}
+
+	now := time.Now()
+
+	// Rows are validated one by one first, then slugs are checked against
+	// each other and against the database in one query per domain.
+	valid := make(map[int]entities.ShortenerPost, len(rows))
+	slugRows := make(map[domainCode]int)
+	hosts := map[uint]string{}
+	for row, body := range rows {
+		destination, err := r.validateBulkRow(c.Request.Context(), body, c.Request.Host, now)
+		if errors.Is(err, urlcheck.ErrHostLookup) {
+			return apperr.Internal(err)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, bulkRowError(row, err))
+			continue
+		}
+		body.Url = destination
+
+		if _, ok := hosts[body.DomainID]; !ok && body.DomainID != 0 {
+			domain, err := model.GetDomainByID(r.db, body.DomainID)
+			switch {
+			case err != nil || !domain.InScope(scope):
+				rowErrors = append(rowErrors, entities.ShortenerBulkRowError{Row: row, Field: "domainId", Message: "Unknown domain"})
+				continue
+			case !domain.IsVerified():
+				rowErrors = append(rowErrors, entities.ShortenerBulkRowError{Row: row, Field: "domainId", Message: "Domain is not verified"})
+				continue
+			}
+			hosts[domain.ID] = domain.Hostname
+		}
+
+		if body.Slug != "" {
+			key := domainCode{domainID: body.DomainID, code: body.Slug}
+			if first, ok := slugRows[key]; ok {
+				rowErrors = append(rowErrors, entities.ShortenerBulkRowError{
+					Row:     row,
+					Field:   "slug",
+					Message: fmt.Sprintf("slug already used in row %d", first),
+				})
+				continue
+			}
+			slugRows[key] = row
+		}
+
+		valid[row] = body
+	}
+
+	slugs := make(map[uint][]string)
+	for key := range slugRows {
+		slugs[key.domainID] = append(slugs[key.domainID], key.code)
+	}
+	for domainID, codes := range slugs {
+		taken, err := model.ExistingShortLinkCodes(r.db, domainID, codes)
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		for _, code := range codes {
+			if taken[code] {
+				row := slugRows[domainCode{domainID: domainID, code: code}]
+				delete(valid, row)
+				rowErrors = append(rowErrors, entities.ShortenerBulkRowError{Row: row, Field: "slug", Message: "Slug already in use"})
+			}
+		}
+	}
+
+	if rowErrors == nil {
+		rowErrors = []entities.ShortenerBulkRowError{}
+	}
+	sortRowErrors(rowErrors)
+
+	response := entities.ShortenerBulkResponse{
+		Mode:    query.Mode,
+		Created: []entities.ShortenerBulkCreated{},
+		Errors:  rowErrors,
+	}
+
+	if query.Mode == "atomic" && len(rowErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return nil
+	}
+
+	order := make([]int, 0, len(valid))
+	for row := range valid {
+		order = append(order, row)
+	}
+	slices.Sort(order)
+
+	links := make([]model.ShortLink, len(order))
+	var needCodes []int
+	for i, row := range order {
+		body := valid[row]
+		links[i] = model.ShortLink{
+			UserID:      int(scope.UserID),
+			WorkspaceID: scope.WorkspaceID,
+			DomainID:    body.DomainID,
+			Code:        body.Slug,
+			URL:         body.Url,
+			ActivatesAt: body.ActivatesAt,
+			ExpiresAt:   body.ExpiresAt,
+			MaxClicks:   body.MaxClicks,
+		}
+		if body.Slug == "" {
+			needCodes = append(needCodes, i)
+		}
+	}
+
+	if err := r.assignCodes(links, needCodes); err != nil {
+		return apperr.Internal(err)
+	}
+
+	// Partial imports insert row by row, so a slug taken since the check
+	// only fails its own row.
+	created := make([]bool, len(links))
+	if query.Mode == "partial" {
+		for i, err := range model.CreateShortLinksEach(r.db, links) {
+			if err == nil {
+				created[i] = true
+				continue
+			}
+			response.Errors = append(response.Errors, insertRowError(order[i], valid[order[i]], err))
+		}
+		sortRowErrors(response.Errors)
+	} else {
+		if err := model.CreateShortLinks(r.db, links); err != nil {
+			return errLinkConflict
+		}
+		for i := range created {
+			created[i] = true
+		}
+	}
+
+	inserted := make([]model.ShortLink, 0, len(links))
+	for i, link := range links {
+		if !created[i] {
+			continue
+		}
+		inserted = append(inserted, link)
+		response.Created = append(response.Created, entities.ShortenerBulkCreated{
+			Row:      order[i],
+			ID:       link.ID,
+			Code:     link.Code,
+			ShortUrl: shortURL(c, hosts[link.DomainID], link.Code),
+		})
+	}
+	r.links.Invalidate(c.Request.Context(), inserted...)
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+func (r *ShortenerRouter) GetShortenerExport(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.ShortenerExportQuery](c)
+	if err != nil {
+		return err
+	}
+	if query.Format == "" {
+		query.Format = "csv"
+	}
+
+	scope, err := linkScope(c, r.db, query.Workspace, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	// Links can only be served on domains of their own scope.
+	scopeDomains, err := model.ListDomains(r.db, scope)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	hosts := make(map[uint]string, len(scopeDomains))
+	for _, domain := range scopeDomains {
+		hosts[domain.ID] = domain.Hostname
+	}
+
+	filename := fmt.Sprintf("short-links-%s.%s", time.Now().UTC().Format("20060102-150405"), query.Format)
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if query.Format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Status(http.StatusOK)
+
+	w := bufio.NewWriter(c.Writer)
+	var write func(entities.ShortenerExportRow) error
+
+	if query.Format == "csv" {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "code", "url", "shortUrl", "activatesAt", "expiresAt", "maxClicks", "disabled", "totalClicks", "createdAt", "domainId"})
+		write = func(row entities.ShortenerExportRow) error {
+			cw.Write([]string{
+				strconv.FormatUint(uint64(row.ID), 10),
+				row.Code,
+				row.Url,
+				row.ShortUrl,
+				formatOptionalTime(row.ActivatesAt),
+				formatOptionalTime(row.ExpiresAt),
+				strconv.Itoa(row.MaxClicks),
+				strconv.FormatBool(row.Disabled),
+				strconv.FormatInt(row.TotalClicks, 10),
+				row.CreatedAt.UTC().Format(time.RFC3339),
+				strconv.FormatUint(uint64(row.DomainID), 10),
+			})
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		enc := json.NewEncoder(w)
+		write = func(row entities.ShortenerExportRow) error {
+			return enc.Encode(row)
+		}
+	}
+
+	var count int
+	err = model.ExportShortLinks(r.db, scope, func(link model.ShortLinkExport) error {
+		err := write(entities.ShortenerExportRow{
+			ID:          link.ID,
+			Code:        link.Code,
+			Url:         link.URL,
+			ShortUrl:    shortURL(c, hosts[link.DomainID], link.Code),
+			ActivatesAt: link.ActivatesAt,
+			ExpiresAt:   link.ExpiresAt,
+			MaxClicks:   link.MaxClicks,
+			Disabled:    link.Disabled,
+			TotalClicks: link.TotalClicks,
+			CreatedAt:   link.CreatedAt,
+			DomainID:    link.DomainID,
+		})
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%500 == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent, all we can do is cut the stream short.
+		return err
+	}
+
+	w.Flush()
+	c.Writer.Flush()
+	return nil
+}
+
+// assignCodes generates codes for links[i] for every i in indexes that are
+// unused on the link's domain.
+func (r *ShortenerRouter) assignCodes(links []model.ShortLink, indexes []int) error {
+	seen := make(map[domainCode]bool, len(links))
+	for _, link := range links {
+		if link.Code != "" {
+			seen[domainCode{domainID: link.DomainID, code: link.Code}] = true
+		}
+	}
+
+	for attempt := 0; len(indexes) > 0; attempt++ {
+		if attempt == maxCodeAttempts {
+			return errors.New("could not generate unique codes")
+		}
+
+		codes := make(map[uint][]string)
+		for _, idx := range indexes {
+			code, err := shortcode.Generate(shortcode.DefaultLength)
+			if err != nil {
+				return err
+			}
+			links[idx].Code = code
+			codes[links[idx].DomainID] = append(codes[links[idx].DomainID], code)
+		}
+
+		taken := make(map[domainCode]bool)
+		for domainID, domainCodes := range codes {
+			existing, err := model.ExistingShortLinkCodes(r.db, domainID, domainCodes)
+			if err != nil {
+				return err
+			}
+			for code := range existing {
+				taken[domainCode{domainID: domainID, code: code}] = true
+			}
+		}
+
+		var retry []int
+		for _, idx := range indexes {
+			key := domainCode{domainID: links[idx].DomainID, code: links[idx].Code}
+			if taken[key] || seen[key] {
+				retry = append(retry, idx)
+				continue
+			}
+			seen[key] = true
+		}
+		indexes = retry
+	}
+
+	return nil
+}
+
+// readBulkRows decodes the request into rows keyed by their 1-based position.
+// Rows that cannot even be decoded are reported as row errors.
+func readBulkRows(c *gin.Context) (map[int]entities.ShortenerPost, []entities.ShortenerBulkRowError, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+
+	switch mediaType {
+	case "multipart/form-data":
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, nil, errors.New("missing file upload")
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return nil, nil, err
+		}
+		defer file.Close()
+
+		if strings.EqualFold(filepath.Ext(header.Filename), ".json") {
+			return readBulkJSON(file)
+		}
+		return readBulkCSV(file)
+	case "text/csv":
+		return readBulkCSV(c.Request.Body)
+	default:
+		return readBulkJSON(c.Request.Body)
+	}
+}
+
+func readBulkJSON(r io.Reader) (map[int]entities.ShortenerPost, []entities.ShortenerBulkRowError, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, nil, errors.New("Invalid request body: expected a JSON array")
+	}
+	if len(raw) > maxBulkRows {
+		return nil, nil, errTooManyRows
+	}
+
+	rows := make(map[int]entities.ShortenerPost, len(raw))
+	var rowErrors []entities.ShortenerBulkRowError
+	for i, item := range raw {
+		var body entities.ShortenerPost
+		if err := json.Unmarshal(item, &body); err != nil {
+			rowErrors = append(rowErrors, entities.ShortenerBulkRowError{Row: i + 1, Message: "invalid JSON object: " + err.Error()})
+			continue
+		}
+		rows[i+1] = body
+	}
+
+	return rows, rowErrors, nil
+}
+
+func readBulkCSV(r io.Reader) (map[int]entities.ShortenerPost, []entities.ShortenerBulkRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("Invalid CSV: missing header row")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		known := false
+		for _, column := range bulkCSVColumns {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				columns[column] = i
+				known = true
+			}
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("Invalid CSV: unknown column %q, expected %s", name, strings.Join(bulkCSVColumns, ", "))
+		}
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, nil, errors.New("Invalid CSV: missing url column")
+	}
+
+	rows := make(map[int]entities.ShortenerPost)
+	var rowErrors []entities.ShortenerBulkRowError
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		if row > maxBulkRows {
+			return nil, nil, errTooManyRows
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		body := entities.ShortenerPost{
+			Url:  field("url"),
+			Slug: field("slug"),
+		}
+
+		var fieldErr *entities.ShortenerBulkRowError
+		for _, name := range []string{"activatesAt", "expiresAt"} {
+			value := field(name)
+			if value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				fieldErr = &entities.ShortenerBulkRowError{Row: row, Field: name, Message: "must be an RFC 3339 timestamp"}
+				break
+			}
+			if name == "activatesAt" {
+				body.ActivatesAt = &t
+			} else {
+				body.ExpiresAt = &t
+			}
+		}
+		if value := field("domainId"); value != "" {
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				fieldErr = &entities.ShortenerBulkRowError{Row: row, Field: "domainId", Message: "must be a domain ID"}
+			}
+			body.DomainID = uint(n)
+		}
+		if value := field("maxClicks"); fieldErr == nil && value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				fieldErr = &entities.ShortenerBulkRowError{Row: row, Field: "maxClicks", Message: "must be an integer"}
+			}
+			body.MaxClicks = n
+		}
+
+		if fieldErr != nil {
+			rowErrors = append(rowErrors, *fieldErr)
+			continue
+		}
+		rows[row] = body
+	}
+
+	return rows, rowErrors, nil
+}
+
+// validateBulkRow applies the same rules as PostShortener to a single row
+// and returns the normalized destination. Redirect chains are not followed
+// for bulk imports as that would mean thousands of outgoing requests.
+func (r *ShortenerRouter) validateBulkRow(ctx context.Context, body entities.ShortenerPost, host string, now time.Time) (string, error) {
+	if err := binding.Validator.ValidateStruct(&body); err != nil {
+		return "", err
+	}
+
+	destination, err := r.urls.ValidateStatic(ctx, body.Url, host)
+	if err != nil {
+		return "", err
+	}
+
+	if body.Slug != "" {
+		if err := shortcode.ValidateSlug(body.Slug); err != nil {
+			return "", fieldError{field: "slug", err: err}
+		}
+	}
+
+	if err := validateSchedule(body.ActivatesAt, body.ExpiresAt, now); err != nil {
+		return "", fieldError{field: "expiresAt", err: err}
+	}
+
+	return destination, nil
+}
+
+// insertRowError reports why a row that passed validation was not inserted.
+func insertRowError(row int, body entities.ShortenerPost, err error) entities.ShortenerBulkRowError {
+	duplicate := errors.Is(err, gorm.ErrDuplicatedKey)
+	if duplicate && body.Slug != "" {
+		return entities.ShortenerBulkRowError{Row: row, Field: "slug", Message: "Slug already in use"}
+	}
+	if !duplicate {
+		log.Printf("Bulk import failed to insert row %d: %v", row, err)
+	}
+	return entities.ShortenerBulkRowError{Row: row, Message: "Could not create link, please retry"}
+}
+
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e fieldError) Error() string { return e.err.Error() }
+
+func bulkRowError(row int, err error) entities.ShortenerBulkRowError {
+	var checkErr *urlcheck.Error
+	if errors.As(err, &checkErr) {
+		return entities.ShortenerBulkRowError{Row: row, Field: checkErr.Field, Code: checkErr.Code, Message: checkErr.Message}
+	}
+
+	var fe fieldError
+	if errors.As(err, &fe) {
+		return entities.ShortenerBulkRowError{Row: row, Field: fe.field, Message: fe.Error()}
+	}
+
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) && len(validationErrors) > 0 {
+		first := validationErrors[0]
+		return entities.ShortenerBulkRowError{
+			Row:     row,
+			Field:   lowerFirst(first.Field()),
+			Message: fmt.Sprintf("failed on the '%s' rule", first.Tag()),
+		}
+	}
+
+	return entities.ShortenerBulkRowError{Row: row, Message: err.Error()}
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func sortRowErrors(rowErrors []entities.ShortenerBulkRowError) {
+	slices.SortStableFunc(rowErrors, func(a, b entities.ShortenerBulkRowError) int {
+		return a.Row - b.Row
+	})
+}
@@ -0,0 +1,281 @@
+package routers
+
+import (
+	"errors"
+	"fmt"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/utils"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultListLimit = 20
+	defaultListSort  = "-createdAt"
+)
+
+func (r *ShortenerRouter) ListShortLinks(c *gin.Context) error {
+	query, err := utils.GetSearchParams[entities.ShortenerListQuery](c)
+	if err != nil {
+		return err
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultListLimit
+	}
+	if query.Sort == "" {
+		query.Sort = defaultListSort
+	}
+
+	var cursor *model.Cursor
+	if query.Cursor != "" {
+		decoded, err := model.DecodeCursor(query.Cursor)
+		if err != nil {
+			return errInvalidCursorParam
+		}
+		cursor = decoded
+	}
+
+	scope, err := linkScope(c, r.db, query.Workspace, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	links, next, err := model.ListShortLinks(r.db, model.ListShortLinksQuery{
+		Scope:  scope,
+		Search: query.Search,
+		Sort:   query.Sort,
+		Cursor: cursor,
+		Limit:  query.Limit,
+	})
+	if errors.Is(err, model.ErrInvalidCursor) {
+		return errInvalidCursorParam
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	hosts, err := linkHosts(r.db, links...)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := entities.ShortLinkListResponse{
+		Items: make([]entities.ShortLinkResponse, len(links)),
+	}
+	for i := range links {
+		response.Items[i] = toShortLinkResponse(c, &links[i], hosts[links[i].DomainID])
+	}
+	if next != nil {
+		response.NextCursor = model.EncodeCursor(*next)
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+func (r *ShortenerRouter) GetShortLink(c *gin.Context) error {
+	link, err := r.accessibleLink(c, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	return respondShortLink(c, r.db, link)
+}
+
+func (r *ShortenerRouter) PatchShortener(c *gin.Context) error {
+	link, err := r.accessibleLink(c, model.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.ShortenerPatch](c)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]any{}
+
+	if body.Url != nil {
+		destination, err := r.urls.Validate(c.Request.Context(), *body.Url, c.Request.Host)
+		if err != nil {
+			return invalidURL(err)
+		}
+		updates["url"] = destination
+	}
+
+	if body.Slug != nil && *body.Slug != link.Code {
+		code, err := r.pickCode(link.DomainID, *body.Slug)
+		if err != nil {
+			return err
+		}
+		updates["code"] = code
+	}
+
+	activatesAt, expiresAt := link.ActivatesAt, link.ExpiresAt
+	if body.ActivatesAt.Set {
+		activatesAt = body.ActivatesAt.Value
+		updates["activates_at"] = activatesAt
+	}
+	if body.ExpiresAt.Set {
+		expiresAt = body.ExpiresAt.Value
+		updates["expires_at"] = expiresAt
+	}
+	if body.ActivatesAt.Set || body.ExpiresAt.Set {
+		if err := validateScheduleChange(activatesAt, expiresAt, body.ExpiresAt.Set, time.Now()); err != nil {
+			return errInvalidSchedule.WithMessage(err.Error())
+		}
+	}
+
+	if body.MaxClicks != nil {
+		updates["max_clicks"] = *body.MaxClicks
+	}
+
+	if body.Disabled != nil {
+		updates["disabled"] = *body.Disabled
+	}
+
+	if len(updates) > 0 {
+		previous := *link
+		err := model.UpdateShortLink(r.db, link, updates)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// The slug was taken after pickCode checked it.
+			return errSlugTaken
+		}
+		if err != nil {
+			return apperr.Internal(err)
+		}
+		r.links.Invalidate(c.Request.Context(), previous, *link)
+	}
+
+	return respondShortLink(c, r.db, link)
+}
+
+func (r *ShortenerRouter) DeleteShortener(c *gin.Context) error {
+	link, err := r.accessibleLink(c, model.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if err := model.DeleteShortLink(r.db, link); err != nil {
+		return apperr.Internal(err)
+	}
+	r.links.Invalidate(c.Request.Context(), *link)
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+// accessibleLink loads the link named by the :id parameter and makes sure the
+// current user may access it: personal links only by their owner, workspace
+// links by members with at least role. Links the user cannot see at all are
+// reported as missing so IDs cannot be probed.
+func (r *ShortenerRouter) accessibleLink(c *gin.Context, role string) (*model.ShortLink, error) {
+	params, err := utils.GetParams[entities.ShortenerIDParams](c)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := auth.GetCurrentUserID(c)
+	link, err := model.GetShortLinkByID(r.db, params.ID)
+	if err != nil {
+		return nil, errLinkNotFound
+	}
+
+	if link.WorkspaceID == nil {
+		if link.UserID != int(userID) {
+			return nil, errLinkNotFound
+		}
+		return link, nil
+	}
+
+	member, err := model.GetWorkspaceMember(r.db, *link.WorkspaceID, userID)
+	if err != nil {
+		return nil, errLinkNotFound
+	}
+	if !member.Can(role) {
+		return nil, errWorkspaceRole
+	}
+
+	return link, nil
+}
+
+// linkScope resolves the workspace a request acts on, checking that the
+// current user is a member with at least role. A zero workspaceID selects
+// the user's personal links.
+func linkScope(c *gin.Context, db *gorm.DB, workspaceID uint, role string) (model.ShortLinkScope, error) {
+	userID := auth.GetCurrentUserID(c)
+	if workspaceID == 0 {
+		return model.ShortLinkScope{UserID: userID}, nil
+	}
+
+	member, err := model.GetWorkspaceMember(db, workspaceID, userID)
+	if err != nil {
+		return model.ShortLinkScope{}, errWorkspaceNotFound
+	}
+	if !member.Can(role) {
+		return model.ShortLinkScope{}, errWorkspaceRole
+	}
+
+	return model.ShortLinkScope{UserID: userID, WorkspaceID: &workspaceID}, nil
+}
+
+// linkHosts maps the custom domains of links to their hostnames. Links on the
+// default host have no entry.
+func linkHosts(db *gorm.DB, links ...model.ShortLink) (map[uint]string, error) {
+	var ids []uint
+	for _, link := range links {
+		if link.DomainID != 0 && !slices.Contains(ids, link.DomainID) {
+			ids = append(ids, link.DomainID)
+		}
+	}
+	return model.DomainHostnames(db, ids)
+}
+
+// respondShortLink answers with a single link.
+func respondShortLink(c *gin.Context, db *gorm.DB, link *model.ShortLink) error {
+	hosts, err := linkHosts(db, *link)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, toShortLinkResponse(c, link, hosts[link.DomainID]))
+	return nil
+}
+
+func toShortLinkResponse(c *gin.Context, link *model.ShortLink, host string) entities.ShortLinkResponse {
+	return entities.ShortLinkResponse{
+		ID:          link.ID,
+		Code:        link.Code,
+		Url:         link.URL,
+		ShortUrl:    shortURL(c, host, link.Code),
+		ActivatesAt: link.ActivatesAt,
+		ExpiresAt:   link.ExpiresAt,
+		MaxClicks:   link.MaxClicks,
+		ClickCount:  link.ClickCount,
+		Disabled:    link.Disabled,
+		CreatedAt:   link.CreatedAt,
+		UpdatedAt:   link.UpdatedAt,
+
+		TakenDownAt:    link.TakenDownAt,
+		TakedownReason: link.TakedownReason,
+		WorkspaceID:    link.WorkspaceID,
+		DomainID:       link.DomainID,
+	}
+}
+
+// shortURL builds the public URL of a code on host, or on the host of the
+// request when host is empty.
+func shortURL(c *gin.Context, host, code string) string {
+	if host == "" {
+		host = c.Request.Host
+	}
+	return fmt.Sprintf("%s://%s/short/%s", utils.GetProtocol(c), host, code)
+}
@@ -0,0 +1,150 @@
+package routers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-api/entities"
+	"go-api/internal/apperr"
+	"go-api/internal/domains"
+	"go-api/internal/qrcode"
+	"go-api/internal/utils"
+	"image/color"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultQRSize  = 256
+	qrCacheControl = "public, max-age=86400"
+	// qrRevision is part of every ETag, so changes to how codes are drawn
+	// reach clients that cached the old ones.
+	qrRevision = "1"
+)
+
+// GetShortLinkQR draws a QR code of the public short URL of a link. Links
+// that are not active yet get one too, so it can be printed ahead of time.
+func (r *ShortenerRouter) GetShortLinkQR(c *gin.Context) error {
+	params, err := utils.GetParams[entities.ShortenerParams](c)
+	if err != nil {
+		return err
+	}
+
+	query, err := utils.GetSearchParams[entities.ShortenerQRQuery](c)
+	if err != nil {
+		return err
+	}
+	level, style, err := qrStyle(query)
+	if err != nil {
+		return err
+	}
+
+	domainID, err := r.links.ResolveHost(c.Request.Context(), domains.HostOnly(c.Request.Host))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	link, err := r.links.Resolve(c.Request.Context(), domainID, params.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && link == nil) {
+		return errLinkNotFound
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if link.IsTakenDown() {
+		return errLinkTakenDown
+	}
+
+	// The request was made to the link's own host, as a redirect would be.
+	target := shortURL(c, "", link.Code)
+
+	format := query.Format
+	if format == "" {
+		format = "png"
+	}
+
+	etag := qrETag(target, format, level, style)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", qrCacheControl)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return nil
+	}
+
+	code, err := qrcode.Encode([]byte(target), level)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	if format == "svg" {
+		c.Data(http.StatusOK, "image/svg+xml", code.SVG(style))
+		return nil
+	}
+
+	image, err := code.PNG(style)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	c.Data(http.StatusOK, "image/png", image)
+	return nil
+}
+
+// qrStyle parses the options of query, falling back to a black on white
+// code of the default size.
+func qrStyle(query entities.ShortenerQRQuery) (qrcode.Level, qrcode.Style, error) {
+	level := qrcode.Medium
+	if query.Level != "" {
+		level, _ = qrcode.ParseLevel(query.Level)
+	}
+
+	style := qrcode.Style{
+		Size:       defaultQRSize,
+		Margin:     qrcode.DefaultMargin,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+	if query.Size != 0 {
+		style.Size = query.Size
+	}
+	if query.Margin != nil {
+		style.Margin = *query.Margin
+	}
+
+	var err error
+	if query.Foreground != "" {
+		if style.Foreground, err = qrcode.ParseColor(query.Foreground); err != nil {
+			return level, style, errInvalidQRFg
+		}
+	}
+	if query.Background != "" {
+		if style.Background, err = qrcode.ParseColor(query.Background); err != nil {
+			return level, style, errInvalidQRBg
+		}
+	}
+	if style.Foreground == style.Background {
+		return level, style, errQRNoContrast
+	}
+	return level, style, nil
+}
+
+// qrETag identifies the image drawn of target.
+func qrETag(target, format string, level qrcode.Level, style qrcode.Style) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\n%s\n%s\n%s\n%+v", qrRevision, target, format, level, style))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, weakly
+// compared as RFC 9110 asks for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
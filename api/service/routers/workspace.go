@@ -0,0 +1,496 @@
+package routers
+
+import (
+	"errors"
+	"fmt"
+	"go-api/database/model"
+	"go-api/entities"
+	"go-api/internal/apiversion"
+	"go-api/internal/apperr"
+	"go-api/internal/auth"
+	"go-api/internal/config"
+	"go-api/internal/mailer"
+	"go-api/internal/middleware"
+	"go-api/internal/openapi"
+	"go-api/internal/utils"
+	"go-api/service/jobs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WorkspaceRouter manages workspaces, their members and invitations. The
+// links of a workspace are served by the shortener routes with the
+// workspace query parameter.
+type WorkspaceRouter struct {
+	db         *gorm.DB
+	links      *model.ShortLinkCache
+	mailer     mailer.Mailer
+	background *jobs.Queue
+	cfg        *config.Config
+}
+
+func NewWorkspaceRouter(db *gorm.DB, links *model.ShortLinkCache, mailer mailer.Mailer, background *jobs.Queue, cfg *config.Config) *WorkspaceRouter {
+	return &WorkspaceRouter{db: db, links: links, mailer: mailer, background: background, cfg: cfg}
+}
+
+func (r *WorkspaceRouter) RegisterRouter(router *gin.RouterGroup, _ apiversion.Version) {
+	workspaceRouter := router.Group("/workspaces", middleware.AuthMiddleware(r.db), middleware.RequireSession())
+	{
+		workspaceRouter.GET("", apperr.Handle(r.ListWorkspaces))
+		workspaceRouter.POST("", apperr.Handle(r.CreateWorkspace))
+		workspaceRouter.POST("/invitations/accept", apperr.Handle(r.AcceptInvitation))
+		workspaceRouter.GET("/:id", apperr.Handle(r.GetWorkspace))
+		workspaceRouter.PATCH("/:id", apperr.Handle(r.UpdateWorkspace))
+		workspaceRouter.DELETE("/:id", apperr.Handle(r.DeleteWorkspace))
+		workspaceRouter.GET("/:id/members", apperr.Handle(r.ListMembers))
+		workspaceRouter.PATCH("/:id/members/:userId", apperr.Handle(r.UpdateMember))
+		workspaceRouter.DELETE("/:id/members/:userId", apperr.Handle(r.RemoveMember))
+		workspaceRouter.GET("/:id/invitations", apperr.Handle(r.ListInvitations))
+		workspaceRouter.POST("/:id/invitations", apperr.Handle(r.CreateInvitation))
+		workspaceRouter.DELETE("/:id/invitations/:invitationId", apperr.Handle(r.RevokeInvitation))
+	}
+}
+
+func (r *WorkspaceRouter) DescribeRoutes(spec *openapi.Group, _ apiversion.Version) {
+	workspaceRouter := spec.Group("/workspaces", "workspaces").Authenticated()
+	{
+		workspaceRouter.GET("", openapi.Op{
+			ID:        "listWorkspaces",
+			Summary:   "List the workspaces the user is a member of",
+			Responses: openapi.Responses{http.StatusOK: []entities.WorkspaceResponse{}},
+		})
+		workspaceRouter.POST("", openapi.Op{
+			ID:        "createWorkspace",
+			Body:      entities.WorkspaceBody{},
+			Responses: openapi.Responses{http.StatusCreated: entities.WorkspaceResponse{}},
+		})
+		workspaceRouter.POST("/invitations/accept", openapi.Op{
+			ID:        "acceptInvitation",
+			Summary:   "Join a workspace with an invitation token",
+			Body:      entities.WorkspaceAcceptBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.WorkspaceResponse{}},
+		})
+		workspaceRouter.GET("/:id", openapi.Op{
+			ID:        "getWorkspace",
+			Params:    entities.WorkspaceParams{},
+			Responses: openapi.Responses{http.StatusOK: entities.WorkspaceResponse{}},
+		})
+		workspaceRouter.PATCH("/:id", openapi.Op{
+			ID:        "renameWorkspace",
+			Params:    entities.WorkspaceParams{},
+			Body:      entities.WorkspaceBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.WorkspaceResponse{}},
+		})
+		workspaceRouter.DELETE("/:id", openapi.Op{
+			ID:        "deleteWorkspace",
+			Params:    entities.WorkspaceParams{},
+			Responses: openapi.Responses{http.StatusNoContent: nil},
+		})
+		workspaceRouter.GET("/:id/members", openapi.Op{
+			ID:        "listMembers",
+			Params:    entities.WorkspaceParams{},
+			Responses: openapi.Responses{http.StatusOK: []entities.WorkspaceMemberResponse{}},
+		})
+		workspaceRouter.PATCH("/:id/members/:userId", openapi.Op{
+			ID:        "updateMember",
+			Summary:   "Change the role of a member",
+			Params:    entities.WorkspaceMemberParams{},
+			Body:      entities.WorkspaceMemberRoleBody{},
+			Responses: openapi.Responses{http.StatusOK: entities.WorkspaceMemberResponse{}},
+		})
+		workspaceRouter.DELETE("/:id/members/:userId", openapi.Op{
+			ID:        "removeMember",
+			Params:    entities.WorkspaceMemberParams{},
+			Responses: openapi.Responses{http.StatusNoContent: nil},
+		})
+		workspaceRouter.GET("/:id/invitations", openapi.Op{
+			ID:        "listInvitations",
+			Summary:   "List the pending invitations of a workspace",
+			Params:    entities.WorkspaceParams{},
+			Responses: openapi.Responses{http.StatusOK: []entities.WorkspaceInvitationResponse{}},
+		})
+		workspaceRouter.POST("/:id/invitations", openapi.Op{
+			ID:        "createInvitation",
+			Summary:   "Mail an invitation to join a workspace",
+			Params:    entities.WorkspaceParams{},
+			Body:      entities.WorkspaceInviteBody{},
+			Responses: openapi.Responses{http.StatusCreated: entities.WorkspaceInvitationResponse{}},
+		})
+		workspaceRouter.DELETE("/:id/invitations/:invitationId", openapi.Op{
+			ID:        "revokeInvitation",
+			Params:    entities.WorkspaceInvitationParams{},
+			Responses: openapi.Responses{http.StatusNoContent: nil},
+		})
+	}
+}
+
+func (r *WorkspaceRouter) ListWorkspaces(c *gin.Context) error {
+	workspaces, err := model.ListUserWorkspaces(r.db, auth.GetCurrentUserID(c))
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := make([]entities.WorkspaceResponse, len(workspaces))
+	for i, workspace := range workspaces {
+		response[i] = toWorkspaceResponse(&workspace.Workspace, workspace.Role)
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+func (r *WorkspaceRouter) CreateWorkspace(c *gin.Context) error {
+	body, err := utils.GetBody[entities.WorkspaceBody](c)
+	if err != nil {
+		return err
+	}
+
+	workspace := model.Workspace{
+		Name:        strings.TrimSpace(body.Name),
+		CreatedByID: auth.GetCurrentUserID(c),
+	}
+	if workspace.Name == "" {
+		return errBlankName
+	}
+
+	if err := model.CreateWorkspace(r.db, &workspace); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusCreated, toWorkspaceResponse(&workspace, model.WorkspaceRoleOwner))
+	return nil
+}
+
+func (r *WorkspaceRouter) GetWorkspace(c *gin.Context) error {
+	workspace, member, err := r.workspace(c, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, toWorkspaceResponse(workspace, member.Role))
+	return nil
+}
+
+func (r *WorkspaceRouter) UpdateWorkspace(c *gin.Context) error {
+	workspace, member, err := r.workspace(c, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.WorkspaceBody](c)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		return errBlankName
+	}
+
+	if err := model.UpdateWorkspace(r.db, workspace, map[string]any{"name": name}); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, toWorkspaceResponse(workspace, member.Role))
+	return nil
+}
+
+// DeleteWorkspace deletes the workspace and every link it owns.
+func (r *WorkspaceRouter) DeleteWorkspace(c *gin.Context) error {
+	workspace, _, err := r.workspace(c, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	links, err := model.DeleteWorkspace(r.db, workspace)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	r.links.Invalidate(c.Request.Context(), links...)
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+func (r *WorkspaceRouter) ListMembers(c *gin.Context) error {
+	workspace, _, err := r.workspace(c, model.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	members, err := model.ListWorkspaceMembers(r.db, workspace.ID)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := make([]entities.WorkspaceMemberResponse, len(members))
+	for i := range members {
+		response[i] = toWorkspaceMemberResponse(&members[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+func (r *WorkspaceRouter) UpdateMember(c *gin.Context) error {
+	target, err := r.targetMember(c)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.WorkspaceMemberRoleBody](c)
+	if err != nil {
+		return err
+	}
+
+	err = model.UpdateWorkspaceMemberRole(r.db, target, body.Role)
+	if errors.Is(err, model.ErrLastWorkspaceOwner) {
+		return errLastOwner
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	target.Role = body.Role
+	c.JSON(http.StatusOK, toWorkspaceMemberResponse(target))
+	return nil
+}
+
+// RemoveMember removes a member. Owners may remove anyone, other members
+// only themselves to leave the workspace.
+func (r *WorkspaceRouter) RemoveMember(c *gin.Context) error {
+	params, err := utils.GetParams[entities.WorkspaceMemberParams](c)
+	if err != nil {
+		return err
+	}
+
+	requiredRole := model.WorkspaceRoleOwner
+	if params.UserID == auth.GetCurrentUserID(c) {
+		requiredRole = model.WorkspaceRoleViewer
+	}
+
+	if _, _, err := r.workspace(c, requiredRole); err != nil {
+		return err
+	}
+
+	target, err := model.GetWorkspaceMember(r.db, params.ID, params.UserID)
+	if err != nil {
+		return errMemberNotFound
+	}
+
+	err = model.RemoveWorkspaceMember(r.db, target)
+	if errors.Is(err, model.ErrLastWorkspaceOwner) {
+		return errLastOwner
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+func (r *WorkspaceRouter) ListInvitations(c *gin.Context) error {
+	workspace, _, err := r.workspace(c, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	invitations, err := model.ListPendingWorkspaceInvitations(r.db, workspace.ID, time.Now())
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	response := make([]entities.WorkspaceInvitationResponse, len(invitations))
+	for i := range invitations {
+		response[i] = toWorkspaceInvitationResponse(&invitations[i])
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil
+}
+
+// CreateInvitation mails an invitation link to the given address. Only the
+// account registered with that address can accept it.
+func (r *WorkspaceRouter) CreateInvitation(c *gin.Context) error {
+	workspace, _, err := r.workspace(c, model.WorkspaceRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	body, err := utils.GetBody[entities.WorkspaceInviteBody](c)
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	ttl := r.cfg.Workspaces.InvitationTTL
+	invitation := model.WorkspaceInvitation{
+		WorkspaceID: workspace.ID,
+		Email:       body.Email,
+		Role:        body.Role,
+		TokenHash:   utils.HashToken(token),
+		InvitedByID: auth.GetCurrentUserID(c),
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+
+	if err := model.CreateWorkspaceInvitation(r.db, &invitation); err != nil {
+		return apperr.Internal(err)
+	}
+
+	sendMail(r.background, r.mailer, mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to %s", workspace.Name),
+		Text: fmt.Sprintf("You have been invited to join the workspace %q as %s. Accept the invitation by opening the link below:\n\n%s\n\nThe link expires in %s.",
+			workspace.Name, invitation.Role, appLink(r.cfg.Mail.AppURL, "/workspaces/accept", token), ttl),
+	})
+
+	c.JSON(http.StatusCreated, toWorkspaceInvitationResponse(&invitation))
+	return nil
+}
+
+func (r *WorkspaceRouter) RevokeInvitation(c *gin.Context) error {
+	params, err := utils.GetParams[entities.WorkspaceInvitationParams](c)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := r.workspace(c, model.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	invitation, err := model.GetWorkspaceInvitation(r.db, params.ID, params.InvitationID)
+	if err != nil || invitation.AcceptedAt != nil {
+		return errInvitationNotFound
+	}
+
+	if err := model.DeleteWorkspaceInvitation(r.db, invitation); err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+func (r *WorkspaceRouter) AcceptInvitation(c *gin.Context) error {
+	body, err := utils.GetBody[entities.WorkspaceAcceptBody](c)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := model.GetWorkspaceInvitationByHash(r.db, utils.HashToken(body.Token))
+	if err != nil || !invitation.IsPending(time.Now()) {
+		return errInvalidInvitation
+	}
+
+	user, err := model.GetUserByID(r.db, auth.GetCurrentUserID(c))
+	if err != nil {
+		return middleware.ErrUnauthorized
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return errInvitationEmail
+	}
+
+	workspace, err := model.GetWorkspaceByID(r.db, invitation.WorkspaceID)
+	if err != nil {
+		return errInvalidInvitation
+	}
+
+	member, err := model.AcceptWorkspaceInvitation(r.db, invitation, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errInvalidInvitation
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+
+	c.JSON(http.StatusOK, toWorkspaceResponse(workspace, member.Role))
+	return nil
+}
+
+// workspace loads the workspace named by the :id parameter and the current
+// user's membership, which must have at least role. Workspaces the user is
+// not a member of are reported as missing.
+func (r *WorkspaceRouter) workspace(c *gin.Context, role string) (*model.Workspace, *model.WorkspaceMember, error) {
+	params, err := utils.GetParams[entities.WorkspaceParams](c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	member, err := model.GetWorkspaceMember(r.db, params.ID, auth.GetCurrentUserID(c))
+	if err != nil {
+		return nil, nil, errWorkspaceNotFound
+	}
+
+	workspace, err := model.GetWorkspaceByID(r.db, params.ID)
+	if err != nil {
+		return nil, nil, errWorkspaceNotFound
+	}
+
+	if !member.Can(role) {
+		return nil, nil, errWorkspaceRole
+	}
+
+	return workspace, member, nil
+}
+
+// targetMember loads the member named by :userId after checking that the
+// current user owns the workspace.
+func (r *WorkspaceRouter) targetMember(c *gin.Context) (*model.WorkspaceMember, error) {
+	params, err := utils.GetParams[entities.WorkspaceMemberParams](c)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := r.workspace(c, model.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+
+	member, err := model.GetWorkspaceMember(r.db, params.ID, params.UserID)
+	if err != nil {
+		return nil, errMemberNotFound
+	}
+
+	return member, nil
+}
+
+func toWorkspaceResponse(workspace *model.Workspace, role string) entities.WorkspaceResponse {
+	return entities.WorkspaceResponse{
+		ID:        workspace.ID,
+		Name:      workspace.Name,
+		Role:      role,
+		CreatedAt: workspace.CreatedAt,
+	}
+}
+
+func toWorkspaceMemberResponse(member *model.WorkspaceMember) entities.WorkspaceMemberResponse {
+	response := entities.WorkspaceMemberResponse{
+		UserID:   member.UserID,
+		Role:     member.Role,
+		JoinedAt: member.CreatedAt,
+	}
+	if member.User != nil {
+		response.Email = member.User.Email
+		response.Name = member.User.Name
+	}
+	return response
+}
+
+func toWorkspaceInvitationResponse(invitation *model.WorkspaceInvitation) entities.WorkspaceInvitationResponse {
+	return entities.WorkspaceInvitationResponse{
+		ID:          invitation.ID,
+		Email:       invitation.Email,
+		Role:        invitation.Role,
+		InvitedByID: invitation.InvitedByID,
+		ExpiresAt:   invitation.ExpiresAt,
+		CreatedAt:   invitation.CreatedAt,
+	}
+}